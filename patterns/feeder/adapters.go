@@ -0,0 +1,193 @@
+package feeder
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+
+	"github.com/gostdlib/base/context"
+	"github.com/gostdlib/concurrency/broadcast"
+	"github.com/gostdlib/concurrency/patterns/stream"
+)
+
+// The adapters below build a KVPipeline from a common source so a caller does not hand-build the pipe and
+// stop channels. Each one starts a producer every time the pipeline is established, which with WithRetry means
+// every re-establishment reads the source again from wherever a fresh read starts. A source error is sent as
+// KeyVal.Err, which ends that establishment: a retryable error re-establishes the pipeline under WithRetry and
+// an error wrapping ErrPermanent ends the Feed.
+//
+// A producer ends when its source ends, after it sends an error, or when the establishment's Context is
+// cancelled: the Context passed to Feed is, which closes the pipe and ends the Feed with the Context's error, or
+// the Feeder has stopped reading the pipe because a Set or Delete failed.
+
+// FromSeq returns a KVPipeline that adds every key/value pair seq yields. Each establishment ranges seq again, so
+// seq must be safe to range more than once when used with WithRetry. seq cannot report an error; use
+// FromResults for a source that can fail.
+func FromSeq[K cmp.Ordered, V any](seq iter.Seq2[K, V]) KVPipeline[K, V] {
+	if seq == nil {
+		panic("feeder.FromSeq: seq cannot be nil")
+	}
+	return func(ctx context.Context) (chan KeyVal[K, V], chan struct{}, error) {
+		return produce(ctx, func(yield func(KeyVal[K, V]) bool) {
+			for k, v := range seq {
+				if !yield(KeyVal[K, V]{Op: Add, K: k, V: v}) {
+					return
+				}
+			}
+		})
+	}
+}
+
+// FromResults returns a KVPipeline that adds the value of every stream.Result seq yields, such as the output of
+// foreach.Item. A Result with an Err is sent as KeyVal.Err, which ends the establishment. Each establishment
+// ranges seq again.
+func FromResults[K cmp.Ordered, V any](seq iter.Seq2[K, stream.Result[V]]) KVPipeline[K, V] {
+	if seq == nil {
+		panic("feeder.FromResults: seq cannot be nil")
+	}
+	return func(ctx context.Context) (chan KeyVal[K, V], chan struct{}, error) {
+		return produce(ctx, func(yield func(KeyVal[K, V]) bool) {
+			for k, r := range seq {
+				if r.Err != nil {
+					yield(KeyVal[K, V]{Err: r.Err})
+					return
+				}
+				if !yield(KeyVal[K, V]{Op: Add, K: k, V: r.V}) {
+					return
+				}
+			}
+		})
+	}
+}
+
+// FromChan returns a KVPipeline that adds every value received on c under the key that key returns for it. c is
+// read with stream.Chan, so the pipe closes when c is closed or the Feed's Context is cancelled. A channel cannot
+// be read again, so a re-establishment picks up with the next value received.
+func FromChan[K cmp.Ordered, V any](c <-chan V, key func(V) K) KVPipeline[K, V] {
+	if key == nil {
+		panic("feeder.FromChan: key cannot be nil")
+	}
+	return func(ctx context.Context) (chan KeyVal[K, V], chan struct{}, error) {
+		return produce(ctx, func(yield func(KeyVal[K, V]) bool) {
+			for _, v := range stream.Chan(ctx, c) {
+				if !yield(KeyVal[K, V]{Op: Add, K: key(v), V: v}) {
+					return
+				}
+			}
+		})
+	}
+}
+
+// FromBroadcast returns a KVPipeline that applies every KeyVal sent on b. Each establishment subscribes again, so
+// a KeyVal sent while the pipeline is being re-established is not seen. The pipe closes when b is closed or the
+// Feed's Context is cancelled. A KeyVal with an Err set is passed through, so a sender can end the Feed, or
+// have it re-establish, by sending one.
+func FromBroadcast[K cmp.Ordered, V any](b *broadcast.Value[KeyVal[K, V]]) KVPipeline[K, V] {
+	if b == nil {
+		panic("feeder.FromBroadcast: b cannot be nil")
+	}
+	return func(ctx context.Context) (chan KeyVal[K, V], chan struct{}, error) {
+		if err := ctx.Err(); err != nil {
+			return nil, nil, err
+		}
+		// Subscribe here, not in the producer, so a KeyVal sent after the pipeline is established is never missed.
+		sub := b.Subscribe(ctx)
+		return produce(ctx, func(yield func(KeyVal[K, V]) bool) {
+			for kv := range sub {
+				if !yield(kv) {
+					return
+				}
+				if kv.Err != nil {
+					return
+				}
+			}
+		})
+	}
+}
+
+// Opener opens the source a reader-based adapter decodes. It is called every time the pipeline is established,
+// so a re-establishment reads the source from the start; upserts make reading a record again harmless. An error
+// is returned from the KVPipeline, so it is retried under WithRetry unless it wraps ErrPermanent.
+type Opener func(ctx context.Context) (io.ReadCloser, error)
+
+// FromJSONL returns a KVPipeline that decodes one V from every non-empty line of the source open returns, with
+// stream.JSONL, and adds it under the key that key returns. A line that does not decode, or is longer than
+// bufio.MaxScanTokenSize, is sent as a KeyVal.Err wrapping ErrPermanent, as reading it again would fail the same
+// way; a read error is retryable.
+func FromJSONL[K cmp.Ordered, V any](open Opener, key func(V) K) KVPipeline[K, V] {
+	if open == nil {
+		panic("feeder.FromJSONL: open cannot be nil")
+	}
+	if key == nil {
+		panic("feeder.FromJSONL: key cannot be nil")
+	}
+	return fromReader("feeder.FromJSONL", open, key, stream.JSONL[V])
+}
+
+// FromCSV returns a KVPipeline that decodes one V from every record of the CSV source open returns, with
+// stream.CSV, which uses the first record as the header, and adds it under the key that key returns. A header or
+// record that does not decode is sent as a KeyVal.Err wrapping ErrPermanent, as reading it again would fail the
+// same way; a read error is retryable. An empty source adds nothing.
+func FromCSV[K cmp.Ordered, V any](open Opener, key func(V) K) KVPipeline[K, V] {
+	if open == nil {
+		panic("feeder.FromCSV: open cannot be nil")
+	}
+	if key == nil {
+		panic("feeder.FromCSV: key cannot be nil")
+	}
+	return fromReader("feeder.FromCSV", open, key, stream.CSV[V])
+}
+
+// fromReader returns a KVPipeline that opens its source with open and adds every value decode yields from it. The
+// first error decode yields ends the establishment, made permanent if it wraps stream.ErrMalformed. name prefixes
+// the errors.
+func fromReader[K cmp.Ordered, V any](name string, open Opener, key func(V) K, decode func(context.Context, io.Reader) iter.Seq2[int, stream.Result[V]]) KVPipeline[K, V] {
+	return func(ctx context.Context) (chan KeyVal[K, V], chan struct{}, error) {
+		rc, err := open(ctx)
+		if err != nil {
+			return nil, nil, err
+		}
+		return produce(ctx, func(yield func(KeyVal[K, V]) bool) {
+			defer rc.Close()
+
+			for _, r := range decode(ctx, rc) {
+				if r.Err != nil {
+					err := r.Err
+					if errors.Is(err, stream.ErrMalformed) {
+						err = fmt.Errorf("%w: %w", err, ErrPermanent)
+					}
+					yield(KeyVal[K, V]{Err: fmt.Errorf("%s: %w", name, err)})
+					return
+				}
+				if !yield(KeyVal[K, V]{Op: Add, K: key(r.V), V: r.V}) {
+					return
+				}
+			}
+		})
+	}
+}
+
+// produce starts the producer for one establishment of a pipeline: it ranges seq on the default pool and sends
+// every KeyVal on the returned pipe, which it closes when seq ends, after sending a KeyVal with an Err, or when
+// ctx is cancelled. The producer runs on the default pool, never the Context's pool, which may be Limited: it
+// lives as long as its source, so a limited slot it took would be held for that whole time instead of doing
+// work. The stop channel is never closed, as closing the pipe is how a producer says it is done.
+func produce[K cmp.Ordered, V any](ctx context.Context, seq iter.Seq[KeyVal[K, V]]) (chan KeyVal[K, V], chan struct{}, error) {
+	pipe := make(chan KeyVal[K, V])
+	_ = context.Pool(ctx).Default().Submit(context.WithoutCancel(ctx), func() {
+		defer close(pipe)
+		for kv := range seq {
+			select {
+			case pipe <- kv:
+			case <-ctx.Done():
+				return
+			}
+			if kv.Err != nil {
+				return
+			}
+		}
+	})
+	return pipe, make(chan struct{}), nil
+}
@@ -0,0 +1,294 @@
+package feeder
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gostdlib/base/context"
+	"github.com/gostdlib/base/retry/exponential"
+	"github.com/gostdlib/concurrency/broadcast"
+	"github.com/gostdlib/concurrency/patterns/stream"
+
+	"github.com/kylelemons/godebug/pretty"
+)
+
+type record struct {
+	Name string `json:"name" csv:"name"`
+	N    int    `json:"n" csv:"n"`
+}
+
+func recordKey(r record) string {
+	return r.Name
+}
+
+// readerOpener returns an Opener that opens s from the start every time it is called.
+func readerOpener(s string) Opener {
+	return func(ctx context.Context) (io.ReadCloser, error) {
+		return io.NopCloser(strings.NewReader(s)), nil
+	}
+}
+
+func TestAdapters(t *testing.T) {
+	back, err := exponential.New(exponential.WithTesting())
+	if err != nil {
+		t.Fatalf("TestAdapters: exponential.New: %s", err)
+	}
+
+	// flaky fails its first range part way through with a retryable error, then yields every value.
+	ranged := 0
+	flaky := func(yield func(string, stream.Result[record]) bool) {
+		ranged++
+		if !yield("a", stream.Result[record]{V: record{Name: "a", N: 1}}) {
+			return
+		}
+		if ranged == 1 {
+			yield("", stream.Result[record]{Err: errors.New("temporary failure")})
+			return
+		}
+		yield("b", stream.Result[record]{V: record{Name: "b", N: 2}})
+	}
+
+	c := make(chan record, 2)
+	c <- record{Name: "a", N: 1}
+	c <- record{Name: "b", N: 2}
+	close(c)
+
+	tests := []struct {
+		name      string
+		pipe      KVPipeline[string, record]
+		want      map[string]record
+		wantErr   bool
+		permanent bool
+	}{
+		{
+			name: "Success: FromSeq adds every pair",
+			pipe: FromSeq(maps.All(map[string]record{"a": {Name: "a", N: 1}, "b": {Name: "b", N: 2}})),
+			want: map[string]record{"a": {Name: "a", N: 1}, "b": {Name: "b", N: 2}},
+		},
+		{
+			name: "Success: FromResults re-establishes after a retryable error",
+			pipe: FromResults(flaky),
+			want: map[string]record{"a": {Name: "a", N: 1}, "b": {Name: "b", N: 2}},
+		},
+		{
+			name: "Success: FromChan adds every value until the channel closes",
+			pipe: FromChan(c, recordKey),
+			want: map[string]record{"a": {Name: "a", N: 1}, "b": {Name: "b", N: 2}},
+		},
+		{
+			name: "Success: FromJSONL decodes every line and skips blank ones",
+			pipe: FromJSONL(readerOpener("{\"name\":\"a\",\"n\":1}\n\n{\"name\":\"b\",\"n\":2}\n"), recordKey),
+			want: map[string]record{"a": {Name: "a", N: 1}, "b": {Name: "b", N: 2}},
+		},
+		{
+			name: "Success: FromCSV decodes every record under the header",
+			pipe: FromCSV(readerOpener("name,n\na,1\nb,2\n"), recordKey),
+			want: map[string]record{"a": {Name: "a", N: 1}, "b": {Name: "b", N: 2}},
+		},
+		{
+			name: "Success: FromCSV with an empty source adds nothing",
+			pipe: FromCSV(readerOpener(""), recordKey),
+			want: map[string]record{},
+		},
+		{
+			name:      "Error: FromJSONL stops at a line that does not decode",
+			pipe:      FromJSONL(readerOpener("{\"name\":\"a\",\"n\":1}\nnot json\n{\"name\":\"b\",\"n\":2}\n"), recordKey),
+			want:      map[string]record{"a": {Name: "a", N: 1}},
+			wantErr:   true,
+			permanent: true,
+		},
+		{
+			name:      "Error: FromJSONL stops at a line too long to scan",
+			pipe:      FromJSONL(readerOpener("{\"name\":\"a\",\"n\":1}\n"+strings.Repeat("x", bufio.MaxScanTokenSize+1)+"\n"), recordKey),
+			want:      map[string]record{"a": {Name: "a", N: 1}},
+			wantErr:   true,
+			permanent: true,
+		},
+		{
+			name:      "Error: FromCSV stops at a record that does not decode",
+			pipe:      FromCSV(readerOpener("name,n\na,1\nb,two\n"), recordKey),
+			want:      map[string]record{"a": {Name: "a", N: 1}},
+			wantErr:   true,
+			permanent: true,
+		},
+		{
+			name: "Error: FromJSONL returns an error from open",
+			pipe: FromJSONL(func(ctx context.Context) (io.ReadCloser, error) {
+				return nil, errors.New("cannot open")
+			}, recordKey),
+			want:      map[string]record{},
+			wantErr:   true,
+			permanent: false,
+		},
+	}
+
+	for _, test := range tests {
+		m := &Map[string, record]{M: map[string]record{}}
+		var opts []FeederOption[string, record]
+		if !test.wantErr || test.permanent {
+			opts = append(opts, WithRetry[string, record](back))
+		}
+		f, err := NewFeeder[string, record](t.Context(), m, opts...)
+		if err != nil {
+			t.Errorf("TestAdapters(%s): NewFeeder: %s", test.name, err)
+			continue
+		}
+
+		err = <-f.Feed(t.Context(), test.pipe)
+		switch {
+		case err == nil && test.wantErr:
+			t.Errorf("TestAdapters(%s): got err == nil, want err != nil", test.name)
+			continue
+		case err != nil && !test.wantErr:
+			t.Errorf("TestAdapters(%s): got err == %s, want err == nil", test.name, err)
+			continue
+		case err != nil && test.permanent && !errors.Is(err, ErrPermanent):
+			t.Errorf("TestAdapters(%s): got err == %s, want an error wrapping ErrPermanent", test.name, err)
+			continue
+		}
+
+		if diff := pretty.Compare(test.want, m.M); diff != "" {
+			t.Errorf("TestAdapters(%s): map -want/+got:\n%s", test.name, diff)
+		}
+	}
+}
+
+func TestFromBroadcast(t *testing.T) {
+	permanent := errors.New("sender gave up")
+
+	tests := []struct {
+		name    string
+		send    []KeyVal[string, int]
+		close   bool
+		want    map[string]int
+		wantErr bool
+	}{
+		{
+			name:  "Success: every KeyVal sent is applied until the Value is closed",
+			send:  []KeyVal[string, int]{{Op: Add, K: "a", V: 1}, {Op: Add, K: "b", V: 2}, {Op: Delete, K: "a"}},
+			close: true,
+			want:  map[string]int{"b": 2},
+		},
+		{
+			name:    "Error: a KeyVal with an Err ends the Feed",
+			send:    []KeyVal[string, int]{{Op: Add, K: "a", V: 1}, {Err: permanent}},
+			want:    map[string]int{"a": 1},
+			wantErr: true,
+		},
+	}
+
+	for _, test := range tests {
+		b := &broadcast.Value[KeyVal[string, int]]{}
+		m := &Map[string, int]{M: map[string]int{}}
+		f, err := NewFeeder[string, int](t.Context(), m)
+		if err != nil {
+			t.Errorf("TestFromBroadcast(%s): NewFeeder: %s", test.name, err)
+			continue
+		}
+
+		// Wait for the pipeline to subscribe before sending, as anything sent before then is not seen.
+		subscribed := make(chan struct{})
+		pipe := FromBroadcast(b)
+		finished := f.Feed(t.Context(), func(ctx context.Context) (chan KeyVal[string, int], chan struct{}, error) {
+			defer close(subscribed)
+			return pipe(ctx)
+		})
+		<-subscribed
+
+		for _, kv := range test.send {
+			b.Send(t.Context(), kv)
+		}
+		if test.close {
+			b.Close(t.Context())
+		}
+
+		err = <-finished
+		switch {
+		case err == nil && test.wantErr:
+			t.Errorf("TestFromBroadcast(%s): got err == nil, want err != nil", test.name)
+			continue
+		case err != nil && !test.wantErr:
+			t.Errorf("TestFromBroadcast(%s): got err == %s, want err == nil", test.name, err)
+			continue
+		}
+
+		if diff := pretty.Compare(test.want, m.M); diff != "" {
+			t.Errorf("TestFromBroadcast(%s): map -want/+got:\n%s", test.name, diff)
+		}
+	}
+}
+
+// TestAdapterRelease verifies a producer is released when its Feed attempt ends on an op error, rather than
+// staying blocked on the pipe until the Feed's Context is cancelled.
+func TestAdapterRelease(t *testing.T) {
+	released := make(chan struct{})
+	endless := func(yield func(string, int) bool) {
+		defer close(released)
+		for i := 0; yield("k", i); i++ {
+		}
+	}
+	m := &Map[string, int]{
+		M: map[string]int{},
+		SetAccept: func(key string, val, prev int, replaced bool) (bool, error) {
+			return false, fmt.Errorf("rejected: %w", ErrPermanent)
+		},
+	}
+	f, err := NewFeeder[string, int](t.Context(), m)
+	if err != nil {
+		t.Fatalf("TestAdapterRelease: NewFeeder: %s", err)
+	}
+
+	if err := <-f.Feed(t.Context(), FromSeq(endless)); !errors.Is(err, ErrPermanent) {
+		t.Fatalf("TestAdapterRelease: got err == %v, want ErrPermanent", err)
+	}
+	select {
+	case <-released:
+	case <-time.After(5 * time.Second):
+		t.Errorf("TestAdapterRelease: producer still running after the Feed ended")
+	}
+}
+
+// TestAdapterCancel verifies a Feed whose Context is cancelled partway through its source reports an error rather
+// than nil, so a truncated feed cannot be taken for a complete one.
+func TestAdapterCancel(t *testing.T) {
+	endless := func(yield func(string, int) bool) {
+		for i := 0; yield("k", i); i++ {
+		}
+	}
+	ch := make(chan int, 1)
+	ch <- 1
+
+	tests := []struct {
+		name string
+		pipe KVPipeline[string, int]
+	}{
+		{name: "Error: FromSeq", pipe: FromSeq(endless)},
+		{name: "Error: FromChan", pipe: FromChan(ch, func(v int) string { return "k" })},
+	}
+
+	for _, test := range tests {
+		ctx, cancel := context.WithCancel(t.Context())
+		m := &Map[string, int]{
+			M: map[string]int{},
+			SetAccept: func(key string, val, prev int, replaced bool) (bool, error) {
+				cancel()
+				return true, nil
+			},
+		}
+		f, err := NewFeeder[string, int](ctx, m)
+		if err != nil {
+			t.Fatalf("TestAdapterCancel(%s): NewFeeder: %s", test.name, err)
+		}
+
+		if err := <-f.Feed(ctx, test.pipe); !errors.Is(err, context.Canceled) {
+			t.Errorf("TestAdapterCancel(%s): got err == %v, want context.Canceled", test.name, err)
+		}
+		cancel()
+	}
+}
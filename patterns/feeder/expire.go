@@ -0,0 +1,301 @@
+package feeder
+
+import (
+	"container/list"
+	"errors"
+	"time"
+
+	"github.com/gostdlib/base/concurrency/sync"
+	"github.com/gostdlib/base/context"
+	"github.com/gostdlib/base/telemetry/otel/trace/span"
+)
+
+// entry is a value stored in a ShardedMap. The expiry rides with the value so a shard's lock covers both:
+// a Set that refreshes a key can never be undone by a reaper that read the old expiry.
+type entry[V any] struct {
+	v V
+	// expires is when the value expires. The zero value never expires.
+	expires time.Time
+}
+
+// expired reports whether e has expired at now.
+func (e entry[V]) expired(now time.Time) bool {
+	return !e.expires.IsZero() && !now.Before(e.expires)
+}
+
+// init prepares metrics on first use. The Context of the first caller supplies the MeterProvider.
+func (m *Map[K, V]) init(ctx context.Context) {
+	m.once.Do(func() {
+		if m.Name != "" {
+			m.metrics = newMapMetrics(context.MeterProvider(ctx).Meter(meterName + "/" + m.Name))
+		}
+	})
+}
+
+// rejected records that an accept function rejected an op.
+func (m *Map[K, V]) rejected(op Op) {
+	if m.metrics != nil {
+		m.metrics.Rejections.Add(context.Background(), 1, opAttr(op))
+	}
+}
+
+func (m *Map[K, V]) clock() time.Time {
+	if m.now == nil {
+		return time.Now()
+	}
+	return m.now()
+}
+
+// expired reports whether k has an expiry that has passed at now. m.mu must be held.
+func (m *Map[K, V]) expired(k K, now time.Time) bool {
+	exp, ok := m.expires[k]
+	return ok && !now.Before(exp)
+}
+
+// track records that k was just set with ttl: it replaces k's expiry and, with MaxSize set, makes k the most
+// recently used key. m.mu must be held.
+func (m *Map[K, V]) track(k K, ttl time.Duration) {
+	if ttl > 0 {
+		if m.expires == nil {
+			m.expires = map[K]time.Time{}
+		}
+		m.expires[k] = m.clock().Add(ttl)
+	} else {
+		delete(m.expires, k)
+	}
+	if m.MaxSize > 0 {
+		m.touch(k)
+	}
+}
+
+// untrack forgets everything tracked for a deleted key. m.mu must be held.
+func (m *Map[K, V]) untrack(k K) {
+	delete(m.expires, k)
+	if e, ok := m.elems[k]; ok {
+		m.lru.Remove(e)
+		delete(m.elems, k)
+	}
+}
+
+// touch makes k the most recently used key. m.mu must be held.
+func (m *Map[K, V]) touch(k K) {
+	l := m.lruList()
+	if e, ok := m.elems[k]; ok {
+		l.MoveToBack(e)
+		return
+	}
+	m.elems[k] = l.PushBack(k)
+}
+
+// lruList returns the eviction order, building it on first use. Keys already in M (a Map handed a populated
+// map) have no recorded use, so they start out least recently used. m.mu must be held.
+func (m *Map[K, V]) lruList() *list.List {
+	if m.lru == nil {
+		m.lru = list.New()
+		m.elems = make(map[K]*list.Element, len(m.M))
+		for k := range m.M {
+			m.elems[k] = m.lru.PushBack(k)
+		}
+	}
+	return m.lru
+}
+
+// evict deletes a key to make room for a new one: an expired key if DeleteAccept accepts the deletion of one,
+// counted as an expiration, or else the least recently used key it accepts. An error from DeleteAccept stops
+// the eviction and is returned, so the Set that needed the room fails and can be retried. m.mu must be held.
+func (m *Map[K, V]) evict(now time.Time) error {
+	for k, exp := range m.expires {
+		if now.Before(exp) {
+			continue
+		}
+		prev, found := m.M[k]
+		if !found {
+			continue
+		}
+		accept, err := m.deleteAccept(k, prev, found)
+		if err != nil {
+			return err
+		}
+		if accept {
+			delete(m.M, k)
+			m.untrack(k)
+			if m.metrics != nil {
+				m.metrics.Expirations.Add(context.Background(), 1)
+			}
+			return nil
+		}
+	}
+
+	l := m.lruList()
+	for e := l.Front(); e != nil; {
+		next := e.Next()
+		k := e.Value.(K)
+		if m.expired(k, now) {
+			// Already asked above.
+			e = next
+			continue
+		}
+		prev, found := m.M[k]
+		accept, err := m.deleteAccept(k, prev, found)
+		if err != nil {
+			return err
+		}
+		if accept {
+			delete(m.M, k)
+			m.untrack(k)
+			if m.metrics != nil {
+				m.metrics.Evictions.Add(context.Background(), 1)
+			}
+			return nil
+		}
+		e = next
+	}
+	return ErrFull
+}
+
+// Reap deletes every expired key through DeleteAccept and returns how many were deleted. A key whose deletion
+// DeleteAccept rejects or errors on stays, hidden, until a later Reap deletes it; the errors are joined into
+// err. The write lock is held for the whole pass.
+func (m *Map[K, V]) Reap(ctx context.Context) (n int, err error) {
+	m.init(ctx)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := m.clock()
+	var errs []error
+	for k, exp := range m.expires {
+		if now.Before(exp) {
+			continue
+		}
+		prev, found := m.M[k]
+		if !found {
+			m.untrack(k)
+			continue
+		}
+		accept, err := m.deleteAccept(k, prev, found)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if !accept {
+			continue
+		}
+		delete(m.M, k)
+		m.untrack(k)
+		n++
+	}
+	if m.metrics != nil && n > 0 {
+		m.metrics.Expirations.Add(ctx, int64(n))
+	}
+	return n, errors.Join(errs...)
+}
+
+// StartReaper calls Reap every interval in the background until ctx is cancelled. Reap errors are recorded on
+// the span in ctx. interval must be > 0.
+func (m *Map[K, V]) StartReaper(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		panic("feeder.Map.StartReaper: interval must be > 0")
+	}
+	m.init(ctx)
+	reapEvery(ctx, interval, m.Reap)
+}
+
+// init prepares metrics on first use. The Context of the first caller supplies the MeterProvider.
+func (m *ShardedMap[K, V]) init(ctx context.Context) {
+	m.once.Do(func() {
+		if m.Name != "" {
+			m.metrics = newMapMetrics(context.MeterProvider(ctx).Meter(meterName + "/" + m.Name))
+		}
+	})
+}
+
+// rejected records that an accept function rejected an op.
+func (m *ShardedMap[K, V]) rejected(op Op) {
+	if m.metrics != nil {
+		m.metrics.Rejections.Add(context.Background(), 1, opAttr(op))
+	}
+}
+
+func (m *ShardedMap[K, V]) clock() time.Time {
+	if m.now == nil {
+		return time.Now()
+	}
+	return m.now()
+}
+
+// Reap deletes every expired key through DeleteAccept and returns how many were deleted. Expired keys are
+// found with one locked pass over the shards and then deleted one at a time, each under its own shard's lock,
+// where the expiry is checked again: a key refreshed by a Set in between is left alone. A key whose deletion
+// DeleteAccept rejects or errors on stays, hidden, until a later Reap deletes it; the errors are joined into
+// err.
+func (m *ShardedMap[K, V]) Reap(ctx context.Context) (n int, err error) {
+	m.init(ctx)
+
+	now := m.clock()
+	var keys []K
+	for k, e := range m.m.All(sync.WithLock()) {
+		if e.expired(now) {
+			keys = append(keys, k)
+		}
+	}
+
+	var errs []error
+	for _, k := range keys {
+		var derr error
+		_, deleted := m.m.DeleteAccept(k, func(prev entry[V], found bool) bool {
+			if !found || !prev.expired(now) {
+				return false
+			}
+			if m.DeleteAccept == nil {
+				return true
+			}
+			var b bool
+			b, derr = m.DeleteAccept(k, prev.v, found)
+			return derr == nil && b
+		})
+		if derr != nil {
+			errs = append(errs, derr)
+			continue
+		}
+		if deleted {
+			n++
+		}
+	}
+	if m.metrics != nil && n > 0 {
+		m.metrics.Expirations.Add(ctx, int64(n))
+	}
+	return n, errors.Join(errs...)
+}
+
+// StartReaper calls Reap every interval in the background until ctx is cancelled. Reap errors are recorded on
+// the span in ctx. interval must be > 0.
+func (m *ShardedMap[K, V]) StartReaper(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		panic("feeder.ShardedMap.StartReaper: interval must be > 0")
+	}
+	m.init(ctx)
+	reapEvery(ctx, interval, m.Reap)
+}
+
+// reapEvery runs reap every interval until ctx is cancelled. The reaper runs on the default pool, never the
+// Context's pool, which may be Limited: it lives until ctx is cancelled, so a limited slot it took would be
+// held for that whole time instead of doing work.
+func reapEvery(ctx context.Context, interval time.Duration, reap func(context.Context) (int, error)) {
+	_ = context.Pool(ctx).Default().Submit(context.WithoutCancel(ctx), func() {
+		t := time.NewTicker(interval)
+		defer t.Stop()
+
+		spanner := span.Get(ctx)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-t.C:
+			}
+			if _, err := reap(ctx); err != nil {
+				spanner.Span.RecordError(err)
+			}
+		}
+	})
+}
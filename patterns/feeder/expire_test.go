@@ -0,0 +1,480 @@
+package feeder
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gostdlib/base/context"
+	"github.com/kylelemons/godebug/pretty"
+
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+// fakeClock is a settable clock for the expiry tests.
+type fakeClock struct {
+	t time.Time
+}
+
+func (c *fakeClock) now() time.Time { return c.t }
+
+func (c *fakeClock) advance(d time.Duration) { c.t = c.t.Add(d) }
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{t: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)}
+}
+
+func TestMapExpiry(t *testing.T) {
+	boom := errors.New("boom")
+
+	tests := []struct {
+		name         string
+		deleteAccept func(key string, prev int, found bool) (bool, error)
+		// maxSize is the Map's MaxSize, under which Get takes the write lock.
+		maxSize    int
+		wantReaped int
+		wantErr    bool
+		// wantM is the underlying map after Reap, which still holds keys whose deletion was refused.
+		wantM map[string]int
+		// wantDeletes are the keys DeleteAccept was called with.
+		wantDeletes []string
+	}{
+		{
+			name:        "Success: expired keys are deleted through DeleteAccept",
+			wantReaped:  1,
+			wantM:       map[string]int{"forever": 2, "short-lived-by-kv": 3},
+			wantDeletes: []string{"ttl"},
+		},
+		{
+			name:        "Success: expired keys are deleted through DeleteAccept with MaxSize",
+			maxSize:     10,
+			wantReaped:  1,
+			wantM:       map[string]int{"forever": 2, "short-lived-by-kv": 3},
+			wantDeletes: []string{"ttl"},
+		},
+		{
+			name:         "Success: an expired key DeleteAccept rejects stays",
+			deleteAccept: func(key string, prev int, found bool) (bool, error) { return false, nil },
+			wantM:        map[string]int{"ttl": 1, "forever": 2, "short-lived-by-kv": 3},
+			wantDeletes:  []string{"ttl"},
+		},
+		{
+			name:         "Error: an expired key DeleteAccept errors on stays and the error is returned",
+			deleteAccept: func(key string, prev int, found bool) (bool, error) { return true, boom },
+			wantErr:      true,
+			wantM:        map[string]int{"ttl": 1, "forever": 2, "short-lived-by-kv": 3},
+			wantDeletes:  []string{"ttl"},
+		},
+	}
+
+	for _, test := range tests {
+		clk := newFakeClock()
+		var deletes []string
+		m := &Map[string, int]{
+			M:       map[string]int{},
+			TTL:     time.Minute,
+			MaxSize: test.maxSize,
+			DeleteAccept: func(key string, prev int, found bool) (bool, error) {
+				deletes = append(deletes, key)
+				if test.deleteAccept == nil {
+					return true, nil
+				}
+				return test.deleteAccept(key, prev, found)
+			},
+			now: clk.now,
+		}
+
+		if err := m.Set("ttl", 1); err != nil {
+			t.Fatalf("TestMapExpiry(%s): Set: %s", test.name, err)
+		}
+		if err := m.SetTTL("forever", 2, 0); err != nil {
+			t.Fatalf("TestMapExpiry(%s): SetTTL: %s", test.name, err)
+		}
+		if err := m.SetTTL("short-lived-by-kv", 3, time.Hour); err != nil {
+			t.Fatalf("TestMapExpiry(%s): SetTTL: %s", test.name, err)
+		}
+
+		clk.advance(time.Minute)
+
+		if v, ok := m.Get("ttl"); ok || v != 0 {
+			t.Errorf("TestMapExpiry(%s): got Get(ttl) == %d, %v, want the zero value for an expired key", test.name, v, ok)
+		}
+		for k := range m.All() {
+			if k == "ttl" {
+				t.Errorf("TestMapExpiry(%s): All() yielded an expired key", test.name)
+			}
+		}
+
+		n, err := m.Reap(t.Context())
+		switch {
+		case err == nil && test.wantErr:
+			t.Errorf("TestMapExpiry(%s): got err == nil, want err != nil", test.name)
+		case err != nil && !test.wantErr:
+			t.Errorf("TestMapExpiry(%s): got err == %s, want err == nil", test.name, err)
+		}
+		if n != test.wantReaped {
+			t.Errorf("TestMapExpiry(%s): got %d reaped, want %d", test.name, n, test.wantReaped)
+		}
+		if diff := pretty.Compare(test.wantM, m.M); diff != "" {
+			t.Errorf("TestMapExpiry(%s): map -want/+got:\n%s", test.name, diff)
+		}
+		if diff := pretty.Compare(test.wantDeletes, deletes); diff != "" {
+			t.Errorf("TestMapExpiry(%s): DeleteAccept calls -want/+got:\n%s", test.name, diff)
+		}
+	}
+}
+
+// TestMapExpiryRefresh verifies that setting a key again restarts its TTL, so a key that keeps being refreshed
+// is never reaped.
+func TestMapExpiryRefresh(t *testing.T) {
+	clk := newFakeClock()
+	m := &Map[string, int]{M: map[string]int{}, TTL: time.Minute, now: clk.now}
+
+	if err := m.Set("a", 1); err != nil {
+		t.Fatalf("TestMapExpiryRefresh: Set: %s", err)
+	}
+	clk.advance(50 * time.Second)
+	if err := m.Set("a", 2); err != nil {
+		t.Fatalf("TestMapExpiryRefresh: Set: %s", err)
+	}
+	clk.advance(50 * time.Second)
+
+	if n, err := m.Reap(t.Context()); n != 0 || err != nil {
+		t.Errorf("TestMapExpiryRefresh: got Reap() == (%d, %v), want (0, nil)", n, err)
+	}
+	if v, ok := m.Get("a"); !ok || v != 2 {
+		t.Errorf("TestMapExpiryRefresh: got Get(a) == (%d, %v), want (2, true)", v, ok)
+	}
+}
+
+func TestMapMaxSize(t *testing.T) {
+	boom := errors.New("boom")
+
+	tests := []struct {
+		name         string
+		deleteAccept func(key string, prev int, found bool) (bool, error)
+		setAccept    func(key string, val, prev int, replaced bool) (bool, error)
+		// get is a key read before the new key is set, which makes it the most recently used.
+		get string
+		// expire is a key set with a TTL that has passed by the time the new key is set.
+		expire  string
+		wantErr error
+		want    map[string]int
+	}{
+		{
+			name: "Success: the least recently set key is evicted",
+			want: map[string]int{"b": 2, "c": 3, "d": 4},
+		},
+		{
+			name: "Success: a Get makes a key recently used",
+			get:  "a",
+			want: map[string]int{"a": 1, "c": 3, "d": 4},
+		},
+		{
+			name:   "Success: an expired key is evicted before the least recently used",
+			expire: "b",
+			want:   map[string]int{"a": 1, "c": 3, "d": 4},
+		},
+		{
+			name:      "Success: a key SetAccept rejects evicts nothing",
+			setAccept: func(key string, val, prev int, replaced bool) (bool, error) { return false, nil },
+			want:      map[string]int{"a": 1, "b": 2, "c": 3},
+		},
+		{
+			name: "Success: a key DeleteAccept refuses to evict is skipped",
+			deleteAccept: func(key string, prev int, found bool) (bool, error) {
+				return key != "a", nil
+			},
+			want: map[string]int{"a": 1, "c": 3, "d": 4},
+		},
+		{
+			name:         "Error: ErrFull when DeleteAccept refuses every eviction",
+			deleteAccept: func(key string, prev int, found bool) (bool, error) { return false, nil },
+			wantErr:      ErrFull,
+			want:         map[string]int{"a": 1, "b": 2, "c": 3},
+		},
+		{
+			name:         "Error: a DeleteAccept error stops the Set",
+			deleteAccept: func(key string, prev int, found bool) (bool, error) { return false, boom },
+			wantErr:      boom,
+			want:         map[string]int{"a": 1, "b": 2, "c": 3},
+		},
+		{
+			name:      "Error: a SetAccept error evicts nothing",
+			setAccept: func(key string, val, prev int, replaced bool) (bool, error) { return false, boom },
+			wantErr:   boom,
+			want:      map[string]int{"a": 1, "b": 2, "c": 3},
+		},
+	}
+
+	for _, test := range tests {
+		clk := newFakeClock()
+		m := &Map[string, int]{M: map[string]int{}, MaxSize: 3, now: clk.now}
+		for i, k := range []string{"a", "b", "c"} {
+			ttl := time.Duration(0)
+			if k == test.expire {
+				ttl = time.Minute
+			}
+			if err := m.SetTTL(k, i+1, ttl); err != nil {
+				t.Fatalf("TestMapMaxSize(%s): Set(%s): %s", test.name, k, err)
+			}
+		}
+		clk.advance(time.Hour)
+		m.SetAccept = test.setAccept
+		m.DeleteAccept = test.deleteAccept
+		if test.get != "" {
+			m.Get(test.get)
+		}
+
+		err := m.Set("d", 4)
+		if !errors.Is(err, test.wantErr) {
+			t.Errorf("TestMapMaxSize(%s): got err == %v, want %v", test.name, err, test.wantErr)
+		}
+		if diff := pretty.Compare(test.want, m.M); diff != "" {
+			t.Errorf("TestMapMaxSize(%s): map -want/+got:\n%s", test.name, diff)
+		}
+	}
+}
+
+// TestMapMaxSizeRetry verifies a Set whose SetAccept fails transiently leaves a full Map's size alone on every
+// attempt, so a Feeder retrying it does not drain the Map.
+func TestMapMaxSizeRetry(t *testing.T) {
+	boom := errors.New("boom")
+	fails := 3
+	m := &Map[string, int]{
+		M:       map[string]int{"a": 1, "b": 2},
+		MaxSize: 2,
+		SetAccept: func(key string, val, prev int, replaced bool) (bool, error) {
+			if key == "c" && fails > 0 {
+				fails--
+				return false, boom
+			}
+			return true, nil
+		},
+	}
+
+	for i := 0; i < 3; i++ {
+		if err := m.Set("c", 3); !errors.Is(err, boom) {
+			t.Fatalf("TestMapMaxSizeRetry: attempt %d: got err == %v, want boom", i, err)
+		}
+		if len(m.M) != 2 {
+			t.Fatalf("TestMapMaxSizeRetry: attempt %d: got %d keys, want 2", i, len(m.M))
+		}
+	}
+	if err := m.Set("c", 3); err != nil {
+		t.Fatalf("TestMapMaxSizeRetry: Set: %s", err)
+	}
+	if len(m.M) != 2 || m.M["c"] != 3 {
+		t.Errorf("TestMapMaxSizeRetry: got %v, want c set in place of one key", m.M)
+	}
+}
+
+// TestMapSetExpired verifies SetAccept sees a key that has expired but not been reaped as a new key.
+func TestMapSetExpired(t *testing.T) {
+	clk := newFakeClock()
+	type call struct {
+		Prev     int
+		Replaced bool
+	}
+	var got []call
+	m := &Map[string, int]{
+		M:   map[string]int{},
+		TTL: time.Minute,
+		now: clk.now,
+		SetAccept: func(key string, val, prev int, replaced bool) (bool, error) {
+			got = append(got, call{prev, replaced})
+			return true, nil
+		},
+	}
+	sm := &ShardedMap[string, int]{
+		TTL: time.Minute,
+		now: clk.now,
+		SetAccept: func(key string, val, prev int, replaced bool) (bool, error) {
+			got = append(got, call{prev, replaced})
+			return true, nil
+		},
+	}
+
+	for _, v := range []Value[string, int]{m, sm} {
+		for _, n := range []int{1, 2} {
+			if err := v.Set("a", n); err != nil {
+				t.Fatalf("TestMapSetExpired: Set: %s", err)
+			}
+		}
+		clk.advance(time.Hour)
+		if err := v.Set("a", 3); err != nil {
+			t.Fatalf("TestMapSetExpired: Set: %s", err)
+		}
+	}
+
+	want := []call{{0, false}, {1, true}, {0, false}, {0, false}, {1, true}, {0, false}}
+	if diff := pretty.Compare(want, got); diff != "" {
+		t.Errorf("TestMapSetExpired: -want/+got:\n%s", diff)
+	}
+}
+
+// TestMapMaxSizeReplace verifies that replacing an existing key in a full Map evicts nothing.
+func TestMapMaxSizeReplace(t *testing.T) {
+	m := &Map[string, int]{M: map[string]int{}, MaxSize: 2}
+	for _, k := range []string{"a", "b", "a"} {
+		if err := m.Set(k, len(k)); err != nil {
+			t.Fatalf("TestMapMaxSizeReplace: Set(%s): %s", k, err)
+		}
+	}
+	if diff := pretty.Compare(map[string]int{"a": 1, "b": 1}, m.M); diff != "" {
+		t.Errorf("TestMapMaxSizeReplace: map -want/+got:\n%s", diff)
+	}
+}
+
+func TestShardedMapExpiry(t *testing.T) {
+	clk := newFakeClock()
+	var deletes []string
+	m := &ShardedMap[string, int]{
+		TTL: time.Minute,
+		DeleteAccept: func(key string, prev int, found bool) (bool, error) {
+			deletes = append(deletes, key)
+			return true, nil
+		},
+		now: clk.now,
+	}
+
+	if err := m.Set("ttl", 1); err != nil {
+		t.Fatalf("TestShardedMapExpiry: Set: %s", err)
+	}
+	if err := m.SetTTL("forever", 2, 0); err != nil {
+		t.Fatalf("TestShardedMapExpiry: SetTTL: %s", err)
+	}
+	clk.advance(time.Minute)
+
+	if _, ok := m.Get("ttl"); ok {
+		t.Errorf("TestShardedMapExpiry: Get(ttl) found an expired key")
+	}
+	if diff := pretty.Compare(map[string]int{"forever": 2}, collectSharded(m)); diff != "" {
+		t.Errorf("TestShardedMapExpiry: All() -want/+got:\n%s", diff)
+	}
+
+	n, err := m.Reap(t.Context())
+	if err != nil {
+		t.Fatalf("TestShardedMapExpiry: Reap: %s", err)
+	}
+	if n != 1 {
+		t.Errorf("TestShardedMapExpiry: got %d reaped, want 1", n)
+	}
+	if diff := pretty.Compare([]string{"ttl"}, deletes); diff != "" {
+		t.Errorf("TestShardedMapExpiry: DeleteAccept calls -want/+got:\n%s", diff)
+	}
+	if m.m.Len() != 1 {
+		t.Errorf("TestShardedMapExpiry: got %d keys stored, want 1", m.m.Len())
+	}
+}
+
+// TestFeedTTL verifies that a KeyVal's TTL reaches a Value that implements Expirer and is a permanent error
+// for one that does not.
+func TestFeedTTL(t *testing.T) {
+	tests := []struct {
+		name string
+		// value returns the Value to feed and a Get for it, or a nil Get when the Value cannot hold a TTL.
+		value   func(clk *fakeClock) (Value[string, int], func(k string) (int, bool))
+		wantErr bool
+	}{
+		{
+			name: "Success: the KeyVal TTL expires the key in a Map",
+			value: func(clk *fakeClock) (Value[string, int], func(k string) (int, bool)) {
+				m := &Map[string, int]{M: map[string]int{}, now: clk.now}
+				return m, m.Get
+			},
+		},
+		{
+			name: "Success: the KeyVal TTL expires the key in a ShardedMap",
+			value: func(clk *fakeClock) (Value[string, int], func(k string) (int, bool)) {
+				m := &ShardedMap[string, int]{now: clk.now}
+				return m, m.Get
+			},
+		},
+		{
+			name: "Error: a Value that is not an Expirer rejects a TTL",
+			value: func(clk *fakeClock) (Value[string, int], func(k string) (int, bool)) {
+				return plainValue{}, nil
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, test := range tests {
+		clk := newFakeClock()
+		v, get := test.value(clk)
+		f, err := NewFeeder[string, int](t.Context(), v)
+		if err != nil {
+			t.Fatalf("TestFeedTTL(%s): NewFeeder: %s", test.name, err)
+		}
+
+		err = <-f.Feed(t.Context(), staticPipe([]KeyVal[string, int]{{Op: Add, K: "a", V: 1, TTL: time.Minute}}, nil))
+		switch {
+		case err == nil && test.wantErr:
+			t.Errorf("TestFeedTTL(%s): got err == nil, want err != nil", test.name)
+			continue
+		case err != nil && !test.wantErr:
+			t.Errorf("TestFeedTTL(%s): got err == %s, want err == nil", test.name, err)
+			continue
+		case err != nil:
+			if !errors.Is(err, ErrPermanent) {
+				t.Errorf("TestFeedTTL(%s): got err == %s, want it to wrap ErrPermanent", test.name, err)
+			}
+			continue
+		}
+
+		if _, ok := get("a"); !ok {
+			t.Errorf("TestFeedTTL(%s): key missing before its TTL", test.name)
+		}
+		clk.advance(time.Minute)
+		if _, ok := get("a"); ok {
+			t.Errorf("TestFeedTTL(%s): key found after its TTL", test.name)
+		}
+	}
+}
+
+// plainValue is a Value that does not implement Expirer.
+type plainValue struct{}
+
+func (plainValue) Set(k string, v int) error { return nil }
+func (plainValue) Delete(k string) error     { return nil }
+
+func TestMapMetrics(t *testing.T) {
+	reader := sdkmetric.NewManualReader()
+	ctx := context.SetMeterProvider(t.Context(), sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader)))
+
+	clk := newFakeClock()
+	m := &Map[string, int]{M: map[string]int{}, MaxSize: 2, TTL: time.Minute, Name: "test", now: clk.now}
+	// Reap first so the Map takes its MeterProvider from ctx rather than context.Background().
+	if _, err := m.Reap(ctx); err != nil {
+		t.Fatalf("TestMapMetrics: Reap: %s", err)
+	}
+	for i, k := range []string{"a", "b", "c"} {
+		if err := m.Set(k, i); err != nil {
+			t.Fatalf("TestMapMetrics: Set(%s): %s", k, err)
+		}
+	}
+	clk.advance(time.Minute)
+	if _, err := m.Reap(ctx); err != nil {
+		t.Fatalf("TestMapMetrics: Reap: %s", err)
+	}
+
+	var rm metricdata.ResourceMetrics
+	if err := reader.Collect(ctx, &rm); err != nil {
+		t.Fatalf("TestMapMetrics: could not collect metrics: %s", err)
+	}
+	got := map[string]int64{}
+	for _, sm := range rm.ScopeMetrics {
+		for _, item := range sm.Metrics {
+			if sum, ok := item.Data.(metricdata.Sum[int64]); ok {
+				for _, dp := range sum.DataPoints {
+					got[item.Name] += dp.Value
+				}
+			}
+		}
+	}
+	if diff := pretty.Compare(map[string]int64{"evictions": 1, "expirations": 2}, got); diff != "" {
+		t.Errorf("TestMapMetrics: -want/+got:\n%s", diff)
+	}
+}
@@ -32,23 +32,50 @@
 // to stop retries:
 //
 //	f, err := feeder.NewFeeder[string, int](ctx, m, feeder.WithRetry[string, int](backoff))
+//
+// Map and ShardedMap can expire keys. Set TTL on the Value to give every key a lifetime, or KeyVal.TTL
+// to give a single key its own. Expired keys are hidden from Get and All and are deleted through
+// DeleteAccept by Reap, or by the background reaper StartReaper runs, so the side effects of a delete
+// fire for an expiry too. A Map can also be bounded with MaxSize, evicting its least recently used key:
+//
+//	m := &feeder.Map[string, int]{M: map[string]int{}, TTL: 5 * time.Minute, MaxSize: 10000, Name: "sessions"}
+//	m.StartReaper(ctx, 30*time.Second)
+//
+// When the source delivers full snapshots rather than changes, Reconcile diffs a snapshot against the Value and
+// applies the adds and deletes that make them match.
+//
+// A Group changes several Values together, such as a Map and a secondary index of it, rolling back every change it
+// made if a later one fails. A Group is itself a Value, so a Feeder can feed it.
+//
+// Pass WithName to a Feeder, or set Name on a Map or ShardedMap, to record OTEL metrics with the MeterProvider
+// in the Context. Every Feed attempt is also recorded as events on the span in the Context passed to Feed.
 package feeder
 
 import (
 	"cmp"
+	"container/list"
+	"errors"
 	"fmt"
 	"iter"
+	"time"
 
 	"github.com/gostdlib/base/concurrency/sync"
 	"github.com/gostdlib/base/context"
 	"github.com/gostdlib/base/retry/exponential"
+	"github.com/gostdlib/base/telemetry/otel/trace/span"
 	"github.com/gostdlib/base/values/generics/result"
+
+	"go.opentelemetry.io/otel/attribute"
 )
 
 // ErrPermanent prevents a retry from happening. Usually added to an existing error with
 // fmt.Errorf("%w: %w", origErr, ErrPermanent).
 var ErrPermanent = exponential.ErrPermanent
 
+// ErrFull is returned by a Map with MaxSize set when a new key cannot be added because DeleteAccept
+// rejected the eviction of every key. It is not permanent: a retry can succeed once a key is deleted.
+var ErrFull = errors.New("feeder: Map is full and no key could be evicted")
+
 // Value is a generic constraint that is used to define the behavior of some type of value that is going to be
 // mutated.
 type Value[K cmp.Ordered, V any] interface {
@@ -60,6 +87,13 @@ type Value[K cmp.Ordered, V any] interface {
 	Delete(k K) error
 }
 
+// Expirer is implemented by a Value that can expire keys. Feeder uses it to apply a KeyVal with a TTL;
+// Map and ShardedMap implement it.
+type Expirer[K cmp.Ordered, V any] interface {
+	// SetTTL is Set, except the key expires ttl after it is set. A ttl <= 0 means the key never expires.
+	SetTTL(k K, v V, ttl time.Duration) error
+}
+
 // Map provides a Value type that uses a map[K]V protected by a sync.RWMutex.
 type Map[K cmp.Ordered, V any] struct {
 	// M is the map to set. You should not access the map via M, use the accessor methods.
@@ -72,67 +106,158 @@ type Map[K cmp.Ordered, V any] struct {
 	SetAccept func(key K, val, prev V, replaced bool) (bool, error)
 	// DeleteAccept is called with the key, the value being deleted, and whether the value was found. If it returns
 	// true then the value can be deleted. If nil, all deletes are accepted. Like SetAccept, it runs while the write
-	// lock is held and is the place for side effects that must be atomic with the delete.
+	// lock is held and is the place for side effects that must be atomic with the delete. Expiry and eviction
+	// delete through DeleteAccept as well.
 	DeleteAccept func(key K, prev V, found bool) (bool, error)
+	// TTL, if > 0, is how long a key lives after it is Set. SetTTL (used for a KeyVal with a TTL) overrides it
+	// for a single key. An expired key is hidden from Get and All but stays in the Map until Reap deletes it.
+	TTL time.Duration
+	// MaxSize, if > 0, bounds the number of keys. Once SetAccept accepts a new key for a full Map, an expired key,
+	// or failing that the least recently used key, is evicted through DeleteAccept to make room, skipping keys
+	// whose eviction it rejects. If no key can be evicted the Set fails after SetAccept has run, so SetAccept's
+	// side effects must bear being repeated by a retry. Set and Get count as a use, so with MaxSize set Get takes
+	// the write lock.
+	MaxSize int
+	// Name namespaces the metrics this Map records. If empty, no metrics are recorded. The MeterProvider comes
+	// from the Context of the first StartReaper() or Reap() call, or context.Background() if the Map is used
+	// before either. Name must be set before first use and must not change after that.
+	Name string
 
 	mu sync.RWMutex
+	// expires is the expiry of every key that has one. Guarded by mu.
+	expires map[K]time.Time
+	// lru orders keys from least (front) to most (back) recently used when MaxSize > 0, and elems finds a
+	// key's place in it. Both are built on first use. Guarded by mu.
+	lru   *list.List
+	elems map[K]*list.Element
+
+	once    sync.Once
+	metrics *mapMetrics
+	// now is time.Now unless a test replaces it.
+	now func() time.Time
 }
 
-// Set implements Value.Set().
+// Set implements Value.Set(). The key expires after TTL if it is set.
 func (m *Map[K, V]) Set(k K, v V) error {
+	return m.set(k, v, m.TTL)
+}
+
+// SetTTL implements Expirer.SetTTL().
+func (m *Map[K, V]) SetTTL(k K, v V, ttl time.Duration) error {
+	return m.set(k, v, ttl)
+}
+
+func (m *Map[K, V]) set(k K, v V, ttl time.Duration) error {
+	m.init(context.Background())
+
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	prev, exists := m.M[k]
-	accept, err := true, error(nil)
+	_, err := m.setLocked(k, v, ttl)
+	return err
+}
+
+// setLocked is set with m.mu held. applied reports whether v was stored.
+func (m *Map[K, V]) setLocked(k K, v V, ttl time.Duration) (applied bool, err error) {
+	now := m.clock()
+	prev, present := m.M[k]
+	// An expired key is not replaced as far as SetAccept is concerned, though it is still in M until reaped.
+	exists := present && !m.expired(k, now)
+	if !exists {
+		var zero V
+		prev = zero
+	}
+	accept := true
 	if m.SetAccept != nil {
 		accept, err = m.SetAccept(k, v, prev, exists)
 	}
 	if err != nil {
-		return err
+		return false, err
 	}
 	if !accept {
-		return nil
+		m.rejected(Add)
+		return false, nil
+	}
+	// Make room only once the key is accepted, so a Set that is rejected, or fails and is retried, evicts nothing.
+	if !present && m.MaxSize > 0 && len(m.M) >= m.MaxSize {
+		if err := m.evict(now); err != nil {
+			return false, err
+		}
 	}
 	m.M[k] = v
-	return nil
+	m.track(k, ttl)
+	return true, nil
 }
 
 // Delete deletes k in the map. It is a no-op on a non-found keys.
 func (m *Map[K, V]) Delete(k K) error {
+	m.init(context.Background())
+
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
+	_, err := m.deleteLocked(k)
+	return err
+}
+
+// deleteLocked is Delete with m.mu held. applied reports whether k was deleted.
+func (m *Map[K, V]) deleteLocked(k K) (applied bool, err error) {
 	prev, exists := m.M[k]
-	accept, err := true, error(nil)
-	if m.DeleteAccept != nil {
-		accept, err = m.DeleteAccept(k, prev, exists)
-	}
+	accept, err := m.deleteAccept(k, prev, exists)
 	if err != nil {
-		return err
+		return false, err
 	}
 	if !accept {
-		return nil
+		m.rejected(Delete)
+		return false, nil
 	}
 	delete(m.M, k)
-	return nil
+	m.untrack(k)
+	return exists, nil
+}
+
+// deleteAccept runs DeleteAccept, accepting when it is nil. m.mu must be held.
+func (m *Map[K, V]) deleteAccept(k K, prev V, found bool) (bool, error) {
+	if m.DeleteAccept == nil {
+		return true, nil
+	}
+	return m.DeleteAccept(k, prev, found)
 }
 
-// Get gets value at key k. ok indicates if the key was found. Protected with RLock().
+// Get gets value at key k. ok indicates if the key was found. An expired key is not found. Protected with RLock(),
+// or Lock() when MaxSize is set, as a Get moves the key to the back of the eviction order.
 func (m *Map[K, V]) Get(k K) (v V, ok bool) {
+	if m.MaxSize > 0 {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		if v, ok = m.M[k]; ok && !m.expired(k, m.clock()) {
+			m.touch(k)
+			return v, true
+		}
+		var zero V
+		return zero, false
+	}
+
 	m.mu.RLock()
-	v, ok = m.M[k]
-	m.mu.RUnlock()
+	defer m.mu.RUnlock()
+	if v, ok = m.M[k]; ok && m.expired(k, m.clock()) {
+		var zero V
+		return zero, false
+	}
 	return v, ok
 }
 
-// All read locks the map and ranges over all items in it. Only use this if the map is small.
-// For larger maps, consider ShardedMap.
+// All read locks the map and ranges over all items in it, skipping expired keys. Only use this if the map is
+// small. For larger maps, consider ShardedMap.
 func (m *Map[K, V]) All() iter.Seq2[K, V] {
 	return func(yield func(K, V) bool) {
 		m.mu.RLock()
 		defer m.mu.RUnlock()
+		now := m.clock()
 		for k, v := range m.M {
+			if m.expired(k, now) {
+				continue
+			}
 			if !yield(k, v) {
 				return
 			}
@@ -140,10 +265,12 @@ func (m *Map[K, V]) All() iter.Seq2[K, V] {
 	}
 }
 
-// ShardedMap provides a Value type that uses a sync.ShardedMap internally.
+// ShardedMap provides a Value type that uses a sync.ShardedMap internally. It expires keys like Map but has no
+// MaxSize: least recently used order is a single list that every Get and Set must update, which would put one
+// lock back in front of every shard. Use Map for a bounded cache.
 type ShardedMap[K cmp.Ordered, V any] struct {
 	// m is the map to set.  You should not access the map via m, use the accessor methods.
-	m sync.ShardedMap[K, V]
+	m sync.ShardedMap[K, entry[V]]
 	// SetAccept is called with the key, the incoming value, the previous value, and whether an existing value
 	// is being replaced. It returns whether we accept the change and an error. Errors always prevent the change
 	// and are retried unless they wrap ErrPermanent. If nil, all changes are accepted. Because it runs while the
@@ -152,47 +279,117 @@ type ShardedMap[K cmp.Ordered, V any] struct {
 	SetAccept func(key K, val, prev V, replaced bool) (bool, error)
 	// DeleteAccept is called with the key, the value being deleted, and whether the value was found. If it returns
 	// true then the value can be deleted. If nil, all deletes are accepted. Like SetAccept, it runs while the write
-	// lock is held and is the place for side effects that must be atomic with the delete.
+	// lock is held and is the place for side effects that must be atomic with the delete. Expiry deletes through
+	// DeleteAccept as well.
 	DeleteAccept func(key K, prev V, found bool) (bool, error)
+	// TTL, if > 0, is how long a key lives after it is Set. SetTTL (used for a KeyVal with a TTL) overrides it
+	// for a single key. An expired key is hidden from Get and All but stays in the map until Reap deletes it.
+	TTL time.Duration
+	// Name namespaces the metrics this ShardedMap records. It follows the same rules as Map.Name.
+	Name string
+
+	once    sync.Once
+	metrics *mapMetrics
+	// now is time.Now unless a test replaces it.
+	now func() time.Time
 }
 
-// Set implements Value.Set().
+// Set implements Value.Set(). The key expires after TTL if it is set.
 func (m *ShardedMap[K, V]) Set(k K, v V) error {
-	var err error
-	m.m.SetAccept(k, v, func(prev V, replaced bool) bool {
+	_, err := m.set(k, v, m.TTL)
+	return err
+}
+
+// SetTTL implements Expirer.SetTTL().
+func (m *ShardedMap[K, V]) SetTTL(k K, v V, ttl time.Duration) error {
+	_, err := m.set(k, v, ttl)
+	return err
+}
+
+// set stores v at k through SetAccept. applied reports whether v was stored.
+func (m *ShardedMap[K, V]) set(k K, v V, ttl time.Duration) (applied bool, err error) {
+	m.init(context.Background())
+
+	now := m.clock()
+	e := entry[V]{v: v}
+	if ttl > 0 {
+		e.expires = now.Add(ttl)
+	}
+
+	rejected := false
+	m.m.SetAccept(k, e, func(prev entry[V], replaced bool) bool {
 		if m.SetAccept == nil {
 			return true
 		}
+		// An expired key is not replaced as far as SetAccept is concerned, though it is still stored until reaped.
+		if replaced && prev.expired(now) {
+			prev, replaced = entry[V]{}, false
+		}
 		var b bool
-		b, err = m.SetAccept(k, v, prev, replaced)
+		b, err = m.SetAccept(k, v, prev.v, replaced)
 		if err != nil {
 			return false
 		}
+		rejected = !b
 		return b
 	})
-	return err
+	if rejected {
+		m.rejected(Add)
+	}
+	return err == nil && !rejected, err
 }
 
 // Delete implements Value.Delete().
 func (m *ShardedMap[K, V]) Delete(k K) error {
-	var err error
-	m.m.DeleteAccept(k, func(prev V, found bool) bool {
+	_, err := m.del(k)
+	return err
+}
+
+// del deletes k through DeleteAccept. applied reports whether k was deleted.
+func (m *ShardedMap[K, V]) del(k K) (applied bool, err error) {
+	m.init(context.Background())
+
+	rejected := false
+	_, deleted := m.m.DeleteAccept(k, func(prev entry[V], found bool) bool {
 		if m.DeleteAccept == nil {
 			return true
 		}
 		var b bool
-		b, err = m.DeleteAccept(k, prev, found)
+		b, err = m.DeleteAccept(k, prev.v, found)
 		if err != nil {
 			return false
 		}
+		rejected = !b
 		return b
 	})
-	return err
+	if rejected {
+		m.rejected(Delete)
+	}
+	return deleted, err
+}
+
+// Get gets the value at key k. ok indicates if the key was found. An expired key is not found.
+func (m *ShardedMap[K, V]) Get(k K) (v V, ok bool) {
+	e, ok := m.m.Get(k)
+	if !ok || e.expired(m.clock()) {
+		return v, false
+	}
+	return e.v, true
 }
 
-// All read locks various shards in the map and ranges over all items in them.
+// All read locks various shards in the map and ranges over all items in them, skipping expired keys.
 func (m *ShardedMap[K, V]) All() iter.Seq2[K, V] {
-	return m.m.All(sync.WithLock())
+	return func(yield func(K, V) bool) {
+		now := m.clock()
+		for k, e := range m.m.All(sync.WithLock()) {
+			if e.expired(now) {
+				continue
+			}
+			if !yield(k, e.v) {
+				return
+			}
+		}
+	}
 }
 
 // Op is an operation to do on a key in our Value.
@@ -217,6 +414,9 @@ type KeyVal[K cmp.Ordered, V any] struct {
 	K K
 	// V is the value. Should not be set if Op is Delete.
 	V V
+	// TTL, if > 0, expires the key TTL after it is added, overriding any TTL the Value has. The Value must
+	// implement Expirer. Ignored if Op is Delete.
+	TTL time.Duration
 	// Result is the result of the operation. If nil this will not be set.
 	Result *result.Value[struct{}]
 
@@ -228,7 +428,8 @@ type KeyVal[K cmp.Ordered, V any] struct {
 // KVPipeline returns a channel that is used to feed a Value type. Once the returned channel is closed
 // the feeder will close, unless the final value is KeyVal.Err != nil. In that case if Feeder has
 // WithRetry() set, it will call KVPipeline again to re-establish the connection. A KVPipeline should honor
-// a Context timeout until it returns the channel, after which that timeout is not honored. A caller
+// a Context timeout until it returns the channel, after which that timeout is not honored. The Context is
+// cancelled once the Feeder stops reading the channel, so a producer should stop when it is done. A caller
 // can call stop() in order to stop the pipeline. If there is some error to startup, it should be returned.
 // If that should not be retried, then ErrPermanent should be included.
 type KVPipeline[K cmp.Ordered, V any] func(context.Context) (pipe chan KeyVal[K, V], stop chan struct{}, err error)
@@ -237,6 +438,11 @@ type KVPipeline[K cmp.Ordered, V any] func(context.Context) (pipe chan KeyVal[K,
 type Feeder[K cmp.Ordered, V any] struct {
 	v    Value[K, V]
 	back *exponential.Backoff
+
+	name    string
+	metrics *feederMetrics
+	// equal compares values for Reconcile. If nil, reflect.DeepEqual is used.
+	equal func(a, b V) bool
 }
 
 // FeederOption is an option to the NewFeeder constructor.
@@ -250,6 +456,20 @@ func WithRetry[K cmp.Ordered, V any](b *exponential.Backoff) FeederOption[K, V]
 	}
 }
 
+// WithName names the Feeder, which turns on its metrics and namespaces them under name. The MeterProvider comes
+// from the Context passed to NewFeeder(). A Feeder records the ops it applies, the ops that fail, retries and the
+// time spent backing off, pipeline re-establishments and how long each op takes. An op whose change an accept
+// function rejects succeeds as far as the Feeder can tell; name the Map or ShardedMap to count those.
+func WithName[K cmp.Ordered, V any](name string) FeederOption[K, V] {
+	return func(o *Feeder[K, V]) error {
+		if name == "" {
+			return errors.New("feeder.WithName: name cannot be empty")
+		}
+		o.name = name
+		return nil
+	}
+}
+
 // NewFeeder is a constructor for Feeder.
 func NewFeeder[K cmp.Ordered, V any](ctx context.Context, v Value[K, V], options ...FeederOption[K, V]) (*Feeder[K, V], error) {
 	f := &Feeder[K, V]{
@@ -261,6 +481,9 @@ func NewFeeder[K cmp.Ordered, V any](ctx context.Context, v Value[K, V], options
 			return nil, err
 		}
 	}
+	if f.name != "" {
+		f.metrics = newFeederMetrics(context.MeterProvider(ctx).Meter(meterName + "/" + f.name))
+	}
 	return f, nil
 }
 
@@ -268,10 +491,13 @@ func NewFeeder[K cmp.Ordered, V any](ctx context.Context, v Value[K, V], options
 // different pipe constructors you want to feed into our value. When the feed is done, finished will return an error
 // or nil. This starts 1 goroutine per Feed call. Consider ShardedMap Value if doing many feeds.
 //
+// Every attempt to establish and feed the pipeline is recorded as a pair of events on the span in ctx.
+//
 // The pump runs on the default pool, never the Context's pool, which may be Limited: a feeder that had to win a
 // limited slot would wait behind the very Workers it feeds, and against a saturated pool it would never start while
 // the caller blocked on finished forever. Submitting on a WithoutCancel ctx means the pool never declines the pump,
 // so finished always receives exactly one terminal value; the pump honors cancellation through the captured ctx.
+// A Feed whose ctx ends before the pipeline is done sends an error, never nil, on finished.
 func (f *Feeder[K, V]) Feed(ctx context.Context, pipe KVPipeline[K, V]) (finished chan error) {
 	finished = make(chan error, 1)
 
@@ -288,10 +514,14 @@ func (f *Feeder[K, V]) Feed(ctx context.Context, pipe KVPipeline[K, V]) (finishe
 func (f *Feeder[K, V]) pump(ctx context.Context, pipe KVPipeline[K, V], finished chan error) {
 	var err error
 	if f.back == nil {
-		err = f.attempt(ctx, pipe)
+		err = f.attempt(ctx, pipe, exponential.Record{Attempt: 1})
 	} else {
-		err = f.back.Retry(ctx, func(ctx context.Context, _ exponential.Record) error {
-			return f.attempt(ctx, pipe)
+		err = f.back.Retry(ctx, func(ctx context.Context, r exponential.Record) error {
+			if r.Attempt > 1 && f.metrics != nil {
+				f.metrics.Reestablishments.Add(ctx, 1)
+				f.metrics.Backoff.Add(ctx, r.LastInterval.Seconds(), pipelineAttr)
+			}
+			return f.attempt(ctx, pipe, r)
 		})
 	}
 	finished <- err
@@ -299,58 +529,116 @@ func (f *Feeder[K, V]) pump(ctx context.Context, pipe KVPipeline[K, V], finished
 }
 
 // attempt establishes the pipeline once and feeds it into the Value until the pipe closes or an error occurs.
-func (f *Feeder[K, V]) attempt(ctx context.Context, pipe KVPipeline[K, V]) error {
-	p, stop, err := pipe(ctx)
-	if err != nil {
-		return err
+// r is the backoff's record of this attempt.
+func (f *Feeder[K, V]) attempt(ctx context.Context, pipe KVPipeline[K, V], r exponential.Record) error {
+	spanner := span.Get(ctx)
+	spanner.Event("feeder.Feed: attempt started", attribute.Int("attempt", r.Attempt))
+
+	var (
+		applied int
+		err     error
+	)
+	// Each establishment gets a Context of its own, cancelled once the attempt is over, so a producer still
+	// sending on a pipe this attempt stopped reading, after an op failed, is released instead of leaking.
+	pctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	p, stop, err := pipe(pctx)
+	if err == nil {
+		applied, err = f.feed(ctx, p, stop)
+	}
+	// A pipe closed because ctx ended was cut short, not finished: report it, so a truncated feed does not look
+	// like a complete one.
+	if err == nil {
+		err = ctx.Err()
+	}
+
+	if spanner.IsRecording() {
+		attrs := []attribute.KeyValue{attribute.Int("attempt", r.Attempt), attribute.Int("applied", applied)}
+		if err != nil {
+			attrs = append(attrs, attribute.String("error", err.Error()))
+		}
+		spanner.Event("feeder.Feed: attempt ended", attrs...)
 	}
-	return f.feed(ctx, p, stop)
+	return err
 }
 
-func (f *Feeder[K, V]) feed(ctx context.Context, p chan KeyVal[K, V], stop chan struct{}) error {
+// feed applies every KeyVal received on p to the Value until p closes, stop closes or an error occurs. applied is
+// the number of ops that were applied.
+func (f *Feeder[K, V]) feed(ctx context.Context, p chan KeyVal[K, V], stop chan struct{}) (applied int, err error) {
 	for {
 		select {
 		case <-stop:
-			return nil
+			return applied, nil
 		case kv, ok := <-p:
 			if !ok {
-				return nil
+				return applied, nil
 			}
 			if kv.Err != nil {
-				return kv.Err
+				return applied, kv.Err
 			}
+			start := time.Now()
 			switch kv.Op {
 			case Add:
-				err := f.set(ctx, kv.K, kv.V)
-				if kv.Result != nil {
-					kv.Result.Set(struct{}{}, err)
-				}
-				if err != nil {
-					return err
-				}
+				err = f.set(ctx, kv.K, kv.V, kv.TTL)
 			case Delete:
-				err := f.delete(ctx, kv.K)
-				if kv.Result != nil {
-					kv.Result.Set(struct{}{}, err)
-				}
-				if err != nil {
-					return err
-				}
+				err = f.delete(ctx, kv.K)
 			default:
 				panic(fmt.Sprintf("cannot send an Op with code %v", kv.Op))
 			}
+			f.record(ctx, kv.Op, time.Since(start), err)
+			if kv.Result != nil {
+				kv.Result.Set(struct{}{}, err)
+			}
+			if err != nil {
+				return applied, err
+			}
+			applied++
 		}
 	}
 }
 
-func (f *Feeder[K, V]) set(ctx context.Context, k K, v V) error {
+// record records the outcome of an op that took d if the Feeder has metrics.
+func (f *Feeder[K, V]) record(ctx context.Context, op Op, d time.Duration, err error) {
+	if f.metrics == nil {
+		return
+	}
+	attr := opAttr(op)
+	if err != nil {
+		f.metrics.Errors.Add(ctx, 1, attr)
+	} else {
+		f.metrics.Ops.Add(ctx, 1, attr)
+	}
+	f.metrics.Latency.Record(ctx, d.Seconds(), attr)
+}
+
+// retried records that op is being retried after waiting r.LastInterval if the Feeder has metrics.
+func (f *Feeder[K, V]) retried(ctx context.Context, op Op, r exponential.Record) {
+	if r.Attempt <= 1 || f.metrics == nil {
+		return
+	}
+	attr := opAttr(op)
+	f.metrics.Retries.Add(ctx, 1, attr)
+	f.metrics.Backoff.Add(ctx, r.LastInterval.Seconds(), attr)
+}
+
+func (f *Feeder[K, V]) set(ctx context.Context, k K, v V, ttl time.Duration) error {
+	setter := f.v.Set
+	if ttl > 0 {
+		e, ok := f.v.(Expirer[K, V])
+		if !ok {
+			return fmt.Errorf("feeder: KeyVal.TTL is set but Value %T does not implement Expirer: %w", f.v, ErrPermanent)
+		}
+		setter = func(k K, v V) error { return e.SetTTL(k, v, ttl) }
+	}
+
 	if f.back == nil {
-		return f.v.Set(k, v)
+		return setter(k, v)
 	}
 	return f.back.Retry(
 		ctx,
 		func(ctx context.Context, r exponential.Record) error {
-			return f.v.Set(k, v)
+			f.retried(ctx, Add, r)
+			return setter(k, v)
 		},
 	)
 }
@@ -362,6 +650,7 @@ func (f *Feeder[K, V]) delete(ctx context.Context, k K) error {
 	return f.back.Retry(
 		ctx,
 		func(ctx context.Context, r exponential.Record) error {
+			f.retried(ctx, Delete, r)
 			return f.v.Delete(k)
 		},
 	)
@@ -0,0 +1,305 @@
+package feeder
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gostdlib/base/concurrency/sync"
+	"github.com/gostdlib/base/context"
+)
+
+// ErrRollback is joined to the error a Group returns when it could not undo a change it had made before a later
+// change failed. The Values may no longer agree with each other.
+var ErrRollback = errors.New("feeder: Group could not roll back a change")
+
+// Store is a Value that can also get the value at a key. A Group needs to read what a change replaces so it can put
+// it back. Map and ShardedMap implement it.
+type Store[K cmp.Ordered, V any] interface {
+	Value[K, V]
+	// Get gets the value at key k. ok indicates if the key was found.
+	Get(k K) (v V, ok bool)
+}
+
+// undo reverses a change a Member made.
+type undo func() error
+
+// changer is implemented by a Store a Group can put back exactly as it was. Map and ShardedMap implement it.
+type changer[K cmp.Ordered, V any] interface {
+	// change applies op to k, with v for an Add, like Set or Delete, and reports whether it was applied: an accept
+	// function rejecting it without an error is not. restore puts k's entry back as it was before, with its
+	// expiry, without calling the accept functions and without evicting.
+	change(op Op, k K, v V) (applied bool, restore func(), err error)
+}
+
+// change applies op to k in s and returns whether it was applied and how to undo it, nil if it was not. A Store
+// that implements changer is put back exactly; any other Store is undone with a Set or Delete of what it held.
+func change[K cmp.Ordered, V any](s Store[K, V], op Op, k K, v V) (undo, error) {
+	if c, ok := s.(changer[K, V]); ok {
+		applied, restore, err := c.change(op, k, v)
+		if err != nil || !applied {
+			return nil, err
+		}
+		return func() error {
+			restore()
+			return nil
+		}, nil
+	}
+
+	prev, found := s.Get(k)
+	var err error
+	switch op {
+	case Add:
+		err = s.Set(k, v)
+	case Delete:
+		err = s.Delete(k)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return func() error {
+		if found {
+			return s.Set(k, prev)
+		}
+		return s.Delete(k)
+	}, nil
+}
+
+// change implements changer.
+func (m *Map[K, V]) change(op Op, k K, v V) (bool, func(), error) {
+	m.init(context.Background())
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	old, present := m.M[k]
+	exp, expires := m.expires[k]
+	var (
+		applied bool
+		err     error
+	)
+	switch op {
+	case Add:
+		applied, err = m.setLocked(k, v, m.TTL)
+	case Delete:
+		applied, err = m.deleteLocked(k)
+	}
+	restore := func() {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+
+		if !present {
+			delete(m.M, k)
+			m.untrack(k)
+			return
+		}
+		m.M[k] = old
+		if expires {
+			if m.expires == nil {
+				m.expires = map[K]time.Time{}
+			}
+			m.expires[k] = exp
+		} else {
+			delete(m.expires, k)
+		}
+		if m.MaxSize > 0 {
+			m.touch(k)
+		}
+	}
+	return applied, restore, err
+}
+
+// change implements changer. The entry is read before the change under its own shard lock; changes through a
+// Group are serialized, changes made to m directly are not.
+func (m *ShardedMap[K, V]) change(op Op, k K, v V) (bool, func(), error) {
+	old, present := m.m.Get(k)
+	var (
+		applied bool
+		err     error
+	)
+	switch op {
+	case Add:
+		applied, err = m.set(k, v, m.TTL)
+	case Delete:
+		applied, err = m.del(k)
+	}
+	restore := func() {
+		if present {
+			m.m.Set(k, old)
+			return
+		}
+		m.m.Del(k)
+	}
+	return applied, restore, err
+}
+
+// Member is one of the Values a Group changes along with its Primary. Build one with Index, or fill in both funcs
+// for a Value that Index does not fit.
+type Member[K cmp.Ordered, V any] struct {
+	// Set applies the Group's Set of k and v. prev and found are what the Primary held at k before the change.
+	// It returns a func that reverses the change, which is called if a later change in the Group fails.
+	Set func(k K, v V, prev V, found bool) (undo func() error, err error)
+	// Delete applies the Group's Delete of k. prev and found are what the Primary held at k before the change.
+	// It returns a func that reverses the change, which is called if a later change in the Group fails.
+	Delete func(k K, prev V, found bool) (undo func() error, err error)
+}
+
+// Index returns a Member that keeps v, a secondary index of the Primary, in step with it: index maps a key and
+// value in the Primary to the key and value stored in v. When a Set changes the index key of a value, the entry at
+// the old index key is deleted. Deleting a key from the Primary deletes its entry from v.
+func Index[K cmp.Ordered, V any, IK cmp.Ordered, IV any](v Store[IK, IV], index func(k K, v V) (IK, IV)) Member[K, V] {
+	if v == nil {
+		panic("feeder.Index: v cannot be nil")
+	}
+	if index == nil {
+		panic("feeder.Index: index cannot be nil")
+	}
+
+	// remove deletes ik from v and returns how to put back what was there.
+	remove := func(ik IK) (func() error, error) {
+		u, err := change(v, Delete, ik, *new(IV))
+		if err != nil {
+			return nil, err
+		}
+		return func() error { return rollback([]undo{u}) }, nil
+	}
+
+	return Member[K, V]{
+		Set: func(k K, val V, prev V, found bool) (func() error, error) {
+			ik, iv := index(k, val)
+			var undos []undo
+			if found {
+				if oik, _ := index(k, prev); oik != ik {
+					u, err := remove(oik)
+					if err != nil {
+						return nil, err
+					}
+					undos = append(undos, u)
+				}
+			}
+
+			u, err := change(v, Add, ik, iv)
+			if err != nil {
+				return nil, errors.Join(err, rollback(undos))
+			}
+			undos = append(undos, u)
+			return func() error { return rollback(undos) }, nil
+		},
+		Delete: func(k K, prev V, found bool) (func() error, error) {
+			if !found {
+				return func() error { return nil }, nil
+			}
+			ik, _ := index(k, prev)
+			return remove(ik)
+		},
+	}
+}
+
+// Group is a Value that applies every change to its Primary and each of its Members as one all-or-nothing change:
+// if any of them fails, every change already made is undone, in reverse order, and the error is returned. Because
+// a Group is a Value, a Feeder can feed it, and a retryable error retries the whole change from a clean state.
+//
+// A Map or ShardedMap is undone by putting the key's entry back as it was, with its expiry, without calling the
+// accept functions again or evicting; a key evicted to make room for a Set is not put back. Any other Store is
+// undone with a Set or Delete of what was there before, through its own accept logic. A change that an accept
+// function rejects without an error is not a failure and has nothing to undo; when the Primary rejects it, the
+// Members are left alone too. Return an error from SetAccept or DeleteAccept to have the Group roll back. Changes
+// through a Group are serialized with each other, but not with changes made to the Values directly.
+//
+// Set up the Group before first use and do not change its fields after that.
+type Group[K cmp.Ordered, V any] struct {
+	// Primary is the Value keyed the same way as the Group. It is changed first, and Get reads from it.
+	Primary Store[K, V]
+	// Members are the other Values the Group changes, in order, after Primary.
+	Members []Member[K, V]
+
+	mu sync.Mutex
+}
+
+// Set implements Value.Set().
+func (g *Group[K, V]) Set(k K, v V) error {
+	return g.Apply(KeyVal[K, V]{Op: Add, K: k, V: v})
+}
+
+// Delete implements Value.Delete().
+func (g *Group[K, V]) Delete(k K) error {
+	return g.Apply(KeyVal[K, V]{Op: Delete, K: k})
+}
+
+// Get implements Store.Get() by reading from Primary.
+func (g *Group[K, V]) Get(k K) (v V, ok bool) {
+	return g.Primary.Get(k)
+}
+
+// Apply applies every op in kvs as one all-or-nothing change: if any op fails, every op already applied is undone
+// and the error is returned. Only the Op, K and V of each KeyVal are used.
+func (g *Group[K, V]) Apply(kvs ...KeyVal[K, V]) error {
+	if g.Primary == nil {
+		return fmt.Errorf("feeder.Group: Primary cannot be nil: %w", ErrPermanent)
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	var undos []undo
+	for _, kv := range kvs {
+		us, err := g.apply(kv)
+		undos = append(undos, us...)
+		if err != nil {
+			return errors.Join(err, rollback(undos))
+		}
+	}
+	return nil
+}
+
+// apply applies a single op and returns how to undo every change it made, including the changes it made before
+// it failed. g.mu must be held.
+func (g *Group[K, V]) apply(kv KeyVal[K, V]) ([]undo, error) {
+	if kv.Op != Add && kv.Op != Delete {
+		return nil, fmt.Errorf("feeder.Group: cannot apply an Op with code %v: %w", kv.Op, ErrPermanent)
+	}
+	prev, found := g.Primary.Get(kv.K)
+
+	u, err := change(g.Primary, kv.Op, kv.K, kv.V)
+	if err != nil || u == nil {
+		return nil, err
+	}
+
+	undos := []undo{u}
+	for _, m := range g.Members {
+		var (
+			u   func() error
+			err error
+		)
+		switch kv.Op {
+		case Add:
+			u, err = m.Set(kv.K, kv.V, prev, found)
+		case Delete:
+			u, err = m.Delete(kv.K, prev, found)
+		}
+		if err != nil {
+			return undos, err
+		}
+		undos = append(undos, u)
+	}
+	return undos, nil
+}
+
+// rollback calls undos in reverse order, skipping a nil undo for a change that was not applied. Every undo is
+// called even if one fails; the errors are joined with ErrRollback.
+func rollback(undos []undo) error {
+	var errs []error
+	for i := len(undos) - 1; i >= 0; i-- {
+		if undos[i] == nil {
+			continue
+		}
+		if err := undos[i](); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) == 0 {
+		return nil
+	}
+	return errors.Join(append([]error{ErrRollback}, errs...)...)
+}
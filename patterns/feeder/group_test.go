@@ -0,0 +1,240 @@
+package feeder
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/gostdlib/base/context"
+
+	"github.com/kylelemons/godebug/pretty"
+)
+
+type user struct {
+	Name  string
+	Email string
+}
+
+// byEmail indexes users by their email.
+func byEmail(k string, v user) (string, string) {
+	return v.Email, k
+}
+
+func TestGroup(t *testing.T) {
+	permanent := fmt.Errorf("index write failed: %w", ErrPermanent)
+
+	tests := []struct {
+		name string
+		// users and emails are what the primary and the index hold before ops are applied.
+		users  map[string]user
+		emails map[string]string
+		// failEmail makes the index's SetAccept fail for this email.
+		failEmail string
+		ops       []KeyVal[string, user]
+		wantErr   bool
+		wantUsers map[string]user
+		wantIndex map[string]string
+	}{
+		{
+			name:      "Success: a Set changes the primary and the index",
+			users:     map[string]user{},
+			emails:    map[string]string{},
+			ops:       []KeyVal[string, user]{{Op: Add, K: "jo", V: user{Name: "Jo", Email: "jo@a"}}},
+			wantUsers: map[string]user{"jo": {Name: "Jo", Email: "jo@a"}},
+			wantIndex: map[string]string{"jo@a": "jo"},
+		},
+		{
+			name:      "Success: a Set that changes the index key deletes the old index entry",
+			users:     map[string]user{"jo": {Name: "Jo", Email: "jo@a"}},
+			emails:    map[string]string{"jo@a": "jo"},
+			ops:       []KeyVal[string, user]{{Op: Add, K: "jo", V: user{Name: "Jo", Email: "jo@b"}}},
+			wantUsers: map[string]user{"jo": {Name: "Jo", Email: "jo@b"}},
+			wantIndex: map[string]string{"jo@b": "jo"},
+		},
+		{
+			name:      "Success: a Delete removes the key from the primary and the index",
+			users:     map[string]user{"jo": {Name: "Jo", Email: "jo@a"}},
+			emails:    map[string]string{"jo@a": "jo"},
+			ops:       []KeyVal[string, user]{{Op: Delete, K: "jo"}},
+			wantUsers: map[string]user{},
+			wantIndex: map[string]string{},
+		},
+		{
+			name:      "Error: a failed index write rolls back the primary and the old index entry",
+			users:     map[string]user{"jo": {Name: "Jo", Email: "jo@a"}},
+			emails:    map[string]string{"jo@a": "jo"},
+			failEmail: "jo@b",
+			ops:       []KeyVal[string, user]{{Op: Add, K: "jo", V: user{Name: "Jo", Email: "jo@b"}}},
+			wantErr:   true,
+			wantUsers: map[string]user{"jo": {Name: "Jo", Email: "jo@a"}},
+			wantIndex: map[string]string{"jo@a": "jo"},
+		},
+		{
+			name:      "Error: a failed op rolls back every op applied before it",
+			users:     map[string]user{},
+			emails:    map[string]string{},
+			failEmail: "al@a",
+			ops: []KeyVal[string, user]{
+				{Op: Add, K: "jo", V: user{Name: "Jo", Email: "jo@a"}},
+				{Op: Add, K: "al", V: user{Name: "Al", Email: "al@a"}},
+			},
+			wantErr:   true,
+			wantUsers: map[string]user{},
+			wantIndex: map[string]string{},
+		},
+	}
+
+	for _, test := range tests {
+		users := &Map[string, user]{M: test.users}
+		emails := &Map[string, string]{
+			M: test.emails,
+			SetAccept: func(key string, val, prev string, replaced bool) (bool, error) {
+				if key == test.failEmail {
+					return false, permanent
+				}
+				return true, nil
+			},
+		}
+		g := &Group[string, user]{
+			Primary: users,
+			Members: []Member[string, user]{Index(emails, byEmail)},
+		}
+
+		err := g.Apply(test.ops...)
+		switch {
+		case err == nil && test.wantErr:
+			t.Errorf("TestGroup(%s): got err == nil, want err != nil", test.name)
+			continue
+		case err != nil && !test.wantErr:
+			t.Errorf("TestGroup(%s): got err == %s, want err == nil", test.name, err)
+			continue
+		case err != nil && errors.Is(err, ErrRollback):
+			t.Errorf("TestGroup(%s): got err == %s, want a rollback that succeeded", test.name, err)
+			continue
+		}
+
+		if diff := pretty.Compare(test.wantUsers, users.M); diff != "" {
+			t.Errorf("TestGroup(%s): primary -want/+got:\n%s", test.name, diff)
+		}
+		if diff := pretty.Compare(test.wantIndex, emails.M); diff != "" {
+			t.Errorf("TestGroup(%s): index -want/+got:\n%s", test.name, diff)
+		}
+	}
+}
+
+// sliceStore is a Store that is not a Map or ShardedMap, so a Group undoes its changes with Set and Delete. Its
+// Delete always fails.
+type sliceStore struct {
+	m map[string]user
+}
+
+func (s *sliceStore) Set(k string, v user) error { s.m[k] = v; return nil }
+func (s *sliceStore) Delete(k string) error      { return errors.New("cannot delete") }
+func (s *sliceStore) Get(k string) (user, bool)  { v, ok := s.m[k]; return v, ok }
+
+func TestGroupRollbackFails(t *testing.T) {
+	users := &sliceStore{m: map[string]user{}}
+	emails := &Map[string, string]{
+		M: map[string]string{},
+		SetAccept: func(key string, val, prev string, replaced bool) (bool, error) {
+			return false, errors.New("cannot set")
+		},
+	}
+	g := &Group[string, user]{Primary: users, Members: []Member[string, user]{Index(emails, byEmail)}}
+
+	err := g.Set("jo", user{Name: "Jo", Email: "jo@a"})
+	if !errors.Is(err, ErrRollback) {
+		t.Errorf("TestGroupRollbackFails: got err == %v, want an error wrapping ErrRollback", err)
+	}
+}
+
+// TestGroupRestore verifies a rollback puts a Map's entry back with its expiry and without calling its accept
+// functions, and that a change the Primary rejects leaves the Members alone.
+func TestGroupRestore(t *testing.T) {
+	clk := newFakeClock()
+	var calls []string
+	users := &Map[string, user]{
+		M:   map[string]user{},
+		now: clk.now,
+		SetAccept: func(key string, val, prev user, replaced bool) (bool, error) {
+			calls = append(calls, "set "+key)
+			return key != "al", nil
+		},
+		DeleteAccept: func(key string, prev user, found bool) (bool, error) {
+			calls = append(calls, "delete "+key)
+			return true, nil
+		},
+	}
+	emails := &Map[string, string]{
+		M: map[string]string{},
+		SetAccept: func(key string, val, prev string, replaced bool) (bool, error) {
+			if key == "jo@b" {
+				return false, errors.New("cannot set")
+			}
+			return true, nil
+		},
+	}
+	g := &Group[string, user]{Primary: users, Members: []Member[string, user]{Index(emails, byEmail)}}
+
+	if err := users.SetTTL("jo", user{Name: "Jo", Email: "jo@a"}, time.Hour); err != nil {
+		t.Fatalf("TestGroupRestore: SetTTL: %s", err)
+	}
+	exp := users.expires["jo"]
+	if err := g.Set("jo", user{Name: "Jo", Email: "jo@b"}); err == nil || errors.Is(err, ErrRollback) {
+		t.Fatalf("TestGroupRestore: got err == %v, want a rollback that succeeded", err)
+	}
+	if err := g.Set("al", user{Name: "Al", Email: "al@a"}); err != nil {
+		t.Fatalf("TestGroupRestore: Set(al): %s", err)
+	}
+
+	if diff := pretty.Compare(map[string]user{"jo": {Name: "Jo", Email: "jo@a"}}, users.M); diff != "" {
+		t.Errorf("TestGroupRestore: primary -want/+got:\n%s", diff)
+	}
+	if got := users.expires["jo"]; !got.Equal(exp) {
+		t.Errorf("TestGroupRestore: got expiry %v, want %v", got, exp)
+	}
+	if diff := pretty.Compare(map[string]string{}, emails.M); diff != "" {
+		t.Errorf("TestGroupRestore: index -want/+got:\n%s", diff)
+	}
+	if diff := pretty.Compare([]string{"set jo", "set jo", "set al"}, calls); diff != "" {
+		t.Errorf("TestGroupRestore: accept calls -want/+got:\n%s", diff)
+	}
+}
+
+func TestFeedGroup(t *testing.T) {
+	users := &Map[string, user]{M: map[string]user{}}
+	emails := &Map[string, string]{
+		M: map[string]string{},
+		SetAccept: func(key string, val, prev string, replaced bool) (bool, error) {
+			if key == "taken@a" {
+				return false, fmt.Errorf("email is taken: %w", ErrPermanent)
+			}
+			return true, nil
+		},
+	}
+	g := &Group[string, user]{Primary: users, Members: []Member[string, user]{Index(emails, byEmail)}}
+
+	f, err := NewFeeder[string, user](t.Context(), g)
+	if err != nil {
+		t.Fatalf("TestFeedGroup: NewFeeder: %s", err)
+	}
+
+	ch := make(chan KeyVal[string, user], 2)
+	ch <- KeyVal[string, user]{Op: Add, K: "jo", V: user{Name: "Jo", Email: "jo@a"}}
+	ch <- KeyVal[string, user]{Op: Add, K: "al", V: user{Name: "Al", Email: "taken@a"}}
+	close(ch)
+	pipe := func(ctx context.Context) (chan KeyVal[string, user], chan struct{}, error) {
+		return ch, make(chan struct{}), nil
+	}
+
+	if err := <-f.Feed(t.Context(), pipe); !errors.Is(err, ErrPermanent) {
+		t.Fatalf("TestFeedGroup: got err == %v, want an error wrapping ErrPermanent", err)
+	}
+	if diff := pretty.Compare(map[string]user{"jo": {Name: "Jo", Email: "jo@a"}}, users.M); diff != "" {
+		t.Errorf("TestFeedGroup: primary -want/+got:\n%s", diff)
+	}
+	if diff := pretty.Compare(map[string]string{"jo@a": "jo"}, emails.M); diff != "" {
+		t.Errorf("TestFeedGroup: index -want/+got:\n%s", diff)
+	}
+}
@@ -0,0 +1,104 @@
+package feeder
+
+import (
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// meterName is the import path of this package. A Value's or Feeder's Name is appended to this to namespace its
+// metrics.
+const meterName = "github.com/gostdlib/concurrency/patterns/feeder"
+
+// mapMetrics are the OTEL instruments recorded by a Map or ShardedMap that has a Name.
+type mapMetrics struct {
+	meter metric.Meter
+	// Expirations is the number of expired keys Reap deleted. An expired key whose deletion DeleteAccept
+	// rejected is not counted until a later Reap deletes it.
+	Expirations metric.Int64Counter
+	// Evictions is the number of keys a full Map evicted to make room for a new key. Only a Map with MaxSize
+	// evicts.
+	Evictions metric.Int64Counter
+	// Rejections is the number of Set or Delete calls whose change SetAccept or DeleteAccept rejected without an
+	// error, by the "op" attribute. The change is not made but the call succeeds, so a Feeder cannot see these.
+	Rejections metric.Int64Counter
+}
+
+func newMapMetrics(m metric.Meter) *mapMetrics {
+	mets := &mapMetrics{meter: m}
+
+	var err error
+	mets.Expirations, err = m.Int64Counter("expirations", metric.WithDescription("The number of expired keys deleted."))
+	if err != nil {
+		panic(err)
+	}
+	mets.Evictions, err = m.Int64Counter("evictions", metric.WithDescription("The number of keys evicted to make room for a new key."))
+	if err != nil {
+		panic(err)
+	}
+	mets.Rejections, err = m.Int64Counter("rejections", metric.WithDescription("The number of changes an accept function rejected."))
+	if err != nil {
+		panic(err)
+	}
+
+	return mets
+}
+
+// opAttr returns the attribute option that labels a measurement with op.
+func opAttr(op Op) metric.MeasurementOption {
+	return metric.WithAttributes(attribute.String("op", op.String()))
+}
+
+// pipelineAttr labels a backoff measurement taken while re-establishing a pipeline rather than retrying an op.
+var pipelineAttr = metric.WithAttributes(attribute.String("op", "Pipeline"))
+
+// feederMetrics are the OTEL instruments recorded by a Feeder that has a Name.
+type feederMetrics struct {
+	meter metric.Meter
+	// Ops is the number of ops applied to the Value, by the "op" attribute. An op the Value accepted the call for
+	// but whose change an accept function rejected is counted here; see the Value's own Rejections for those.
+	Ops metric.Int64Counter
+	// Errors is the number of ops that failed after any retries, by the "op" attribute. A failed op ends the Feed
+	// attempt it is in.
+	Errors metric.Int64Counter
+	// Retries is the number of times an op was retried, by the "op" attribute.
+	Retries metric.Int64Counter
+	// Backoff is the time spent waiting between attempts, in seconds, by the "op" attribute. Waits before a
+	// pipeline is re-established are labeled "Pipeline".
+	Backoff metric.Float64Counter
+	// Reestablishments is the number of times a pipeline was established again after an attempt failed.
+	Reestablishments metric.Int64Counter
+	// Latency is how long an op took to apply, in seconds, including any retries, by the "op" attribute.
+	Latency metric.Float64Histogram
+}
+
+func newFeederMetrics(m metric.Meter) *feederMetrics {
+	mets := &feederMetrics{meter: m}
+
+	var err error
+	mets.Ops, err = m.Int64Counter("ops", metric.WithDescription("The number of ops applied to the Value."))
+	if err != nil {
+		panic(err)
+	}
+	mets.Errors, err = m.Int64Counter("errors", metric.WithDescription("The number of ops that failed after any retries."))
+	if err != nil {
+		panic(err)
+	}
+	mets.Retries, err = m.Int64Counter("retries", metric.WithDescription("The number of times an op was retried."))
+	if err != nil {
+		panic(err)
+	}
+	mets.Backoff, err = m.Float64Counter("backoff", metric.WithDescription("The time spent waiting between attempts."), metric.WithUnit("s"))
+	if err != nil {
+		panic(err)
+	}
+	mets.Reestablishments, err = m.Int64Counter("reestablishments", metric.WithDescription("The number of times a pipeline was established again."))
+	if err != nil {
+		panic(err)
+	}
+	mets.Latency, err = m.Float64Histogram("op.latency", metric.WithDescription("How long an op took to apply, including retries."), metric.WithUnit("s"))
+	if err != nil {
+		panic(err)
+	}
+
+	return mets
+}
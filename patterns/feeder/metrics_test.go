@@ -0,0 +1,181 @@
+package feeder
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/gostdlib/base/context"
+	"github.com/gostdlib/base/retry/exponential"
+
+	"github.com/kylelemons/godebug/pretty"
+	"go.opentelemetry.io/otel/attribute"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestFeederMetrics(t *testing.T) {
+	reader := sdkmetric.NewManualReader()
+	ctx := context.SetMeterProvider(t.Context(), sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader)))
+
+	back, err := exponential.New(exponential.WithTesting())
+	if err != nil {
+		t.Fatalf("TestFeederMetrics: exponential.New: %s", err)
+	}
+
+	// "flaky" fails its first Set, "reject" is always rejected and the pipeline fails its first establishment.
+	failed := false
+	m := &Map[string, int]{
+		M:    map[string]int{},
+		Name: "values",
+		SetAccept: func(key string, val, prev int, replaced bool) (bool, error) {
+			switch {
+			case key == "reject":
+				return false, nil
+			case key == "flaky" && !failed:
+				failed = true
+				return false, errors.New("temporary failure")
+			}
+			return true, nil
+		},
+	}
+	if _, err := m.Reap(ctx); err != nil {
+		t.Fatalf("TestFeederMetrics: Reap: %s", err)
+	}
+
+	f, err := NewFeeder[string, int](ctx, m, WithRetry[string, int](back), WithName[string, int]("feed"))
+	if err != nil {
+		t.Fatalf("TestFeederMetrics: NewFeeder: %s", err)
+	}
+	ops := []KeyVal[string, int]{
+		{Op: Add, K: "a", V: 1},
+		{Op: Add, K: "flaky", V: 2},
+		{Op: Add, K: "reject", V: 3},
+		{Op: Delete, K: "a"},
+	}
+	if err := <-f.Feed(ctx, flakyPipe(1, errors.New("temporary failure"), ops)); err != nil {
+		t.Fatalf("TestFeederMetrics: Feed: %s", err)
+	}
+
+	var rm metricdata.ResourceMetrics
+	if err := reader.Collect(ctx, &rm); err != nil {
+		t.Fatalf("TestFeederMetrics: could not collect metrics: %s", err)
+	}
+	got := map[string]int64{}
+	for _, sm := range rm.ScopeMetrics {
+		for _, item := range sm.Metrics {
+			switch data := item.Data.(type) {
+			case metricdata.Sum[int64]:
+				for _, dp := range data.DataPoints {
+					op, _ := dp.Attributes.Value("op")
+					got[sm.Scope.Name+" "+item.Name+" "+op.AsString()] += dp.Value
+				}
+			case metricdata.Histogram[float64]:
+				for _, dp := range data.DataPoints {
+					op, _ := dp.Attributes.Value("op")
+					got[sm.Scope.Name+" "+item.Name+" "+op.AsString()] += int64(dp.Count)
+				}
+			}
+		}
+	}
+
+	want := map[string]int64{
+		meterName + "/feed ops Add":           3,
+		meterName + "/feed ops Delete":        1,
+		meterName + "/feed retries Add":       1,
+		meterName + "/feed reestablishments ": 1,
+		meterName + "/feed op.latency Add":    3,
+		meterName + "/feed op.latency Delete": 1,
+		meterName + "/values rejections Add":  1,
+	}
+	if diff := pretty.Compare(want, got); diff != "" {
+		t.Errorf("TestFeederMetrics: -want/+got:\n%s", diff)
+	}
+}
+
+func TestWithName(t *testing.T) {
+	tests := []struct {
+		name    string
+		feeder  string
+		wantErr bool
+	}{
+		{name: "Success: a name turns on metrics", feeder: "feed"},
+		{name: "Error: an empty name", wantErr: true},
+	}
+
+	for _, test := range tests {
+		f, err := NewFeeder[string, int](t.Context(), &Map[string, int]{M: map[string]int{}}, WithName[string, int](test.feeder))
+		switch {
+		case err == nil && test.wantErr:
+			t.Errorf("TestWithName(%s): got err == nil, want err != nil", test.name)
+			continue
+		case err != nil && !test.wantErr:
+			t.Errorf("TestWithName(%s): got err == %s, want err == nil", test.name, err)
+			continue
+		case err != nil:
+			continue
+		}
+		if f.metrics == nil {
+			t.Errorf("TestWithName(%s): got no metrics, want metrics", test.name)
+		}
+	}
+}
+
+// TestFeedSpanEvents verifies every Feed attempt records a started and an ended event on the Context's span.
+func TestFeedSpanEvents(t *testing.T) {
+	sr := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(sr))
+	ctx, root := tp.Tracer("test").Start(t.Context(), "root")
+
+	back, err := exponential.New(exponential.WithTesting())
+	if err != nil {
+		t.Fatalf("TestFeedSpanEvents: exponential.New: %s", err)
+	}
+	f, err := NewFeeder[string, int](ctx, &Map[string, int]{M: map[string]int{}}, WithRetry[string, int](back))
+	if err != nil {
+		t.Fatalf("TestFeedSpanEvents: NewFeeder: %s", err)
+	}
+	if err := <-f.Feed(ctx, flakyPipe(1, errors.New("temporary failure"), []KeyVal[string, int]{{Op: Add, K: "a", V: 1}})); err != nil {
+		t.Fatalf("TestFeedSpanEvents: Feed: %s", err)
+	}
+	root.End()
+
+	type event struct {
+		Name    string
+		Attempt int64
+		Applied int64
+		Err     bool
+	}
+	var got []event
+	for _, s := range sr.Ended() {
+		for _, e := range s.Events() {
+			if !strings.HasPrefix(e.Name, "feeder.") {
+				continue
+			}
+			ev := event{Name: e.Name}
+			for _, a := range e.Attributes {
+				switch a.Key {
+				case attribute.Key("attempt"):
+					ev.Attempt = a.Value.AsInt64()
+				case attribute.Key("applied"):
+					ev.Applied = a.Value.AsInt64()
+				case attribute.Key("error"):
+					ev.Err = true
+				}
+			}
+			got = append(got, ev)
+		}
+	}
+
+	want := []event{
+		{Name: "feeder.Feed: attempt started", Attempt: 1},
+		{Name: "feeder.Feed: attempt ended", Attempt: 1, Err: true},
+		{Name: "feeder.Feed: attempt started", Attempt: 2},
+		{Name: "feeder.Feed: attempt ended", Attempt: 2, Applied: 1},
+	}
+	if diff := pretty.Compare(want, got); diff != "" {
+		t.Errorf("TestFeedSpanEvents: -want/+got:\n%s", diff)
+	}
+}
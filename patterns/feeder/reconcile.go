@@ -0,0 +1,128 @@
+package feeder
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"iter"
+	"maps"
+	"reflect"
+	"slices"
+	"time"
+
+	"github.com/gostdlib/base/context"
+	"github.com/gostdlib/base/telemetry/otel/trace/span"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// Ranger is implemented by a Value that can range over its keys and values. Feeder.Reconcile uses it to find
+// what a snapshot changes; Map and ShardedMap implement it.
+type Ranger[K cmp.Ordered, V any] interface {
+	// All ranges over every key and value. It must not be holding a lock the Value's Set or Delete takes once
+	// the range is done.
+	All() iter.Seq2[K, V]
+}
+
+// WithEqual sets how Reconcile decides that a value in a snapshot is the same as the value already in the Value,
+// which means the key is left alone. If not set, reflect.DeepEqual is used.
+func WithEqual[K cmp.Ordered, V any](equal func(a, b V) bool) FeederOption[K, V] {
+	return func(o *Feeder[K, V]) error {
+		if equal == nil {
+			return errors.New("feeder.WithEqual: equal cannot be nil")
+		}
+		o.equal = equal
+		return nil
+	}
+}
+
+// ReconcileStats are the counts of what a Reconcile did.
+type ReconcileStats struct {
+	// Added is the number of keys in the snapshot that were not in the Value.
+	Added int
+	// Updated is the number of keys in the snapshot whose value differed from the one in the Value.
+	Updated int
+	// Deleted is the number of keys in the Value that were not in the snapshot.
+	Deleted int
+	// Unchanged is the number of keys in the snapshot whose value was the same as the one in the Value.
+	Unchanged int
+}
+
+// Reconcile makes the Value match snapshot, a full copy of the source rather than a changelog. Every key in
+// snapshot that is new, or whose value differs from the Value's, is added and every key in the Value that is not
+// in snapshot is deleted, both through the same Set and Delete, and so the same accept functions and retry
+// policy, as Feed uses. Deletes happen after every add, in key order. The Value must implement Ranger.
+//
+// Reconcile returns at the first op that fails, with stats counting the ops that were applied before it, or when
+// ctx is cancelled. It reads the Value once, before applying anything, so a Feed running at the same time can
+// change a key Reconcile has already compared; run them against the same Value only if that is acceptable.
+// The outcome is recorded as an event on the span in ctx and, with WithName, in the Feeder's metrics.
+func (f *Feeder[K, V]) Reconcile(ctx context.Context, snapshot iter.Seq2[K, V]) (stats ReconcileStats, err error) {
+	if snapshot == nil {
+		return stats, fmt.Errorf("feeder.Reconcile: snapshot cannot be nil: %w", ErrPermanent)
+	}
+	r, ok := f.v.(Ranger[K, V])
+	if !ok {
+		return stats, fmt.Errorf("feeder.Reconcile: Value %T does not implement Ranger: %w", f.v, ErrPermanent)
+	}
+
+	defer func() {
+		attrs := []attribute.KeyValue{
+			attribute.Int("added", stats.Added),
+			attribute.Int("updated", stats.Updated),
+			attribute.Int("deleted", stats.Deleted),
+			attribute.Int("unchanged", stats.Unchanged),
+		}
+		if err != nil {
+			attrs = append(attrs, attribute.String("error", err.Error()))
+		}
+		span.Get(ctx).Event("feeder.Reconcile: ended", attrs...)
+	}()
+
+	// Copy what the Value holds first: All may hold a lock that Set and Delete need. Every key the snapshot has is
+	// removed from current, which leaves the keys to delete.
+	current := maps.Collect(r.All())
+
+	equal := f.equal
+	if equal == nil {
+		equal = func(a, b V) bool { return reflect.DeepEqual(a, b) }
+	}
+
+	for k, v := range snapshot {
+		if err := ctx.Err(); err != nil {
+			return stats, err
+		}
+		prev, found := current[k]
+		delete(current, k)
+		if found && equal(prev, v) {
+			stats.Unchanged++
+			continue
+		}
+
+		start := time.Now()
+		err := f.set(ctx, k, v, 0)
+		f.record(ctx, Add, time.Since(start), err)
+		if err != nil {
+			return stats, err
+		}
+		if found {
+			stats.Updated++
+		} else {
+			stats.Added++
+		}
+	}
+
+	for _, k := range slices.Sorted(maps.Keys(current)) {
+		if err := ctx.Err(); err != nil {
+			return stats, err
+		}
+		start := time.Now()
+		err := f.delete(ctx, k)
+		f.record(ctx, Delete, time.Since(start), err)
+		if err != nil {
+			return stats, err
+		}
+		stats.Deleted++
+	}
+	return stats, nil
+}
@@ -0,0 +1,112 @@
+package feeder
+
+import (
+	"errors"
+	"maps"
+	"testing"
+
+	"github.com/kylelemons/godebug/pretty"
+)
+
+// setOnly is a Value that does not implement Ranger.
+type setOnly struct{}
+
+func (setOnly) Set(k string, v int) error { return nil }
+func (setOnly) Delete(k string) error     { return nil }
+
+func TestReconcile(t *testing.T) {
+	boom := errors.New("boom")
+
+	tests := []struct {
+		name      string
+		value     func() Value[string, int]
+		options   []FeederOption[string, int]
+		snapshot  map[string]int
+		wantErr   bool
+		wantStats ReconcileStats
+		want      map[string]int
+	}{
+		{
+			name:      "Success: new keys are added, changed keys updated and missing keys deleted",
+			value:     func() Value[string, int] { return &Map[string, int]{M: map[string]int{"a": 1, "b": 2, "c": 3}} },
+			snapshot:  map[string]int{"a": 1, "b": 20, "d": 4},
+			wantStats: ReconcileStats{Added: 1, Updated: 1, Deleted: 1, Unchanged: 1},
+			want:      map[string]int{"a": 1, "b": 20, "d": 4},
+		},
+		{
+			name:      "Success: an empty snapshot deletes every key",
+			value:     func() Value[string, int] { return &Map[string, int]{M: map[string]int{"a": 1, "b": 2}} },
+			snapshot:  map[string]int{},
+			wantStats: ReconcileStats{Deleted: 2},
+			want:      map[string]int{},
+		},
+		{
+			name: "Success: ShardedMap is reconciled",
+			value: func() Value[string, int] {
+				m := &ShardedMap[string, int]{}
+				m.Set("a", 1)
+				m.Set("b", 2)
+				return m
+			},
+			snapshot:  map[string]int{"a": 10},
+			wantStats: ReconcileStats{Updated: 1, Deleted: 1},
+			want:      map[string]int{"a": 10},
+		},
+		{
+			name:      "Success: WithEqual decides what is unchanged",
+			value:     func() Value[string, int] { return &Map[string, int]{M: map[string]int{"a": 1}} },
+			options:   []FeederOption[string, int]{WithEqual[string, int](func(a, b int) bool { return a%10 == b%10 })},
+			snapshot:  map[string]int{"a": 11},
+			wantStats: ReconcileStats{Unchanged: 1},
+			want:      map[string]int{"a": 1},
+		},
+		{
+			name: "Error: a failed delete stops the Reconcile",
+			value: func() Value[string, int] {
+				return &Map[string, int]{
+					M:            map[string]int{"a": 1, "b": 2},
+					DeleteAccept: func(key string, prev int, found bool) (bool, error) { return false, boom },
+				}
+			},
+			snapshot:  map[string]int{"c": 3},
+			wantErr:   true,
+			wantStats: ReconcileStats{Added: 1},
+			want:      map[string]int{"a": 1, "b": 2, "c": 3},
+		},
+		{
+			name:     "Error: a Value that is not a Ranger",
+			value:    func() Value[string, int] { return setOnly{} },
+			snapshot: map[string]int{"a": 1},
+			wantErr:  true,
+		},
+	}
+
+	for _, test := range tests {
+		v := test.value()
+		f, err := NewFeeder[string, int](t.Context(), v, test.options...)
+		if err != nil {
+			t.Errorf("TestReconcile(%s): NewFeeder: %s", test.name, err)
+			continue
+		}
+
+		stats, err := f.Reconcile(t.Context(), maps.All(test.snapshot))
+		switch {
+		case err == nil && test.wantErr:
+			t.Errorf("TestReconcile(%s): got err == nil, want err != nil", test.name)
+			continue
+		case err != nil && !test.wantErr:
+			t.Errorf("TestReconcile(%s): got err == %s, want err == nil", test.name, err)
+			continue
+		}
+
+		if diff := pretty.Compare(test.wantStats, stats); diff != "" {
+			t.Errorf("TestReconcile(%s): stats -want/+got:\n%s", test.name, diff)
+		}
+		if test.want == nil {
+			continue
+		}
+		if diff := pretty.Compare(test.want, maps.Collect(v.(Ranger[string, int]).All())); diff != "" {
+			t.Errorf("TestReconcile(%s): value -want/+got:\n%s", test.name, diff)
+		}
+	}
+}
@@ -102,3 +102,27 @@ func ExampleWithGate() {
 	// Output:
 	// [10 20 30]
 }
+
+// ExampleStart audits a fire-and-forget run: Start returns a Run whose Wait reports each failed pair as a
+// *WorkerError, and whose Progress counts how the pairs went.
+func ExampleStart() {
+	ctx := context.Background()
+
+	fn := func(ctx context.Context, _ int, v string) error {
+		if strings.TrimSpace(v) == "" {
+			return fmt.Errorf("empty row")
+		}
+		return nil // write the row
+	}
+
+	r := fanout.Start(ctx, "row-writer", 4, stream.Slice([]string{"a", "b", " ", "d"}), fn)
+	if err := r.Wait(ctx); err != nil {
+		fmt.Println(err)
+	}
+	p := r.Progress()
+	fmt.Println(p.Succeeded, "succeeded,", p.Failed, "failed")
+
+	// Output:
+	// fanout: pair 2: empty row
+	// 3 succeeded, 1 failed
+}
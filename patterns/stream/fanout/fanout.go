@@ -26,9 +26,19 @@ stream.Slice, or stream.Map. It returns a channel that is closed once every pair
 	<-done // wait for every row to be written (or ignore done for pure fire-and-forget)
 
 The Worker error is not streamed back to the caller; it is only consumed by foreach's retry/stop-on-err
-options passed through as Option (WithGate to retry a flaky Worker, WithStopOnErr to cancel the run on
-the first error). Options that shape result delivery (WithOrdered) are not exposed, since fanout
-delivers no results.
+options passed through as Option (WithGate to retry a flaky Worker, WithSharedGate to do so behind a
+foreach.Gate shared with other runs, WithStopOnErr to cancel the run on the first error). Options that
+shape result delivery (WithOrdered) are not exposed, since fanout delivers no results.
+
+When fire-and-forget work still has to be audited, Start runs it the same way and returns a Run instead
+of a channel: Run.Wait reports the failed pairs' errors as a *WorkerError each, up to a cap, Run.Progress
+counts the pairs that succeeded, failed, were retried or were cancelled while the run goes on, and
+Run.Cancel stops it. Run.Resize scales a long run, such as one draining a queue for hours, up or down while
+it goes on; shrinking lets the Workers already running finish.
+
+When every pair must reach several independent sinks — a database write, an event, a cache update — Tee
+reads the input once and feeds each Sink, a named Worker group with its own size, gate and errors run as
+its own Run. A slow Sink holds the input back only once its buffer is full.
 */
 package fanout
 
@@ -48,9 +58,10 @@ import (
 type Worker[K, V any] func(ctx context.Context, k K, v V) error
 
 // Option configures a Limited run. Only the options that affect side-effect work are exposed:
-// WithStopOnErr and WithGate. Option is a distinct type rather than an alias of foreach.Option on
-// purpose — foreach's result-delivery options (WithOrdered, WithMaxHeld) cannot be passed here, so an
-// unsupported option whose validation error fanout would silently drop can never reach the run.
+// WithStopOnErr, WithGate and WithSharedGate. Option is a distinct type rather than an alias of
+// foreach.Option on purpose — foreach's result-delivery options (WithOrdered, WithMaxHeld) cannot be
+// passed here, so an unsupported option whose validation error fanout would silently drop can never
+// reach the run.
 type Option func() (foreach.Option, error)
 
 // WithStopOnErr causes the first Worker error to cancel processing of the remaining pairs. Pairs
@@ -74,6 +85,21 @@ func WithGate(boff *exponential.Backoff) Option {
 	}
 }
 
+// WithSharedGate is WithGate behind g, a foreach.Gate shared with other Limited runs and foreach.Item calls:
+// a Worker retrying in any of them pauses dispatch in all of them, so every run writing to a dependency backs
+// off when one finds it struggling. g's Stats report its pauses. A nil boff or g panics.
+func WithSharedGate(boff *exponential.Backoff, g *foreach.Gate) Option {
+	return func() (foreach.Option, error) {
+		if boff == nil {
+			return nil, fmt.Errorf("fanout.WithSharedGate: boff cannot be nil: %w", ErrPermanent)
+		}
+		if g == nil {
+			return nil, fmt.Errorf("fanout.WithSharedGate: g cannot be nil: %w", ErrPermanent)
+		}
+		return foreach.WithSharedGate(boff, g), nil
+	}
+}
+
 // ErrPermanent marks a Worker error that WithGate must never retry. Wrap it into an error that cannot
 // succeed on a retry: fmt.Errorf("%w: %w", origErr, fanout.ErrPermanent).
 var ErrPermanent = foreach.ErrPermanent
@@ -89,24 +115,49 @@ var ErrPermanent = foreach.ErrPermanent
 // already dispatched drain; done still closes. If seq never ends — for example a stream.Chan whose
 // channel is never closed — the run never finishes and done never closes; the caller owns ending seq.
 func Limited[K, V any](ctx context.Context, name string, size int, seq iter.Seq2[K, V], w Worker[K, V], options ...Option) (done <-chan struct{}) {
+	opts := prepare(ctx, "fanout.Limited", size, seq, w, options)
+	poolCtx := context.SetPool(ctx, context.Pool(ctx).Limited(ctx, name, size))
+
+	d := make(chan struct{})
+	fn := func(ctx context.Context, k K, v V) (struct{}, error) {
+		return struct{}{}, w(ctx, k, v)
+	}
+
+	ctx = context.WithoutCancel(ctx)
+	// Drive the lazy foreach range on the unbounded default pool, discarding every Response since fanout
+	// has no output. The driver blocks on delivery, so keeping it off the Limited pool ensures it never
+	// consumes one of size's slots (a size-1 pool would otherwise deadlock against its own driver).
+	// The driver runs on the WithoutCancel ctx so Submit never declines; that is what lets us drop the
+	// close-on-decline fallback and still guarantee done always closes.
+	_ = context.Pool(ctx).Default().Submit(ctx, func() {
+		for range foreach.Item(poolCtx, seq, fn, opts...) {
+		}
+		close(d)
+	})
+	return d
+}
+
+// prepare checks the arguments of Limited or Start, named fname in the panics, and returns the foreach
+// options the run's options stand for.
+func prepare[K, V any](ctx context.Context, fname string, size int, seq iter.Seq2[K, V], w Worker[K, V], options []Option) []foreach.Option {
 	ctxLimit := context.Pool(ctx).Limit()
 	if size < 1 {
-		panic("fanout.Limited: cannot have a size < 1")
+		panic(fname + ": cannot have a size < 1")
 	}
 	if ctxLimit != 0 && ctxLimit < size {
-		panic(fmt.Sprintf("fanout.Limited: size %d exceeds the Context pool's limit of %d", size, ctxLimit))
+		panic(fmt.Sprintf("%s: size %d exceeds the Context pool's limit of %d", fname, size, ctxLimit))
 	}
 	if w == nil {
-		panic("fanout.Limited: cannot have a nil Worker")
+		panic(fname + ": cannot have a nil Worker")
 	}
 	if seq == nil {
-		panic("fanout.Limited: cannot have a nil seq")
+		panic(fname + ": cannot have a nil seq")
 	}
 
 	opts := make([]foreach.Option, 0, len(options))
 	for _, o := range options {
 		if o == nil {
-			panic("fanout.Limited: cannot have a nil Option")
+			panic(fname + ": cannot have a nil Option")
 		}
 		opt, err := o()
 		if err != nil {
@@ -114,23 +165,5 @@ func Limited[K, V any](ctx context.Context, name string, size int, seq iter.Seq2
 		}
 		opts = append(opts, opt)
 	}
-
-	d := make(chan struct{})
-	poolCtx := context.SetPool(ctx, context.Pool(ctx).Limited(ctx, name, size))
-	fn := func(ctx context.Context, k K, v V) (struct{}, error) {
-		return struct{}{}, w(ctx, k, v)
-	}
-
-	ctx = context.WithoutCancel(ctx)
-	// Drive the lazy foreach range on the unbounded default pool, discarding every Response since fanout
-	// has no output. The driver blocks on delivery, so keeping it off the Limited pool ensures it never
-	// consumes one of size's slots (a size-1 pool would otherwise deadlock against its own driver).
-	// The driver runs on the WithoutCancel ctx so Submit never declines; that is what lets us drop the
-	// close-on-decline fallback and still guarantee done always closes.
-	_ = context.Pool(ctx).Default().Submit(ctx, func() {
-		for range foreach.Item(poolCtx, seq, fn, opts...) {
-		}
-		close(d)
-	})
-	return d
+	return opts
 }
@@ -9,6 +9,7 @@ import (
 	"github.com/gostdlib/base/context"
 	"github.com/gostdlib/base/retry/exponential"
 	"github.com/gostdlib/concurrency/patterns/stream"
+	"github.com/gostdlib/concurrency/patterns/stream/foreach"
 	"github.com/kylelemons/godebug/pretty"
 )
 
@@ -84,6 +85,15 @@ func TestLimited(t *testing.T) {
 				return stream.Slice([]int{7, 8, 9}), map[int]int{0: 7, 1: 8, 2: 9}
 			},
 		},
+		{
+			name:      "Success: a WithSharedGate backoff retries a failing Worker until every pair is processed",
+			size:      4,
+			opts:      []Option{WithSharedGate(testBoff(), foreach.NewGate())},
+			failFirst: true,
+			build: func(ctx context.Context) (iter.Seq2[int, int], map[int]int) {
+				return stream.Slice([]int{7, 8, 9}), map[int]int{0: 7, 1: 8, 2: 9}
+			},
+		},
 	}
 
 	for _, test := range tests {
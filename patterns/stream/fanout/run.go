@@ -0,0 +1,219 @@
+package fanout
+
+import (
+	"errors"
+	"fmt"
+	"iter"
+	"sync/atomic"
+	"time"
+
+	"github.com/gostdlib/base/context"
+	"github.com/gostdlib/concurrency/patterns/stream/foreach"
+)
+
+// Run is a fanout run started by Start. It keeps what Limited drops: the first maxErrs failed pairs' errors, for
+// Wait, and counts of how all the pairs went, for Progress. All methods are safe for concurrent use.
+type Run struct {
+	cancel context.CancelFunc
+	done   chan struct{}
+	start  time.Time
+	// limiter holds the size, which Resize moves up to max.
+	limiter *foreach.Limiter
+	max     int
+
+	started, succeeded, failed, retried, canceled atomic.Int64
+
+	// errs, dropped and end are only written by the driver, before done closes, and only read after. dropped
+	// counts the failed pairs whose errors did not fit in errs.
+	errs    []error
+	dropped int
+	end     time.Time
+}
+
+// Progress is a snapshot of a Run, as returned by Run.Progress.
+type Progress struct {
+	// Started counts the pairs whose Worker has been called, and Succeeded, Failed and Canceled the pairs
+	// that have finished. Canceled are pairs cut short, or never run, because the run was cancelled — by
+	// Run.Cancel, by its Context or by WithStopOnErr — and are not in the error Wait returns.
+	Started   int
+	Succeeded int
+	Failed    int
+	Canceled  int
+	// Retried counts the finished pairs whose Worker was called more than once under WithGate or
+	// WithSharedGate, whatever the outcome.
+	Retried int
+	// Size is how many Workers may run at once, as set by Start or Resize.
+	Size int
+	// Elapsed is the time since the run started, or how long it ran once Done.
+	Elapsed time.Duration
+	Done    bool
+}
+
+// WorkerError is one failed pair in the error Run.Wait returns: the pair's key and the Worker's final error
+// for it. Find the keys with errors.As and a *WorkerError[K].
+type WorkerError[K any] struct {
+	Key K
+	Err error
+}
+
+// Error implements error.
+func (e *WorkerError[K]) Error() string {
+	return fmt.Sprintf("fanout: pair %v: %s", e.Key, e.Err)
+}
+
+// Unwrap returns the Worker's error.
+func (e *WorkerError[K]) Unwrap() error {
+	return e.Err
+}
+
+// tracked is a pair's key as the run passes it to foreach, so the Worker's attempts at the pair can be counted.
+type tracked[K any] struct {
+	k        K
+	attempts atomic.Int32
+}
+
+// maxSize is the most Workers a Run can be resized to when ctx's pool is unlimited and Start's size is lower.
+const maxSize = 1024
+
+// maxErrs is how many failed pairs' errors a Run keeps for Wait. A run that drains a queue for hours can fail
+// far more pairs than are worth holding; Progress still counts them all.
+const maxErrs = 100
+
+// Start is Limited for work that must still be audited or scaled while it runs: it runs w over every key/value
+// pair in seq in the same way, and returns a Run to wait on, watch, resize and cancel. Run.Wait returns the
+// failed pairs' errors joined, as a *WorkerError[K] each, where Limited drops them; a Run keeps the first 100
+// and counts the rest, so its memory stays bounded however long it runs. Cancelling ctx or calling Run.Cancel
+// stops the run like cancelling Limited's ctx. Start panics where Limited does.
+//
+// size Workers run at once until Run.Resize changes it. The pool the Workers run on is Limited to the most
+// Resize allows, so it can grow: ctx's pool's limit, or, for an unlimited pool, size or maxSize (1024),
+// whichever is larger.
+func Start[K, V any](ctx context.Context, name string, size int, seq iter.Seq2[K, V], w Worker[K, V], options ...Option) *Run {
+	return start(ctx, name, size, seq, w, prepare(ctx, "fanout.Start", size, seq, w, options))
+}
+
+// start is Start once its arguments have been checked and its options turned into opts.
+func start[K, V any](ctx context.Context, name string, size int, seq iter.Seq2[K, V], w Worker[K, V], opts []foreach.Option) *Run {
+	ctx, cancel := context.WithCancel(ctx)
+	r := &Run{cancel: cancel, done: make(chan struct{}), start: time.Now(), max: context.Pool(ctx).Limit()}
+	if r.max == 0 {
+		r.max = max(size, maxSize)
+	}
+	r.limiter, _ = foreach.NewLimiter(size) // prepare checked size > 0.
+	poolCtx := context.SetPool(ctx, context.Pool(ctx).Limited(ctx, name, r.max))
+	opts = append(opts, foreach.WithLimiter(r.limiter))
+	in := func(yield func(*tracked[K], V) bool) {
+		for k, v := range seq {
+			if !yield(&tracked[K]{k: k}, v) {
+				return
+			}
+		}
+	}
+	fn := func(ctx context.Context, t *tracked[K], v V) (struct{}, error) {
+		if t.attempts.Add(1) == 1 {
+			r.started.Add(1)
+		}
+		return struct{}{}, w(ctx, t.k, v)
+	}
+
+	// The driver runs as Limited's does, on the default pool and a WithoutCancel Context.
+	_ = context.Pool(ctx).Default().Submit(context.WithoutCancel(ctx), func() {
+		for t, resp := range foreach.Item(poolCtx, iter.Seq2[*tracked[K], V](in), fn, opts...) {
+			record(ctx, r, t, resp.Err)
+		}
+		if r.dropped > 0 {
+			r.errs = append(r.errs, fmt.Errorf("fanout: %d more failed pairs not kept", r.dropped))
+		}
+		// A run cut short reports why, once, alongside its failed pairs.
+		if err := context.Cause(ctx); err != nil {
+			r.errs = append(r.errs, err)
+		}
+		cancel()
+		r.end = time.Now()
+		close(r.done)
+	})
+	return r
+}
+
+// record counts the pair t finishing with err, keeping err if the pair failed. ctx is the run's Context.
+func record[K any](ctx context.Context, r *Run, t *tracked[K], err error) {
+	if t != nil && t.attempts.Load() > 1 {
+		r.retried.Add(1)
+	}
+	switch {
+	case err == nil:
+		r.succeeded.Add(1)
+	// WithStopOnErr cancels foreach's own Context, not the run's, so a plain Canceled counts whichever it was.
+	case errors.Is(err, context.Canceled), ctx.Err() != nil && errors.Is(err, context.DeadlineExceeded):
+		r.canceled.Add(1)
+	case t == nil:
+		// foreach reports an invalid option under K's zero value, a nil *tracked here.
+		r.failed.Add(1)
+		r.keep(err)
+	default:
+		r.failed.Add(1)
+		r.keep(&WorkerError[K]{Key: t.k, Err: err})
+	}
+}
+
+// keep keeps a failed pair's err for Wait, or counts it once maxErrs are kept. Only the driver calls it.
+func (r *Run) keep(err error) {
+	if len(r.errs) >= maxErrs {
+		r.dropped++
+		return
+	}
+	r.errs = append(r.errs, err)
+}
+
+// Wait blocks until the run has finished and returns the first 100 failed pairs' errors, an error counting
+// the failed pairs past those, and the cause of a cancellation that cut the run short, joined with
+// errors.Join; nil if every pair succeeded. If ctx ends first, Wait returns ctx's error and the run goes on.
+func (r *Run) Wait(ctx context.Context) error {
+	select {
+	case <-r.done:
+		return errors.Join(r.errs...)
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Done returns a channel that is closed once the run has finished, like the one Limited returns.
+func (r *Run) Done() <-chan struct{} {
+	return r.done
+}
+
+// Cancel stops the run: pairs not yet started are not, and Workers running see their Context cancelled. The
+// run still finishes, and Wait reports context.Canceled. Cancel may be called more than once.
+func (r *Run) Cancel() {
+	r.cancel()
+}
+
+// Resize changes how many Workers may run at once to n, between 1 and the most Start's pool allows. Growing
+// starts more Workers at once; shrinking never interrupts a Worker already running: no more start until enough
+// of them have finished.
+func (r *Run) Resize(n int) error {
+	if n < 1 || n > r.max {
+		return fmt.Errorf("fanout.Run.Resize: n must be between 1 and %d, got %d: %w", r.max, n, ErrPermanent)
+	}
+	return r.limiter.Set(n)
+}
+
+// Progress returns a snapshot of how far the run has got.
+func (r *Run) Progress() Progress {
+	p := Progress{
+		Started:   int(r.started.Load()),
+		Succeeded: int(r.succeeded.Load()),
+		Failed:    int(r.failed.Load()),
+		Canceled:  int(r.canceled.Load()),
+		Retried:   int(r.retried.Load()),
+		Size:      r.limiter.Limit(),
+	}
+	select {
+	case <-r.done:
+		p.Done = true
+		p.Elapsed = r.end.Sub(r.start)
+	default:
+		p.Elapsed = time.Since(r.start)
+	}
+	return p
+}
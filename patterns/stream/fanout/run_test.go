@@ -0,0 +1,215 @@
+package fanout
+
+import (
+	"errors"
+	"slices"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gostdlib/base/context"
+	"github.com/gostdlib/concurrency/patterns/stream"
+	"github.com/kylelemons/godebug/pretty"
+)
+
+func TestStart(t *testing.T) {
+	ctx := t.Context()
+
+	tests := []struct {
+		name string
+		opts []Option
+		// failOn makes the Worker fail for these keys; failOnce only fails their first attempt.
+		failOn   []int
+		failOnce bool
+		want     Progress
+		// wantKeys are the keys of the WorkerErrors Wait returns.
+		wantKeys []int
+	}{
+		{
+			name: "Success: every pair succeeds",
+			want: Progress{Started: 5, Succeeded: 5, Size: 4, Done: true},
+		},
+		{
+			name:     "Error: failed pairs are in Wait's error",
+			failOn:   []int{1, 3},
+			want:     Progress{Started: 5, Succeeded: 3, Failed: 2, Size: 4, Done: true},
+			wantKeys: []int{1, 3},
+		},
+		{
+			name:     "Success: pairs WithGate retried are counted",
+			opts:     []Option{WithGate(testBoff())},
+			failOn:   []int{2, 4},
+			failOnce: true,
+			want:     Progress{Started: 5, Succeeded: 5, Retried: 2, Size: 4, Done: true},
+		},
+	}
+
+	for _, test := range tests {
+		failed := make([]bool, 5)
+		fn := func(ctx context.Context, k int, v int) error {
+			if !slices.Contains(test.failOn, k) || (test.failOnce && failed[k]) {
+				return nil
+			}
+			failed[k] = true
+			return errBoom
+		}
+
+		r := Start(ctx, test.name, 4, stream.Slice([]int{0, 1, 2, 3, 4}), fn, test.opts...)
+		err := r.Wait(ctx)
+
+		var keys []int
+		for _, e := range joined(err) {
+			var we *WorkerError[int]
+			if !errors.As(e, &we) || !errors.Is(we, errBoom) {
+				t.Errorf("TestStart(%s): got error %v, want a *WorkerError[int] wrapping errBoom", test.name, e)
+				continue
+			}
+			keys = append(keys, we.Key)
+		}
+		slices.Sort(keys)
+		if diff := pretty.Compare(test.wantKeys, keys); diff != "" {
+			t.Errorf("TestStart(%s): failed keys: -want/+got:\n%s", test.name, diff)
+		}
+
+		got := r.Progress()
+		got.Elapsed = 0
+		if diff := pretty.Compare(test.want, got); diff != "" {
+			t.Errorf("TestStart(%s): Progress: -want/+got:\n%s", test.name, diff)
+		}
+	}
+}
+
+// TestRunErrsCapped verifies a Run keeps only maxErrs failed pairs' errors for Wait, counts the rest in one more
+// error, and still counts every failure in Progress.
+func TestRunErrsCapped(t *testing.T) {
+	ctx := t.Context()
+
+	const n = maxErrs + 50
+	in := make([]int, n)
+	r := Start(ctx, "capped", 4, stream.Slice(in), func(ctx context.Context, k, v int) error { return errBoom })
+	errs := joined(r.Wait(ctx))
+
+	workers := 0
+	for _, e := range errs {
+		var we *WorkerError[int]
+		if errors.As(e, &we) {
+			workers++
+		}
+	}
+	if workers != maxErrs || len(errs) != maxErrs+1 {
+		t.Errorf("TestRunErrsCapped: got %d errors, %d of them WorkerErrors, want %d and %d", len(errs), workers, maxErrs+1, maxErrs)
+	}
+	if got, want := errs[len(errs)-1].Error(), "fanout: 50 more failed pairs not kept"; got != want {
+		t.Errorf("TestRunErrsCapped: got last error %q, want %q", got, want)
+	}
+	if got := r.Progress().Failed; got != n {
+		t.Errorf("TestRunErrsCapped: got Progress().Failed == %d, want %d", got, n)
+	}
+}
+
+// joined returns the errors err joins, or err alone.
+func joined(err error) []error {
+	if err == nil {
+		return nil
+	}
+	if j, ok := err.(interface{ Unwrap() []error }); ok {
+		return j.Unwrap()
+	}
+	return []error{err}
+}
+
+// TestRunCancel proves Cancel ends a run whose Workers block on their Context, which Wait then reports.
+func TestRunCancel(t *testing.T) {
+	ctx := t.Context()
+
+	started := make(chan struct{}, 10)
+	fn := func(ctx context.Context, k int, v int) error {
+		started <- struct{}{}
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	r := Start(ctx, "TestRunCancel", 2, stream.Slice(make([]int, 10)), fn)
+	<-started
+
+	// Wait gives up with its own Context while the run goes on.
+	short, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	if err := r.Wait(short); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("TestRunCancel: got Wait() == %v before Cancel, want context.DeadlineExceeded", err)
+	}
+	if r.Progress().Done {
+		t.Errorf("TestRunCancel: got a Done run before Cancel")
+	}
+
+	r.Cancel()
+	err := r.Wait(ctx)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("TestRunCancel: got Wait() == %v after Cancel, want context.Canceled", err)
+	}
+	var we *WorkerError[int]
+	if errors.As(err, &we) {
+		t.Errorf("TestRunCancel: got a WorkerError for pair %d cut short by Cancel, want none", we.Key)
+	}
+	p := r.Progress()
+	if !p.Done || p.Canceled < p.Started || p.Failed != 0 || p.Succeeded != 0 {
+		t.Errorf("TestRunCancel: got Progress() == %+v, want a Done run with every started pair Canceled", p)
+	}
+}
+
+// TestRunResize proves Resize widens a running run at once and, shrunk, lets the running Workers finish.
+func TestRunResize(t *testing.T) {
+	ctx := t.Context()
+
+	var running atomic.Int64
+	release := make(chan struct{})
+	fn := func(ctx context.Context, k int, v int) error {
+		running.Add(1)
+		defer running.Add(-1)
+		<-release
+		return nil
+	}
+	// settle waits for the Workers running to reach want.
+	settle := func(want int64) {
+		t.Helper()
+		deadline := time.Now().Add(5 * time.Second)
+		for running.Load() != want {
+			if time.Now().After(deadline) {
+				t.Fatalf("TestRunResize: got %d Workers running, want %d", running.Load(), want)
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}
+
+	r := Start(ctx, "TestRunResize", 1, stream.Slice(make([]int, 10)), fn)
+	settle(1)
+
+	if err := r.Resize(0); err == nil {
+		t.Errorf("TestRunResize: got Resize(0) == nil error, want err != nil")
+	}
+	if err := r.Resize(maxSize + 1); err == nil {
+		t.Errorf("TestRunResize: got Resize(%d) == nil error past the pool, want err != nil", maxSize+1)
+	}
+
+	if err := r.Resize(3); err != nil {
+		t.Fatalf("TestRunResize: Resize(3): %s", err)
+	}
+	settle(3)
+	if err := r.Resize(1); err != nil {
+		t.Fatalf("TestRunResize: Resize(1): %s", err)
+	}
+	time.Sleep(20 * time.Millisecond)
+	if n := running.Load(); n != 3 {
+		t.Errorf("TestRunResize: got %d Workers running after shrinking, want the 3 already running", n)
+	}
+	if p := r.Progress(); p.Size != 1 {
+		t.Errorf("TestRunResize: got Progress().Size == %d, want 1", p.Size)
+	}
+
+	close(release)
+	if err := r.Wait(ctx); err != nil {
+		t.Errorf("TestRunResize: got Wait() == %s, want nil", err)
+	}
+	if p := r.Progress(); p.Succeeded != 10 {
+		t.Errorf("TestRunResize: got %d pairs succeeded, want 10", p.Succeeded)
+	}
+}
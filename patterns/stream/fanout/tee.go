@@ -0,0 +1,154 @@
+package fanout
+
+import (
+	"errors"
+	"fmt"
+	"iter"
+
+	"github.com/gostdlib/base/context"
+	"github.com/gostdlib/concurrency/patterns/stream/foreach"
+)
+
+// Sink is one of the independent Worker groups a Tee drives. Its Worker runs on every pair, Size at a time, on a
+// pool of its own named Name, with its own Options: a gate, or a stop on error, that no other Sink sees.
+type Sink[K, V any] struct {
+	// Name names the Sink's pool and is how TeeRun.Sink and the errors of TeeRun.Wait tell the Sinks apart. It
+	// must be unique within a Tee.
+	Name string
+	// Size is how many of the Sink's Workers may run at once, as for Start.
+	Size    int
+	Worker  Worker[K, V]
+	Options []Option
+}
+
+// TeeRun is a Tee's run: one Run per Sink, fed by a single pass over the input. All methods are safe for
+// concurrent use.
+type TeeRun struct {
+	names  []string
+	runs   map[string]*Run
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// pair is one key/value pair on its way to a Sink.
+type pair[K, V any] struct {
+	k K
+	v V
+}
+
+// Tee runs every key/value pair in seq through each of sinks, reading seq once: write a row to a database,
+// publish an event and update a cache, each a Sink with its own Workers, limit, gate and errors. Each Sink is a
+// Run as Start returns, so one Sink failing, retrying or stopping leaves the others running; a Sink that stops
+// before the input ends, through WithStopOnErr or its Run's Cancel, is just sent no more pairs.
+//
+// Each Sink takes pairs through a buffer of buffer pairs. While a Sink is slower than the rest its buffer
+// fills, and once it is full the reading of seq waits for that Sink: the fastest Sink runs at most buffer pairs,
+// plus those the slowest has in hand, ahead of the slowest. Cancelling ctx, or calling TeeRun.Cancel, stops
+// every Sink. Tee panics if sinks is empty, a Name is empty or repeated, buffer < 0, or a Sink's arguments would
+// make Start panic.
+func Tee[K, V any](ctx context.Context, seq iter.Seq2[K, V], buffer int, sinks ...Sink[K, V]) *TeeRun {
+	if seq == nil {
+		panic("fanout.Tee: cannot have a nil seq")
+	}
+	if len(sinks) == 0 {
+		panic("fanout.Tee: cannot have no Sinks")
+	}
+	if buffer < 0 {
+		panic("fanout.Tee: cannot have a buffer < 0")
+	}
+	// Check every Sink before starting any, so a bad one does not leave the others running.
+	opts := make([][]foreach.Option, len(sinks))
+	seen := map[string]bool{}
+	for i, s := range sinks {
+		if s.Name == "" {
+			panic("fanout.Tee: cannot have a Sink with an empty Name")
+		}
+		if seen[s.Name] {
+			panic(fmt.Sprintf("fanout.Tee: cannot have two Sinks named %q", s.Name))
+		}
+		seen[s.Name] = true
+		opts[i] = prepare(ctx, "fanout.Tee: Sink "+s.Name, s.Size, seq, s.Worker, s.Options)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	t := &TeeRun{runs: map[string]*Run{}, cancel: cancel, done: make(chan struct{})}
+	chans := make([]chan pair[K, V], len(sinks))
+	runs := make([]*Run, len(sinks))
+	for i, s := range sinks {
+		ch := make(chan pair[K, V], buffer)
+		in := func(yield func(K, V) bool) {
+			for p := range ch {
+				if !yield(p.k, p.v) {
+					return
+				}
+			}
+		}
+		chans[i] = ch
+		runs[i] = start(ctx, s.Name, s.Size, iter.Seq2[K, V](in), s.Worker, opts[i])
+		t.names = append(t.names, s.Name)
+		t.runs[s.Name] = runs[i]
+	}
+
+	// The reader runs as Limited's driver does, on the default pool and a WithoutCancel Context.
+	_ = context.Pool(ctx).Default().Submit(context.WithoutCancel(ctx), func() {
+		distribute(ctx, seq, chans, runs)
+		for _, ch := range chans {
+			close(ch)
+		}
+		for _, r := range runs {
+			<-r.done
+		}
+		cancel()
+		close(t.done)
+	})
+	return t
+}
+
+// distribute sends every pair in seq to each of chans in turn, skipping the Sinks whose Run has finished, until
+// seq ends or ctx is cancelled.
+func distribute[K, V any](ctx context.Context, seq iter.Seq2[K, V], chans []chan pair[K, V], runs []*Run) {
+	for k, v := range seq {
+		for i, ch := range chans {
+			select {
+			case ch <- pair[K, V]{k: k, v: v}:
+			case <-runs[i].done:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}
+}
+
+// Sink returns the Run of the Sink named name, to watch its Progress, Resize or Cancel it alone, or nil if there
+// is no such Sink.
+func (t *TeeRun) Sink(name string) *Run {
+	return t.runs[name]
+}
+
+// Wait blocks until every Sink has finished and returns each Sink's error from Run.Wait, wrapped with the Sink's
+// name and joined with errors.Join; nil if every pair succeeded in every Sink. If ctx ends first, Wait returns
+// ctx's error and the run goes on.
+func (t *TeeRun) Wait(ctx context.Context) error {
+	select {
+	case <-t.done:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	var errs []error
+	for _, name := range t.names {
+		if err := t.runs[name].Wait(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("fanout: sink %q: %w", name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Done returns a channel that is closed once every Sink has finished.
+func (t *TeeRun) Done() <-chan struct{} {
+	return t.done
+}
+
+// Cancel stops every Sink, as Run.Cancel stops one. Cancel may be called more than once.
+func (t *TeeRun) Cancel() {
+	t.cancel()
+}
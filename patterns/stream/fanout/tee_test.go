@@ -0,0 +1,139 @@
+package fanout
+
+import (
+	"errors"
+	"iter"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gostdlib/base/concurrency/sync"
+	"github.com/gostdlib/base/context"
+	"github.com/gostdlib/concurrency/patterns/stream"
+	"github.com/kylelemons/godebug/pretty"
+)
+
+// TestTee proves every Sink sees every pair, and one Sink's failures, or its early stop, stay its own.
+func TestTee(t *testing.T) {
+	ctx := t.Context()
+
+	// record returns a Worker that records the keys it is called with in got, failing on key fail (-1 for none).
+	record := func(got *[]int, mu *sync.Mutex, fail int) Worker[int, int] {
+		return func(ctx context.Context, k int, v int) error {
+			if k == fail {
+				return errBoom
+			}
+			mu.Lock()
+			*got = append(*got, k)
+			mu.Unlock()
+			return nil
+		}
+	}
+
+	var mu sync.Mutex
+	var db, events, cache []int
+	r := Tee(ctx, stream.Slice(make([]int, 20)), 4,
+		Sink[int, int]{Name: "db", Size: 2, Worker: record(&db, &mu, 3)},
+		Sink[int, int]{Name: "events", Size: 4, Worker: record(&events, &mu, -1)},
+		Sink[int, int]{Name: "cache", Size: 1, Worker: record(&cache, &mu, 0), Options: []Option{WithStopOnErr()}},
+	)
+	err := r.Wait(ctx)
+
+	if !strings.Contains(err.Error(), `sink "db"`) || !strings.Contains(err.Error(), `sink "cache"`) || strings.Contains(err.Error(), `sink "events"`) {
+		t.Errorf("TestTee: got Wait() == %v, want errors from the db and cache Sinks only", err)
+	}
+	var we *WorkerError[int]
+	if !errors.As(err, &we) {
+		t.Errorf("TestTee: got Wait() == %v, want it to hold a *WorkerError[int]", err)
+	}
+
+	want := map[string]Progress{
+		"db":     {Started: 20, Succeeded: 19, Failed: 1, Size: 2, Done: true},
+		"events": {Started: 20, Succeeded: 20, Size: 4, Done: true},
+	}
+	for name, w := range want {
+		got := r.Sink(name).Progress()
+		got.Elapsed = 0
+		if diff := pretty.Compare(w, got); diff != "" {
+			t.Errorf("TestTee: Sink %q Progress: -want/+got:\n%s", name, diff)
+		}
+	}
+	if len(db) != 19 || len(events) != 20 {
+		t.Errorf("TestTee: got %d pairs written to db and %d to events, want 19 and 20", len(db), len(events))
+	}
+	// The cache Sink stopped on its first pair's error, and was sent no more.
+	if p := r.Sink("cache").Progress(); p.Failed != 1 || p.Succeeded+p.Canceled+p.Failed >= 20 {
+		t.Errorf("TestTee: got cache Progress %+v, want a stop after its failed first pair", p)
+	}
+	if r.Sink("none") != nil {
+		t.Errorf("TestTee: got a Run for a Sink that does not exist")
+	}
+}
+
+// TestTeeBackpressure proves a stalled Sink holds the reading of the input back once its buffer is full, and
+// lets it go on when it resumes.
+func TestTeeBackpressure(t *testing.T) {
+	ctx := t.Context()
+
+	const (
+		total  = 100
+		buffer = 5
+	)
+	var read atomic.Int64
+	var seq iter.Seq2[int, int] = func(yield func(int, int) bool) {
+		for i := range total {
+			read.Add(1)
+			if !yield(i, i) {
+				return
+			}
+		}
+	}
+	release := make(chan struct{})
+	slow := func(ctx context.Context, k int, v int) error {
+		<-release
+		return nil
+	}
+	var fast atomic.Int64
+	quick := func(ctx context.Context, k int, v int) error {
+		fast.Add(1)
+		return nil
+	}
+
+	r := Tee(ctx, seq, buffer,
+		Sink[int, int]{Name: "slow", Size: 1, Worker: slow},
+		Sink[int, int]{Name: "fast", Size: 4, Worker: quick},
+	)
+	time.Sleep(50 * time.Millisecond)
+
+	// The slow Sink holds one pair in its Worker and one in its puller, and the reader one more, on top of its
+	// buffer; allow one over that.
+	if n := read.Load(); n > buffer+4 {
+		t.Errorf("TestTeeBackpressure: got %d pairs read while a Sink was stalled, want at most %d", n, buffer+4)
+	}
+	close(release)
+	if err := r.Wait(ctx); err != nil {
+		t.Fatalf("TestTeeBackpressure: got Wait() == %s, want nil", err)
+	}
+	if read.Load() != total || fast.Load() != total {
+		t.Errorf("TestTeeBackpressure: got %d pairs read and %d run by the fast Sink, want %d", read.Load(), fast.Load(), total)
+	}
+}
+
+func TestTeeCancel(t *testing.T) {
+	ctx := t.Context()
+
+	block := func(ctx context.Context, k int, v int) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	r := Tee(ctx, stream.Slice(make([]int, 50)), 1,
+		Sink[int, int]{Name: "a", Size: 2, Worker: block},
+		Sink[int, int]{Name: "b", Size: 2, Worker: block},
+	)
+	r.Cancel()
+	<-r.Done()
+	if err := r.Wait(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("TestTeeCancel: got Wait() == %v, want context.Canceled", err)
+	}
+}
@@ -0,0 +1,139 @@
+package foreach
+
+import (
+	"math"
+	"time"
+
+	"github.com/gostdlib/base/concurrency/sync"
+	"github.com/gostdlib/base/context"
+)
+
+const (
+	// adaptiveTolerance is how many times its baseline an ItemFunc's latency may be before adaptive takes it
+	// as a sign the dependency is queueing and lowers the limit.
+	adaptiveTolerance = 2
+	// adaptiveBackoff is what the limit is multiplied by on an error or a slow ItemFunc.
+	adaptiveBackoff = 0.9
+	// adaptiveDrift is the share of the gap to a slower latency the baseline moves up by on each success, so a
+	// dependency that gets slower for good is in time treated as its new normal.
+	adaptiveDrift = 0.01
+)
+
+// adaptive is the WithAdaptiveConcurrency limit on ItemFuncs in flight. It grows by one for every limit's worth
+// of ItemFuncs that succeed while the limit is in use (additive increase) and shrinks by a tenth on an error or
+// on a latency over adaptiveTolerance times the baseline (multiplicative decrease). The baseline is the lowest
+// latency seen, drifting up towards slower latencies by adaptiveDrift. Dispatch waits on it through the
+// pre-dispatch checkpoint. Each Item range gets its own adaptive. All methods are safe for concurrent use.
+type adaptive struct {
+	// ctx is the range's Context, used to record the limit.
+	ctx context.Context
+	// metrics is the range's WithName metrics, which the limit is recorded on, or nil.
+	metrics *metrics
+
+	mu       sync.Mutex
+	min, max float64
+	// limit is the current limit; its integer part bounds inflight.
+	limit float64
+	// inflight is the number of ItemFuncs dispatched and not yet done.
+	inflight int
+	// baseline is the latency of an ItemFunc that did not queue, or 0 before the first success.
+	baseline time.Duration
+	// changed is closed and replaced whenever inflight falls or the limit rises; waiters block on the channel
+	// they observed, then re-check.
+	changed chan struct{}
+}
+
+// newAdaptive returns an adaptive that starts at min and stays within [min, max]. m may be nil.
+func newAdaptive(ctx context.Context, min, max int, m *metrics) *adaptive {
+	a := &adaptive{
+		ctx:     ctx,
+		metrics: m,
+		min:     float64(min),
+		max:     float64(max),
+		limit:   float64(min),
+		changed: make(chan struct{}),
+	}
+	if m != nil {
+		m.AdaptiveLimit.Record(ctx, int64(min))
+	}
+	return a
+}
+
+// below reports whether another ItemFunc can be dispatched.
+func (a *adaptive) below() bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.inflight < int(a.limit)
+}
+
+// wait blocks until another ItemFunc can be dispatched. It returns ctx.Err() if ctx is cancelled first.
+func (a *adaptive) wait(ctx context.Context) error {
+	for {
+		a.mu.Lock()
+		if a.inflight < int(a.limit) {
+			a.mu.Unlock()
+			return nil
+		}
+		changed := a.changed
+		a.mu.Unlock()
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// start records an ItemFunc being dispatched. Only the dispatch loop calls it, after wait, so inflight cannot
+// pass the limit between the two.
+func (a *adaptive) start() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.inflight++
+}
+
+// done records a dispatched ItemFunc finishing after latency with err, and moves the limit. An error wrapping
+// ErrPermanent or from cancellation says nothing about the dependency's load, so it only frees the slot.
+func (a *adaptive) done(latency time.Duration, err error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	// Whether the limit was in use is judged before this ItemFunc leaves: growing a limit that is not reached
+	// would let it climb without bound while the input is slow.
+	full := a.inflight >= int(a.limit)
+	a.inflight--
+	before := int(a.limit)
+
+	switch {
+	case err != nil && !dependencyErr(err):
+	case err != nil:
+		a.limit = math.Max(a.limit*adaptiveBackoff, a.min)
+	case a.baseline == 0 || latency < a.baseline:
+		a.baseline = latency
+		if full {
+			a.limit = math.Min(a.limit+1/a.limit, a.max)
+		}
+	case latency > adaptiveTolerance*a.baseline:
+		a.baseline += time.Duration(float64(latency-a.baseline) * adaptiveDrift)
+		a.limit = math.Max(a.limit*adaptiveBackoff, a.min)
+	default:
+		a.baseline += time.Duration(float64(latency-a.baseline) * adaptiveDrift)
+		if full {
+			a.limit = math.Min(a.limit+1/a.limit, a.max)
+		}
+	}
+
+	if after := int(a.limit); after != before && a.metrics != nil {
+		a.metrics.AdaptiveLimit.Record(a.ctx, int64(after))
+	}
+	// Wake the dispatch loop: a slot came free, and the limit may have risen.
+	close(a.changed)
+	a.changed = make(chan struct{})
+}
+
+// current returns the current limit.
+func (a *adaptive) current() int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return int(a.limit)
+}
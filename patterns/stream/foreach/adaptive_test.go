@@ -0,0 +1,194 @@
+package foreach
+
+import (
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gostdlib/base/context"
+	"github.com/kylelemons/godebug/pretty"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+func TestAdaptiveDone(t *testing.T) {
+	t.Parallel()
+
+	boom := errors.New("boom")
+
+	// call is one ItemFunc: how many others are in flight with it, how long it took and what it returned.
+	type call struct {
+		others  int
+		latency time.Duration
+		err     error
+	}
+
+	tests := []struct {
+		name     string
+		min, max int
+		calls    []call
+		// want is the limit after each call.
+		want []int
+	}{
+		{
+			name: "Success: successes at the limit grow it by one per limit's worth",
+			min:  1,
+			max:  10,
+			calls: []call{
+				{latency: time.Millisecond},            // 1 -> 2
+				{others: 1, latency: time.Millisecond}, // 2 -> 2.5
+				{others: 1, latency: time.Millisecond}, // 2.5 -> 2.9
+				{others: 1, latency: time.Millisecond}, // 2.9 -> 3.24
+			},
+			want: []int{2, 2, 2, 3},
+		},
+		{
+			name: "Success: successes below the limit do not grow it",
+			min:  2,
+			max:  10,
+			calls: []call{
+				{latency: time.Millisecond},
+				{latency: time.Millisecond},
+			},
+			want: []int{2, 2},
+		},
+		{
+			name: "Success: the limit does not grow past max",
+			min:  2,
+			max:  2,
+			calls: []call{
+				{others: 1, latency: time.Millisecond},
+				{others: 1, latency: time.Millisecond},
+			},
+			want: []int{2, 2},
+		},
+		{
+			name: "Error: an error shrinks the limit by a tenth, but not below min",
+			min:  5,
+			max:  20,
+			calls: []call{
+				{others: 4, latency: time.Millisecond}, // 5 -> 5.2
+				{others: 4, latency: time.Millisecond}, // 5.2 -> 5.39
+				{err: boom},                            // 5.39 -> 5 (floor)
+			},
+			want: []int{5, 5, 5},
+		},
+		{
+			name: "Error: a latency over twice the baseline shrinks the limit",
+			min:  1,
+			max:  20,
+			calls: []call{
+				{latency: time.Millisecond},                // 1 -> 2
+				{others: 1, latency: time.Millisecond},     // 2 -> 2.5
+				{others: 1, latency: time.Millisecond},     // 2.5 -> 2.9
+				{others: 1, latency: time.Millisecond},     // 2.9 -> 3.24
+				{others: 2, latency: 3 * time.Millisecond}, // 3.24 -> 2.92
+			},
+			want: []int{2, 2, 2, 3, 2},
+		},
+		{
+			name: "Success: permanent and cancellation errors leave the limit alone",
+			min:  1,
+			max:  20,
+			calls: []call{
+				{latency: time.Millisecond},                // 1 -> 2
+				{err: fmt.Errorf("bad: %w", ErrPermanent)}, // unchanged
+				{others: 1, err: context.Canceled},         // unchanged
+				{others: 1, err: context.DeadlineExceeded}, // unchanged
+			},
+			want: []int{2, 2, 2, 2},
+		},
+	}
+
+	for _, test := range tests {
+		a := newAdaptive(t.Context(), test.min, test.max, nil)
+		var got []int
+		for _, c := range test.calls {
+			for range c.others + 1 {
+				a.start()
+			}
+			a.done(c.latency, c.err)
+			// The others finish without moving the limit.
+			a.mu.Lock()
+			a.inflight -= c.others
+			a.mu.Unlock()
+			got = append(got, a.current())
+		}
+		if diff := pretty.Compare(test.want, got); diff != "" {
+			t.Errorf("TestAdaptiveDone(%s): -want/+got:\n%s", test.name, diff)
+		}
+	}
+}
+
+// TestWithAdaptiveConcurrency verifies Item never runs more ItemFuncs at once than the adaptive limit allows:
+// max while every call succeeds, and min while every call fails.
+func TestWithAdaptiveConcurrency(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		fail     bool
+		wantPeak int64
+	}{
+		{
+			name:     "Success: grows to but not past max",
+			wantPeak: 4,
+		},
+		{
+			name:     "Error: stays at min while every call fails",
+			fail:     true,
+			wantPeak: 1,
+		},
+	}
+
+	for _, test := range tests {
+		reader := sdkmetric.NewManualReader()
+		ctx := context.SetMeterProvider(t.Context(), sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader)))
+
+		var running, peak atomic.Int64
+		fn := func(ctx context.Context, _ int, v int) (int, error) {
+			n := running.Add(1)
+			defer running.Add(-1)
+			for {
+				p := peak.Load()
+				if n <= p || peak.CompareAndSwap(p, n) {
+					break
+				}
+			}
+			time.Sleep(time.Millisecond)
+			if test.fail {
+				return 0, errors.New("overloaded")
+			}
+			return v, nil
+		}
+
+		n := 0
+		for range Item(ctx, seqOf(ints(200)...), fn, WithAdaptiveConcurrency(1, 4), WithName("adaptive")) {
+			n++
+		}
+		if n != 200 {
+			t.Errorf("TestWithAdaptiveConcurrency(%s): got %d responses, want 200", test.name, n)
+		}
+		if got := peak.Load(); got > test.wantPeak {
+			t.Errorf("TestWithAdaptiveConcurrency(%s): got %d ItemFuncs at once, want at most %d", test.name, got, test.wantPeak)
+		}
+
+		var rm metricdata.ResourceMetrics
+		if err := reader.Collect(ctx, &rm); err != nil {
+			t.Fatalf("TestWithAdaptiveConcurrency(%s): could not collect metrics: %s", test.name, err)
+		}
+		var limit int64 = -1
+		for _, sm := range rm.ScopeMetrics {
+			for _, m := range sm.Metrics {
+				if g, ok := m.Data.(metricdata.Gauge[int64]); ok && sm.Scope.Name == meterName+"/adaptive" && m.Name == "adaptive.limit" {
+					limit = g.DataPoints[0].Value
+				}
+			}
+		}
+		if limit < 1 || limit > test.wantPeak {
+			t.Errorf("TestWithAdaptiveConcurrency(%s): got adaptive.limit %d, want 1..%d", test.name, limit, test.wantPeak)
+		}
+	}
+}
@@ -0,0 +1,219 @@
+package foreach
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gostdlib/base/concurrency/sync"
+	"github.com/gostdlib/base/context"
+)
+
+// ErrCircuitOpen is the Response error of a pair that was not run because WithCircuitBreaker's breaker was
+// open. It wraps ErrPermanent, so WithGate does not retry it: a range against a dependency that has not
+// recovered fails fast instead of waiting out the Backoff. Check for it with errors.Is(err, ErrCircuitOpen).
+var ErrCircuitOpen = fmt.Errorf("foreach: circuit breaker is open: %w", ErrPermanent)
+
+// CircuitState is the state of a CircuitBreaker.
+//
+//go:generate go tool github.com/gostdlib/base/values/generators/stringer -type=CircuitState
+type CircuitState uint8
+
+const (
+	// CircuitClosed runs every pair and counts the failures.
+	CircuitClosed CircuitState = iota
+	// CircuitOpen fails every pair with ErrCircuitOpen without running it.
+	CircuitOpen
+	// CircuitHalfOpen runs a few probe pairs to test whether the dependency has recovered, and fails the rest
+	// with ErrCircuitOpen.
+	CircuitHalfOpen
+)
+
+// Defaults for the CircuitBreaker fields left at zero.
+const (
+	defaultThreshold = 5
+	defaultWindow    = 10 * time.Second
+	defaultCooldown  = 5 * time.Second
+	defaultProbes    = 1
+)
+
+// CircuitBreaker stops running ItemFuncs against a dependency that keeps failing. It starts closed; once
+// Threshold ItemFuncs fail within Window it opens, and every pair fails with ErrCircuitOpen without running.
+// After Cooldown it goes half-open and lets Probes pairs run: if they all succeed it closes again, and if one
+// fails it opens for another Cooldown.
+//
+// Only an error that says the dependency failed counts: an error wrapping ErrPermanent, or from the Context
+// being cancelled or passing its deadline, does not. A probe that ends with one neither fails nor passes, and
+// another pair takes its place. Under WithGate every attempt is run through the breaker.
+//
+// Set the fields before first use and do not change them after. A CircuitBreaker is safe for concurrent use,
+// so one breaker passed to several Item calls (and ranges) against the same dependency trips for all of them.
+type CircuitBreaker struct {
+	// Threshold is how many failures within Window open the breaker. Defaults to 5.
+	Threshold int
+	// Window is how far back failures are counted. Defaults to 10 seconds.
+	Window time.Duration
+	// Cooldown is how long the breaker stays open before it lets probes through. Defaults to 5 seconds.
+	Cooldown time.Duration
+	// Probes is how many pairs run while half-open, and must all succeed to close the breaker. Defaults to 1.
+	Probes int
+
+	mu    sync.Mutex
+	state CircuitState
+	// failures are the times of the failures within Window while closed, oldest first.
+	failures []time.Time
+	// opened is when the breaker last opened.
+	opened time.Time
+	// probing is the number of probes let through since the breaker went half-open; passed is how many of them
+	// have succeeded.
+	probing, passed int
+	// gen counts the times the breaker opened. A call let through before the breaker last changed state
+	// reports with the gen it started in, so its result does not count against the new state.
+	gen uint64
+
+	// now is time.Now, replaced in tests.
+	now func() time.Time
+}
+
+// validate checks the fields.
+func (c *CircuitBreaker) validate() error {
+	switch {
+	case c.Threshold < 0:
+		return fmt.Errorf("foreach.WithCircuitBreaker: Threshold must be >= 0, got %d: %w", c.Threshold, ErrPermanent)
+	case c.Window < 0:
+		return fmt.Errorf("foreach.WithCircuitBreaker: Window must be >= 0, got %s: %w", c.Window, ErrPermanent)
+	case c.Cooldown < 0:
+		return fmt.Errorf("foreach.WithCircuitBreaker: Cooldown must be >= 0, got %s: %w", c.Cooldown, ErrPermanent)
+	case c.Probes < 0:
+		return fmt.Errorf("foreach.WithCircuitBreaker: Probes must be >= 0, got %d: %w", c.Probes, ErrPermanent)
+	}
+	return nil
+}
+
+// State returns the breaker's state. An open breaker whose Cooldown has passed is reported half-open.
+func (c *CircuitBreaker) State() CircuitState {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.advance(c.clock())
+	return c.state
+}
+
+// clock returns the current time. c.mu must be held.
+func (c *CircuitBreaker) clock() time.Time {
+	if c.now == nil {
+		return time.Now()
+	}
+	return c.now()
+}
+
+// advance moves an open breaker whose Cooldown has passed to half-open. c.mu must be held.
+func (c *CircuitBreaker) advance(now time.Time) {
+	if c.state == CircuitOpen && now.Sub(c.opened) >= or(c.Cooldown, defaultCooldown) {
+		c.state = CircuitHalfOpen
+		c.probing, c.passed = 0, 0
+	}
+}
+
+// allow asks to run a pair. It returns ErrCircuitOpen if the pair must not run; otherwise the caller runs it and
+// passes its error to done.
+func (c *CircuitBreaker) allow() (done func(err error), err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.advance(c.clock())
+	switch c.state {
+	case CircuitOpen:
+		return nil, ErrCircuitOpen
+	case CircuitHalfOpen:
+		if c.probing >= or(c.Probes, defaultProbes) {
+			return nil, ErrCircuitOpen
+		}
+		c.probing++
+	}
+	gen, state := c.gen, c.state
+	return func(err error) { c.done(gen, state, err) }, nil
+}
+
+// done records the result of a pair allow let through while the breaker was in state during gen.
+func (c *CircuitBreaker) done(gen uint64, state CircuitState, err error) {
+	failed := err != nil && dependencyErr(err)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.clock()
+	c.advance(now)
+	if gen != c.gen || state != c.state {
+		// The breaker opened (or went half-open) since this pair started; its result is about the old state.
+		return
+	}
+
+	switch c.state {
+	case CircuitClosed:
+		if !failed {
+			return
+		}
+		cutoff := now.Add(-or(c.Window, defaultWindow))
+		i := 0
+		for i < len(c.failures) && !c.failures[i].After(cutoff) {
+			i++
+		}
+		c.failures = append(c.failures[i:], now)
+		if len(c.failures) >= or(c.Threshold, defaultThreshold) {
+			c.open(now)
+		}
+	case CircuitHalfOpen:
+		switch {
+		case failed:
+			c.open(now)
+			return
+		case err != nil:
+			// The probe says nothing about the dependency: free its slot for another.
+			c.probing--
+			return
+		}
+		c.passed++
+		if c.passed >= or(c.Probes, defaultProbes) {
+			c.state = CircuitClosed
+			c.failures = c.failures[:0]
+		}
+	}
+}
+
+// open opens the breaker at now. c.mu must be held.
+func (c *CircuitBreaker) open(now time.Time) {
+	c.state = CircuitOpen
+	c.opened = now
+	c.gen++
+}
+
+// dependencyErr reports whether err says the dependency an ItemFunc called failed, rather than the pair being
+// bad (ErrPermanent) or the run ending (cancellation or a deadline).
+func dependencyErr(err error) bool {
+	return !errors.Is(err, ErrPermanent) && !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded)
+}
+
+// or returns v, or def if v is the zero value.
+func or[T comparable](v, def T) T {
+	var zero T
+	if v == zero {
+		return def
+	}
+	return v
+}
+
+// WithCircuitBreaker runs every ItemFunc through cb, so that while cb is open new pairs yield an ErrCircuitOpen
+// Response instead of running. Pass the same cb to every Item call against the same dependency to have them
+// trip together. A nil cb or one with a negative field is an error.
+func WithCircuitBreaker(cb *CircuitBreaker) Option {
+	return func(o options) (options, error) {
+		if cb == nil {
+			return o, fmt.Errorf("foreach.WithCircuitBreaker: cb cannot be nil: %w", ErrPermanent)
+		}
+		if err := cb.validate(); err != nil {
+			return o, err
+		}
+		o.breaker = cb
+		return o, nil
+	}
+}
@@ -0,0 +1,187 @@
+package foreach
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/gostdlib/base/context"
+	"github.com/kylelemons/godebug/pretty"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	t.Parallel()
+
+	boom := errors.New("boom")
+
+	// step moves the clock on by after, then either runs a pair that returns err (reporting it at once) or,
+	// with hold, starts a pair that reports err only after the next step. It records whether the pair was let
+	// through and the state after.
+	type step struct {
+		after time.Duration
+		err   error
+		hold  bool
+	}
+	type result struct {
+		Allowed bool
+		State   string
+	}
+
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	now := start
+	cb := &CircuitBreaker{Threshold: 2, Window: 10 * time.Second, Cooldown: 5 * time.Second, Probes: 2}
+	cb.now = func() time.Time { return now }
+
+	steps := []step{
+		{err: boom},                              // 1 failure.
+		{after: 11 * time.Second, err: boom},     // The first has left the window: still 1.
+		{err: fmt.Errorf("x: %w", ErrPermanent)}, // Permanent errors do not count.
+		{hold: true},                             // Starts while closed, finishes after the breaker opens.
+		{err: boom},                              // 2 within the window: open.
+		{},                                       // Rejected while open; the held pair reports success, ignored.
+		{after: 5 * time.Second, hold: true},     // Half-open: first probe, held.
+		{},                                       // Second probe succeeds; the held probe then succeeds: closed.
+		{err: boom},                              // Closed again, 1 failure.
+		{err: boom},                              // Open.
+		{after: 5 * time.Second, err: boom},      // Half-open probe fails: open again.
+		{after: time.Second},                     // Still within the new Cooldown.
+		{after: 4 * time.Second, err: context.Canceled}, // Half-open probe cut short: not a pass.
+		{err: fmt.Errorf("x: %w", ErrPermanent)},        // Nor is a permanent error; the slots are freed.
+		{},                                              // First probe to pass.
+		{},                                              // Second probe to pass: closed.
+	}
+	want := []result{
+		{true, "CircuitClosed"},
+		{true, "CircuitClosed"},
+		{true, "CircuitClosed"},
+		{true, "CircuitClosed"},
+		{true, "CircuitOpen"},
+		{false, "CircuitOpen"},
+		{true, "CircuitHalfOpen"},
+		{true, "CircuitClosed"},
+		{true, "CircuitClosed"},
+		{true, "CircuitOpen"},
+		{true, "CircuitOpen"},
+		{false, "CircuitOpen"},
+		{true, "CircuitHalfOpen"},
+		{true, "CircuitHalfOpen"},
+		{true, "CircuitHalfOpen"},
+		{true, "CircuitClosed"},
+	}
+
+	var (
+		got  []result
+		held func(error)
+	)
+	for _, s := range steps {
+		now = now.Add(s.after)
+		done, err := cb.allow()
+		if err != nil && !errors.Is(err, ErrCircuitOpen) {
+			t.Fatalf("TestCircuitBreaker: got err == %v, want ErrCircuitOpen", err)
+		}
+		prev := held
+		held = nil
+		switch {
+		case done == nil:
+		case s.hold:
+			held = done
+		default:
+			done(s.err)
+		}
+		if prev != nil {
+			prev(nil)
+		}
+		got = append(got, result{Allowed: done != nil, State: cb.State().String()})
+	}
+	if diff := pretty.Compare(want, got); diff != "" {
+		t.Errorf("TestCircuitBreaker: -want/+got:\n%s", diff)
+	}
+}
+
+// TestWithCircuitBreaker verifies an open breaker fails pairs fast without running them, in the Item call that
+// tripped it and in another Item call sharing it, and that under WithGate it ends the retry instead of hanging.
+func TestWithCircuitBreaker(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		opts []Option
+		// wantOpen is how many of the 10 pairs fail with ErrCircuitOpen. Under WithGate the first pair retries
+		// until the breaker opens, so its Response is ErrCircuitOpen too.
+		wantOpen int
+	}{
+		{
+			name:     "Success: without retries",
+			wantOpen: 7,
+		},
+		{
+			name:     "Success: with WithGate",
+			opts:     []Option{WithGate(testBoff())},
+			wantOpen: 10,
+		},
+	}
+
+	for _, test := range tests {
+		// A single slot makes the pairs run one at a time, so exactly Threshold of them fail before it opens.
+		ctx := context.SetPool(t.Context(), context.Pool(t.Context()).Limited(t.Context(), "TestWithCircuitBreaker", 1))
+		cb := &CircuitBreaker{Threshold: 3, Cooldown: time.Hour}
+		opts := append([]Option{WithCircuitBreaker(cb)}, test.opts...)
+
+		calls := 0
+		fn := func(ctx context.Context, _ int, v int) (int, error) {
+			calls++
+			return 0, errors.New("unavailable")
+		}
+
+		open := 0
+		for _, resp := range Item(ctx, seqOf(ints(10)...), fn, opts...) {
+			if errors.Is(resp.Err, ErrCircuitOpen) {
+				open++
+			}
+		}
+		if calls != 3 || open != test.wantOpen {
+			t.Errorf("TestWithCircuitBreaker(%s): got %d calls and %d ErrCircuitOpen, want 3 and %d", test.name, calls, open, test.wantOpen)
+		}
+
+		// Another Item call sharing the breaker fails fast too.
+		calls, open = 0, 0
+		for _, resp := range Item(ctx, seqOf(ints(5)...), fn, opts...) {
+			if errors.Is(resp.Err, ErrCircuitOpen) {
+				open++
+			}
+		}
+		if calls != 0 || open != 5 {
+			t.Errorf("TestWithCircuitBreaker(%s, shared): got %d calls and %d ErrCircuitOpen, want 0 and 5", test.name, calls, open)
+		}
+	}
+}
+
+func TestWithCircuitBreakerOption(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		cb      *CircuitBreaker
+		wantErr bool
+	}{
+		{name: "Success: zero fields use the defaults", cb: &CircuitBreaker{}},
+		{name: "Error: nil breaker", wantErr: true},
+		{name: "Error: negative Threshold", cb: &CircuitBreaker{Threshold: -1}, wantErr: true},
+		{name: "Error: negative Window", cb: &CircuitBreaker{Window: -1}, wantErr: true},
+		{name: "Error: negative Cooldown", cb: &CircuitBreaker{Cooldown: -1}, wantErr: true},
+		{name: "Error: negative Probes", cb: &CircuitBreaker{Probes: -1}, wantErr: true},
+	}
+
+	for _, test := range tests {
+		_, err := resolveOptions([]Option{WithCircuitBreaker(test.cb)})
+		switch {
+		case err == nil && test.wantErr:
+			t.Errorf("TestWithCircuitBreakerOption(%s): got err == nil, want err != nil", test.name)
+		case err != nil && !test.wantErr:
+			t.Errorf("TestWithCircuitBreakerOption(%s): got err == %s, want err == nil", test.name, err)
+		case err != nil && !errors.Is(err, ErrPermanent):
+			t.Errorf("TestWithCircuitBreakerOption(%s): got err == %s, want it to wrap ErrPermanent", test.name, err)
+		}
+	}
+}
@@ -0,0 +1,167 @@
+package foreach
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gostdlib/base/context"
+	"github.com/gostdlib/base/telemetry/otel/trace/span"
+)
+
+// Checkpointer persists how far a WithCheckpoint run has got, so a run that crashed can resume where it
+// stopped. Positions count input pairs: n means pairs 0 to n-1, in the order the input yields them, have all
+// completed. Save is only called with a position above the last one saved.
+type Checkpointer interface {
+	// Load returns the position saved by an earlier run, or 0 if there is none.
+	Load(ctx context.Context) (int, error)
+	// Save records that the first n input pairs have completed.
+	Save(ctx context.Context, n int) error
+}
+
+// FileCheckpointer is a Checkpointer that keeps the position in a file, as decimal text. Save replaces the file
+// through a rename, so a crash leaves either the old position or the new one, never a torn write.
+type FileCheckpointer struct {
+	path string
+}
+
+// NewFileCheckpointer returns a FileCheckpointer for the file at path. The file need not exist.
+func NewFileCheckpointer(path string) *FileCheckpointer {
+	return &FileCheckpointer{path: path}
+}
+
+// Load implements Checkpointer. A missing file is position 0.
+func (f *FileCheckpointer) Load(ctx context.Context) (int, error) {
+	b, err := os.ReadFile(f.path)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		return 0, nil
+	case err != nil:
+		return 0, fmt.Errorf("foreach.FileCheckpointer: %w", err)
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(string(b)))
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("foreach.FileCheckpointer: %s does not hold a position: %q", f.path, b)
+	}
+	return n, nil
+}
+
+// Save implements Checkpointer.
+func (f *FileCheckpointer) Save(ctx context.Context, n int) error {
+	tmp := f.path + ".tmp"
+	file, err := os.Create(tmp)
+	if err != nil {
+		return fmt.Errorf("foreach.FileCheckpointer: %w", err)
+	}
+	_, err = file.WriteString(strconv.Itoa(n) + "\n")
+	if err == nil {
+		err = file.Sync()
+	}
+	if cerr := file.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(tmp, f.path)
+	}
+	if err != nil {
+		return fmt.Errorf("foreach.FileCheckpointer: %w", err)
+	}
+	return nil
+}
+
+// Clear removes the file, so the next run starts from the first pair. Call it once a run has finished.
+func (f *FileCheckpointer) Clear() error {
+	if err := os.Remove(f.path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("foreach.FileCheckpointer: %w", err)
+	}
+	return nil
+}
+
+// WithCheckpoint makes a range resumable. When the range starts, Item loads cp's position and skips that many
+// pairs from the front of the input; as the range goes on, it saves the position below which every pair has
+// completed, at most once per every (0 saves at every advance) and once more when the range returns. A run that
+// crashes and is started again with the same input and cp picks up after the last position saved, so at most
+// the pairs completed since then run twice.
+//
+// A pair has completed once its Response has been yielded to the range's body and the body has returned,
+// whether the Response is a value or an error: handle errors in the body, or with WithDeadLetter, before
+// moving on. A pair cut short by cancellation has not, nor has any pair after it, so they run again on resume.
+//
+// The input must yield the same pairs in the same order on every run; the skipped pairs are still read from it,
+// just not run. A Load error ends the range with a single error Response; a Save error is recorded on the span
+// and the position is saved again at the next advance. cp must not be nil and every must be >= 0.
+func WithCheckpoint(cp Checkpointer, every time.Duration) Option {
+	return func(o options) (options, error) {
+		if cp == nil {
+			return o, fmt.Errorf("foreach.WithCheckpoint: cp cannot be nil: %w", ErrPermanent)
+		}
+		if every < 0 {
+			return o, fmt.Errorf("foreach.WithCheckpoint: every must be >= 0, got %s: %w", every, ErrPermanent)
+		}
+		o.checkpoint = cp
+		o.checkpointEvery = every
+		return o, nil
+	}
+}
+
+// progress tracks WithCheckpoint's position for a range. Pairs complete out of input order, so those past the
+// position wait in ahead until the gap below them fills. Only the range goroutine uses it.
+type progress struct {
+	cp    Checkpointer
+	every time.Duration
+	// next is the position: pairs below it have all completed.
+	next int
+	// saved is the last position saved, and last when it was saved.
+	saved int
+	last  time.Time
+	ahead map[int]struct{}
+}
+
+// loadProgress loads cp's position for a range starting.
+func loadProgress(ctx context.Context, cp Checkpointer, every time.Duration) (*progress, error) {
+	n, err := cp.Load(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("foreach.WithCheckpoint: could not load the checkpoint: %w", err)
+	}
+	if n < 0 {
+		return nil, fmt.Errorf("foreach.WithCheckpoint: the checkpoint loaded is negative (%d)", n)
+	}
+	return &progress{cp: cp, every: every, next: n, saved: n, last: time.Now(), ahead: map[int]struct{}{}}, nil
+}
+
+// complete records the pair at input index i as completed, and saves the position if it moved and every has
+// passed since the last save.
+func (p *progress) complete(ctx context.Context, i int) {
+	if i != p.next {
+		p.ahead[i] = struct{}{}
+		return
+	}
+	p.next++
+	for {
+		if _, ok := p.ahead[p.next]; !ok {
+			break
+		}
+		delete(p.ahead, p.next)
+		p.next++
+	}
+	if time.Since(p.last) >= p.every {
+		p.save(ctx)
+	}
+}
+
+// save saves the position if it moved since the last save.
+func (p *progress) save(ctx context.Context) {
+	if p.next == p.saved {
+		return
+	}
+	p.last = time.Now()
+	if err := p.cp.Save(ctx, p.next); err != nil {
+		span.Get(ctx).Span.RecordError(fmt.Errorf("foreach.WithCheckpoint: could not save the checkpoint: %w", err))
+		return
+	}
+	p.saved = p.next
+}
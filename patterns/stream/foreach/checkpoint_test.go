@@ -0,0 +1,170 @@
+package foreach
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/gostdlib/base/concurrency/sync"
+	"github.com/gostdlib/base/context"
+	"github.com/kylelemons/godebug/pretty"
+)
+
+// memCheckpointer is a Checkpointer in memory that records every Save.
+type memCheckpointer struct {
+	mu      sync.Mutex
+	n       int
+	saves   []int
+	loadErr error
+}
+
+func (m *memCheckpointer) Load(ctx context.Context) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.n, m.loadErr
+}
+
+func (m *memCheckpointer) Save(ctx context.Context, n int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.n = n
+	m.saves = append(m.saves, n)
+	return nil
+}
+
+func TestProgress(t *testing.T) {
+	t.Parallel()
+
+	cp := &memCheckpointer{}
+	p, err := loadProgress(t.Context(), cp, 0)
+	if err != nil {
+		t.Fatalf("TestProgress: loadProgress: %s", err)
+	}
+	for _, i := range []int{2, 0, 1, 4, 5, 3, 7} {
+		p.complete(t.Context(), i)
+	}
+
+	// 2 waits for 0, 0 saves 1, 1 fills up to 3, 4 and 5 wait for 3, 3 fills up to 6, 7 waits for 6.
+	want := []int{1, 3, 6}
+	if diff := pretty.Compare(want, cp.saves); diff != "" {
+		t.Errorf("TestProgress: saves: -want/+got:\n%s", diff)
+	}
+	p.save(t.Context())
+	if len(cp.saves) != 3 {
+		t.Errorf("TestProgress: got a save with the position unchanged")
+	}
+}
+
+func TestFileCheckpointer(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "checkpoint")
+	f := NewFileCheckpointer(path)
+
+	if n, err := f.Load(t.Context()); err != nil || n != 0 {
+		t.Errorf("TestFileCheckpointer: got Load() == (%d, %v) with no file, want (0, nil)", n, err)
+	}
+	if err := f.Save(t.Context(), 42); err != nil {
+		t.Fatalf("TestFileCheckpointer: Save: %s", err)
+	}
+	if n, err := f.Load(t.Context()); err != nil || n != 42 {
+		t.Errorf("TestFileCheckpointer: got Load() == (%d, %v), want (42, nil)", n, err)
+	}
+	if err := f.Clear(); err != nil {
+		t.Fatalf("TestFileCheckpointer: Clear: %s", err)
+	}
+	if n, err := f.Load(t.Context()); err != nil || n != 0 {
+		t.Errorf("TestFileCheckpointer: got Load() == (%d, %v) after Clear, want (0, nil)", n, err)
+	}
+
+	if err := os.WriteFile(path, []byte("not a number"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.Load(t.Context()); err == nil {
+		t.Errorf("TestFileCheckpointer: got Load() == nil error on a corrupt file, want err != nil")
+	}
+}
+
+// TestWithCheckpoint verifies a run stopped part way saves its position, and a second run over the same input
+// picks up there: every pair is yielded by one run or the other, and the pairs below the position only by the
+// first.
+func TestWithCheckpoint(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		opts []Option
+	}{
+		{name: "Success: unordered"},
+		{name: "Success: ordered", opts: []Option{WithOrdered()}},
+	}
+
+	for _, test := range tests {
+		cp := &memCheckpointer{}
+		opts := append([]Option{WithCheckpoint(cp, 0)}, test.opts...)
+		fn := func(ctx context.Context, _ int, v int) (int, error) {
+			return v, nil
+		}
+
+		// The first run stops after 30 Responses, like a crash.
+		var first []int
+		for k := range Item(t.Context(), seqOf(ints(100)...), fn, opts...) {
+			first = append(first, k)
+			if len(first) == 30 {
+				break
+			}
+		}
+		pos := cp.n
+		if pos > 30 {
+			t.Fatalf("TestWithCheckpoint(%s): got position %d after 30 Responses, want at most 30", test.name, pos)
+		}
+		if test.opts != nil && pos != 30 {
+			t.Errorf("TestWithCheckpoint(%s): got position %d, want 30", test.name, pos)
+		}
+		for i := range pos {
+			if !slices.Contains(first, i) {
+				t.Errorf("TestWithCheckpoint(%s): position %d is past pair %d, which the first run did not yield", test.name, pos, i)
+			}
+		}
+
+		var second []int
+		for k, resp := range Item(t.Context(), seqOf(ints(100)...), fn, opts...) {
+			if resp.Err != nil {
+				t.Fatalf("TestWithCheckpoint(%s): got err == %s, want nil", test.name, resp.Err)
+			}
+			second = append(second, k)
+		}
+		slices.Sort(second)
+		want := ints(100)[pos:]
+		if diff := pretty.Compare(want, second); diff != "" {
+			t.Errorf("TestWithCheckpoint(%s): second run: -want/+got:\n%s", test.name, diff)
+		}
+		if cp.n != 100 {
+			t.Errorf("TestWithCheckpoint(%s): got position %d after the second run, want 100", test.name, cp.n)
+		}
+	}
+}
+
+func TestWithCheckpointLoadErr(t *testing.T) {
+	t.Parallel()
+
+	broken := errors.New("broken")
+	cp := &memCheckpointer{loadErr: broken}
+	fn := func(ctx context.Context, _ int, v int) (int, error) {
+		t.Errorf("TestWithCheckpointLoadErr: ItemFunc ran after a Load error")
+		return v, nil
+	}
+
+	n := 0
+	for _, resp := range Item(t.Context(), seqOf(ints(5)...), fn, WithCheckpoint(cp, 0)) {
+		n++
+		if !errors.Is(resp.Err, broken) {
+			t.Errorf("TestWithCheckpointLoadErr: got err == %v, want it to wrap the Load error", resp.Err)
+		}
+	}
+	if n != 1 {
+		t.Errorf("TestWithCheckpointLoadErr: got %d Responses, want 1", n)
+	}
+}
@@ -0,0 +1,214 @@
+package foreach
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+	"os"
+	"time"
+
+	"github.com/gostdlib/base/concurrency/sync"
+	"github.com/gostdlib/base/context"
+	"github.com/gostdlib/base/retry/exponential"
+
+	"github.com/go-json-experiment/json"
+)
+
+// DeadLetter is a pair that failed for good, handed to WithDeadLetter's sink so it can be inspected and
+// replayed.
+type DeadLetter[K, V any] struct {
+	// Key and Value are the input pair.
+	Key   K
+	Value V
+	// Err is the error of the pair's Response.
+	Err error
+	// Record is the last attempt's retry record: its Attempt is how many times the ItemFunc was called and its
+	// Err is the last call's error. Without WithGate, Attempt is 1.
+	Record exponential.Record
+}
+
+// deadLetterJSON is the JSON form of a DeadLetter. Errors are kept as their text and intervals as nanoseconds.
+type deadLetterJSON[K, V any] struct {
+	Key           K             `json:"key"`
+	Value         V             `json:"value"`
+	Err           string        `json:"err"`
+	Attempt       int           `json:"attempt"`
+	LastInterval  time.Duration `json:"lastInterval,omitzero,format:nano"`
+	TotalInterval time.Duration `json:"totalInterval,omitzero,format:nano"`
+	LastErr       string        `json:"lastErr,omitempty"`
+}
+
+// MarshalJSON implements json.Marshaler. Err and Record.Err are written as their text, so K and V must
+// marshal to JSON themselves.
+func (d DeadLetter[K, V]) MarshalJSON() ([]byte, error) {
+	j := deadLetterJSON[K, V]{
+		Key:           d.Key,
+		Value:         d.Value,
+		Attempt:       d.Record.Attempt,
+		LastInterval:  d.Record.LastInterval,
+		TotalInterval: d.Record.TotalInterval,
+	}
+	if d.Err != nil {
+		j.Err = d.Err.Error()
+	}
+	if d.Record.Err != nil {
+		j.LastErr = d.Record.Err.Error()
+	}
+	return json.Marshal(j)
+}
+
+// UnmarshalJSON implements json.Unmarshaler. The errors come back as errors.New of their text: errors.Is and
+// errors.As no longer match what the ItemFunc returned.
+func (d *DeadLetter[K, V]) UnmarshalJSON(b []byte) error {
+	var j deadLetterJSON[K, V]
+	if err := json.Unmarshal(b, &j); err != nil {
+		return err
+	}
+	*d = DeadLetter[K, V]{
+		Key:   j.Key,
+		Value: j.Value,
+		Record: exponential.Record{
+			Attempt:       j.Attempt,
+			LastInterval:  j.LastInterval,
+			TotalInterval: j.TotalInterval,
+		},
+	}
+	if j.Err != "" {
+		d.Err = errors.New(j.Err)
+	}
+	if j.LastErr != "" {
+		d.Record.Err = errors.New(j.LastErr)
+	}
+	return nil
+}
+
+// DeadLetterSink receives WithDeadLetter's dead letters. DeadLetter is called from the worker that ran the
+// pair, concurrently with other workers, and holds that worker until it returns: a slow sink slows the range
+// rather than losing pairs. An error it returns is joined to the pair's Response error.
+type DeadLetterSink[K, V any] interface {
+	DeadLetter(ctx context.Context, dl DeadLetter[K, V]) error
+}
+
+// DeadLetterFunc adapts a function to a DeadLetterSink. It must be safe for concurrent use.
+type DeadLetterFunc[K, V any] func(ctx context.Context, dl DeadLetter[K, V]) error
+
+// DeadLetter implements DeadLetterSink.
+func (f DeadLetterFunc[K, V]) DeadLetter(ctx context.Context, dl DeadLetter[K, V]) error {
+	return f(ctx, dl)
+}
+
+// DeadLetterChan returns a DeadLetterSink that sends every dead letter on ch, blocking until it is received or
+// ctx ends. ch is not closed; close it after the range returns.
+func DeadLetterChan[K, V any](ch chan<- DeadLetter[K, V]) DeadLetterSink[K, V] {
+	if ch == nil {
+		panic("foreach.DeadLetterChan: ch cannot be nil")
+	}
+	return DeadLetterFunc[K, V](func(ctx context.Context, dl DeadLetter[K, V]) error {
+		select {
+		case ch <- dl:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	})
+}
+
+// DeadLetterFile is a DeadLetterSink that appends every dead letter to a file as a line of JSON. Read the file
+// back with ReadDeadLetters. It is safe for concurrent use, so one file can take the dead letters of several
+// Item calls.
+type DeadLetterFile[K, V any] struct {
+	mu sync.Mutex
+	f  *os.File
+}
+
+// NewDeadLetterFile opens the file at path for DeadLetterFile, creating it if needed and appending if it
+// exists. Close it after the ranges using it have returned.
+func NewDeadLetterFile[K, V any](path string) (*DeadLetterFile[K, V], error) {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("foreach.NewDeadLetterFile: %w", err)
+	}
+	return &DeadLetterFile[K, V]{f: f}, nil
+}
+
+// DeadLetter implements DeadLetterSink. Each dead letter is a single write, so a crash loses at most the line
+// being written.
+func (d *DeadLetterFile[K, V]) DeadLetter(ctx context.Context, dl DeadLetter[K, V]) error {
+	b, err := json.Marshal(dl)
+	if err != nil {
+		return fmt.Errorf("foreach.DeadLetterFile: %w", err)
+	}
+	b = append(b, '\n')
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if _, err := d.f.Write(b); err != nil {
+		return fmt.Errorf("foreach.DeadLetterFile: %w", err)
+	}
+	return nil
+}
+
+// Close syncs and closes the file.
+func (d *DeadLetterFile[K, V]) Close() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return errors.Join(d.f.Sync(), d.f.Close())
+}
+
+// ReadDeadLetters reads the dead letters DeadLetterFile wrote to r, one per line. A line that does not decode
+// yields its error and the read goes on; an error reading r yields it and ends the sequence. Replay the pairs
+// by feeding their Key and Value to Item again.
+func ReadDeadLetters[K, V any](r io.Reader) iter.Seq2[DeadLetter[K, V], error] {
+	return func(yield func(DeadLetter[K, V], error) bool) {
+		s := bufio.NewScanner(r)
+		s.Buffer(nil, 16<<20)
+		line := 0
+		for s.Scan() {
+			line++
+			if len(s.Bytes()) == 0 {
+				continue
+			}
+			var dl DeadLetter[K, V]
+			if err := json.Unmarshal(s.Bytes(), &dl); err != nil {
+				if !yield(dl, fmt.Errorf("foreach.ReadDeadLetters: line %d: %w", line, err)) {
+					return
+				}
+				continue
+			}
+			if !yield(dl, nil) {
+				return
+			}
+		}
+		if err := s.Err(); err != nil {
+			yield(DeadLetter[K, V]{}, fmt.Errorf("foreach.ReadDeadLetters: %w", err))
+		}
+	}
+}
+
+// WithDeadLetter hands every pair that fails for good to sink: with WithGate, a pair whose retries are
+// exhausted or whose error is permanent; without it, every pair whose ItemFunc returns an error. The pair's
+// Response still carries the error. A pair cut short because the range was cancelled (by ctx, an early break
+// or WithStopOnErr) did not fail and is not dead-lettered. sink's K and V must be Item's, or Item yields a
+// single error Response; a nil sink is an error.
+func WithDeadLetter[K, V any](sink DeadLetterSink[K, V]) Option {
+	return func(o options) (options, error) {
+		if sink == nil {
+			return o, fmt.Errorf("foreach.WithDeadLetter: sink cannot be nil: %w", ErrPermanent)
+		}
+		o.deadLetter = sink
+		return o, nil
+	}
+}
+
+// deadLetter hands a failed pair to WithDeadLetter's sink and returns err with any sink error joined to it.
+func (d *dispatcher[K, V, R]) deadLetter(ctx context.Context, k K, v V, rec exponential.Record, err error) error {
+	sink := d.o.deadLetter.(DeadLetterSink[K, V])
+	if serr := sink.DeadLetter(ctx, DeadLetter[K, V]{Key: k, Value: v, Err: err, Record: rec}); serr != nil {
+		return errors.Join(err, fmt.Errorf("foreach: dead letter not recorded: %w", serr))
+	}
+	return err
+}
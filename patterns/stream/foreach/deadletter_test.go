@@ -0,0 +1,258 @@
+package foreach
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gostdlib/base/context"
+	"github.com/gostdlib/base/retry/exponential"
+	"github.com/kylelemons/godebug/pretty"
+)
+
+// letter is the comparable part of a DeadLetter.
+type letter struct {
+	Key, Value int
+	Err        string
+	Attempt    int
+}
+
+func letterOf(dl DeadLetter[int, int]) letter {
+	return letter{Key: dl.Key, Value: dl.Value, Err: dl.Err.Error(), Attempt: dl.Record.Attempt}
+}
+
+func TestWithDeadLetter(t *testing.T) {
+	t.Parallel()
+
+	policy := exponential.Policy{
+		InitialInterval:     time.Millisecond,
+		Multiplier:          2,
+		RandomizationFactor: 0.5,
+		MaxInterval:         10 * time.Millisecond,
+		MaxAttempts:         3,
+	}
+	threeAttempts := exponential.Must(exponential.New(exponential.WithTesting(), exponential.WithPolicy(policy)))
+
+	tests := []struct {
+		name string
+		opts []Option
+		// fn's error for a value; nil succeeds.
+		errFor func(v int) error
+		want   []letter
+	}{
+		{
+			name:   "Success: nothing fails",
+			errFor: func(int) error { return nil },
+		},
+		{
+			name: "Error: without WithGate every failed pair is a dead letter after one attempt",
+			errFor: func(v int) error {
+				if v%2 == 1 {
+					return fmt.Errorf("odd %d", v)
+				}
+				return nil
+			},
+			want: []letter{
+				{Key: 1, Value: 1, Err: "odd 1", Attempt: 1},
+				{Key: 3, Value: 3, Err: "odd 3", Attempt: 1},
+			},
+		},
+		{
+			name: "Error: with WithGate a pair is a dead letter once its retries are exhausted",
+			opts: []Option{WithGate(threeAttempts)},
+			errFor: func(v int) error {
+				if v == 2 {
+					return errors.New("unavailable")
+				}
+				return nil
+			},
+			want: []letter{
+				{Key: 2, Value: 2, Err: "exceeded max attempts: unavailable: permanent error", Attempt: 3},
+			},
+		},
+		{
+			name: "Error: with WithGate a permanent error is a dead letter at once",
+			opts: []Option{WithGate(threeAttempts)},
+			errFor: func(v int) error {
+				if v == 0 {
+					return fmt.Errorf("bad: %w", ErrPermanent)
+				}
+				return nil
+			},
+			want: []letter{
+				{Key: 0, Value: 0, Err: "bad: permanent error", Attempt: 1},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		ch := make(chan DeadLetter[int, int], 10)
+		fn := func(ctx context.Context, _ int, v int) (int, error) {
+			return v, test.errFor(v)
+		}
+
+		errs := 0
+		opts := append([]Option{WithDeadLetter(DeadLetterChan(ch))}, test.opts...)
+		for _, resp := range Item(t.Context(), seqOf(ints(5)...), fn, opts...) {
+			if resp.Err != nil {
+				errs++
+			}
+		}
+		close(ch)
+
+		var got []letter
+		for dl := range ch {
+			got = append(got, letterOf(dl))
+		}
+		slices.SortFunc(got, func(a, b letter) int { return a.Key - b.Key })
+		if diff := pretty.Compare(test.want, got); diff != "" {
+			t.Errorf("TestWithDeadLetter(%s): -want/+got:\n%s", test.name, diff)
+		}
+		if errs != len(test.want) {
+			t.Errorf("TestWithDeadLetter(%s): got %d error Responses, want %d", test.name, errs, len(test.want))
+		}
+	}
+}
+
+// TestWithDeadLetterCancelled verifies pairs cut short by WithStopOnErr are not dead letters: only the pair
+// that failed is.
+func TestWithDeadLetterCancelled(t *testing.T) {
+	t.Parallel()
+
+	var got []letter
+	sink := DeadLetterFunc[int, int](func(ctx context.Context, dl DeadLetter[int, int]) error {
+		got = append(got, letterOf(dl))
+		return nil
+	})
+	started := make(chan struct{}, 4)
+	fn := func(ctx context.Context, _ int, v int) (int, error) {
+		if v == 0 {
+			// Fail only once the others are running, so they are cut short rather than never dispatched.
+			for range 4 {
+				<-started
+			}
+			return 0, errors.New("boom")
+		}
+		started <- struct{}{}
+		<-ctx.Done()
+		return 0, ctx.Err()
+	}
+
+	for range Item(t.Context(), seqOf(ints(5)...), fn, WithStopOnErr(), WithDeadLetter(sink)) {
+	}
+
+	want := []letter{{Key: 0, Value: 0, Err: "boom", Attempt: 1}}
+	if diff := pretty.Compare(want, got); diff != "" {
+		t.Errorf("TestWithDeadLetterCancelled: -want/+got:\n%s", diff)
+	}
+}
+
+// TestWithDeadLetterSinkErr verifies a sink's error is joined to the pair's Response error.
+func TestWithDeadLetterSinkErr(t *testing.T) {
+	t.Parallel()
+
+	full := errors.New("disk full")
+	boom := errors.New("boom")
+	sink := DeadLetterFunc[int, int](func(ctx context.Context, dl DeadLetter[int, int]) error {
+		return full
+	})
+	fn := func(ctx context.Context, _ int, v int) (int, error) {
+		return 0, boom
+	}
+
+	for _, resp := range Item(t.Context(), seqOf(1), fn, WithDeadLetter(sink)) {
+		if !errors.Is(resp.Err, boom) || !errors.Is(resp.Err, full) {
+			t.Errorf("TestWithDeadLetterSinkErr: got err == %v, want it to wrap the ItemFunc's and the sink's errors", resp.Err)
+		}
+	}
+}
+
+func TestDeadLetterFile(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "dead.jsonl")
+	f, err := NewDeadLetterFile[int, int](path)
+	if err != nil {
+		t.Fatalf("TestDeadLetterFile: NewDeadLetterFile: %s", err)
+	}
+	fn := func(ctx context.Context, _ int, v int) (int, error) {
+		if v >= 3 {
+			return 0, fmt.Errorf("too big: %d", v)
+		}
+		return v, nil
+	}
+	for range Item(t.Context(), seqOf(ints(5)...), fn, WithDeadLetter(f)) {
+	}
+	if err := f.Close(); err != nil {
+		t.Fatalf("TestDeadLetterFile: Close: %s", err)
+	}
+
+	// A line that does not decode is reported and skipped.
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("TestDeadLetterFile: ReadFile: %s", err)
+	}
+	r := strings.NewReader(string(b) + "not json\n")
+
+	var (
+		got  []letter
+		errs int
+	)
+	for dl, err := range ReadDeadLetters[int, int](r) {
+		if err != nil {
+			errs++
+			continue
+		}
+		got = append(got, letterOf(dl))
+	}
+	slices.SortFunc(got, func(a, b letter) int { return a.Key - b.Key })
+
+	want := []letter{
+		{Key: 3, Value: 3, Err: "too big: 3", Attempt: 1},
+		{Key: 4, Value: 4, Err: "too big: 4", Attempt: 1},
+	}
+	if diff := pretty.Compare(want, got); diff != "" {
+		t.Errorf("TestDeadLetterFile: -want/+got:\n%s", diff)
+	}
+	if errs != 1 {
+		t.Errorf("TestDeadLetterFile: got %d read errors, want 1", errs)
+	}
+}
+
+// TestDeadLetterJSON verifies a DeadLetter keeps its retry record through JSON, with the intervals as nanoseconds.
+func TestDeadLetterJSON(t *testing.T) {
+	t.Parallel()
+
+	dl := DeadLetter[int, int]{
+		Key:   1,
+		Value: 2,
+		Err:   errors.New("boom"),
+		Record: exponential.Record{
+			Attempt:       3,
+			LastInterval:  time.Second,
+			TotalInterval: 3 * time.Second,
+			Err:           errors.New("last"),
+		},
+	}
+	b, err := dl.MarshalJSON()
+	if err != nil {
+		t.Fatalf("TestDeadLetterJSON: MarshalJSON: %s", err)
+	}
+	want := `{"key":1,"value":2,"err":"boom","attempt":3,"lastInterval":1000000000,"totalInterval":3000000000,"lastErr":"last"}`
+	if string(b) != want {
+		t.Errorf("TestDeadLetterJSON: got %s, want %s", b, want)
+	}
+
+	var got DeadLetter[int, int]
+	if err := got.UnmarshalJSON(b); err != nil {
+		t.Fatalf("TestDeadLetterJSON: UnmarshalJSON: %s", err)
+	}
+	if diff := pretty.Compare(dl, got); diff != "" {
+		t.Errorf("TestDeadLetterJSON: -want/+got:\n%s", diff)
+	}
+}
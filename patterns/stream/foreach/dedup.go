@@ -0,0 +1,172 @@
+package foreach
+
+import (
+	"container/list"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gostdlib/base/concurrency/sync"
+	"github.com/gostdlib/base/context"
+	"github.com/gostdlib/base/retry/exponential"
+)
+
+// WithDedup collapses pairs with the same dedup key, as returned by key, into one ItemFunc call: a pair whose
+// key matches a pair whose ItemFunc is still running waits for that call and takes its value and error, rather
+// than calling the ItemFunc again. Every pair still yields its own Response under its own input key. Only
+// pairs in flight together are collapsed; add WithDedupCache to also reuse the results of calls that finished.
+//
+// The waiting pair holds a worker while it waits, and shares the value with the pair that ran: an R holding a
+// pointer, slice or map is shared by every Response it is collapsed into. key is called once per pair, on the
+// worker. key's K and V must be Item's, or Item yields a single error Response; a nil key is an error.
+func WithDedup[K, V any, D comparable](key func(k K, v V) D) Option {
+	return func(o options) (options, error) {
+		if key == nil {
+			return o, fmt.Errorf("foreach.WithDedup: key cannot be nil: %w", ErrPermanent)
+		}
+		o.dedup = func(k K, v V) any { return key(k, v) }
+		return o, nil
+	}
+}
+
+// WithDedupCache keeps WithDedup's results for ttl after the call that made them finishes, so a later pair with
+// the same dedup key takes the result without calling the ItemFunc. At most size results are kept, the least
+// recently used going first. Only successes are kept: a pair whose ItemFunc failed is tried again by the next
+// pair with its key. The cache belongs to one Item range. It requires WithDedup; size and ttl must be > 0.
+func WithDedupCache(size int, ttl time.Duration) Option {
+	return func(o options) (options, error) {
+		if size < 1 {
+			return o, fmt.Errorf("foreach.WithDedupCache: size must be > 0, got %d: %w", size, ErrPermanent)
+		}
+		if ttl <= 0 {
+			return o, fmt.Errorf("foreach.WithDedupCache: ttl must be > 0, got %s: %w", ttl, ErrPermanent)
+		}
+		o.cacheSize = size
+		o.cacheTTL = ttl
+		return o, nil
+	}
+}
+
+// flight is one ItemFunc call that pairs with the same dedup key share. done closes once r, rec and err are set.
+type flight struct {
+	done chan struct{}
+	r    any
+	rec  exponential.Record
+	err  error
+}
+
+// cached is a finished flight kept by WithDedupCache.
+type cached struct {
+	key     any
+	f       *flight
+	expires time.Time
+}
+
+// flights tracks WithDedup's calls in flight and, with WithDedupCache, the results of finished ones. Each Item
+// range gets its own flights. All methods are safe for concurrent use.
+type flights struct {
+	mu    sync.Mutex
+	calls map[any]*flight
+
+	// size and ttl bound the cache; size is 0 without WithDedupCache. lru holds *cached entries, most recently
+	// used at the front, and cache indexes them by key.
+	size  int
+	ttl   time.Duration
+	lru   *list.List
+	cache map[any]*list.Element
+
+	// now is time.Now, replaced in tests.
+	now func() time.Time
+}
+
+// newFlights returns the flights for a range, with a cache of size results kept for ttl if size > 0.
+func newFlights(size int, ttl time.Duration) *flights {
+	return &flights{
+		calls: map[any]*flight{},
+		size:  size,
+		ttl:   ttl,
+		lru:   list.New(),
+		cache: map[any]*list.Element{},
+		now:   time.Now,
+	}
+}
+
+// join returns the flight for key. If lead is true the caller must make the call and report it with finish;
+// otherwise the flight is another pair's call, or a cached result, to wait on.
+func (f *flights) join(key any) (fl *flight, lead bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if e, ok := f.cache[key]; ok {
+		c := e.Value.(*cached)
+		if f.now().Before(c.expires) {
+			f.lru.MoveToFront(e)
+			return c.f, false
+		}
+		f.lru.Remove(e)
+		delete(f.cache, key)
+	}
+	if fl, ok := f.calls[key]; ok {
+		return fl, false
+	}
+	fl = &flight{done: make(chan struct{})}
+	f.calls[key] = fl
+	return fl, true
+}
+
+// finish records the result of the call the leader of fl made, releases the pairs waiting on it and, with a
+// cache, keeps a success.
+func (f *flights) finish(key any, fl *flight, r any, rec exponential.Record, err error) {
+	fl.r, fl.rec, fl.err = r, rec, err
+	close(fl.done)
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	delete(f.calls, key)
+	if f.size == 0 || err != nil {
+		return
+	}
+	if e, ok := f.cache[key]; ok {
+		f.lru.Remove(e)
+	}
+	f.cache[key] = f.lru.PushFront(&cached{key: key, f: fl, expires: f.now().Add(f.ttl)})
+	for f.lru.Len() > f.size {
+		e := f.lru.Back()
+		f.lru.Remove(e)
+		delete(f.cache, e.Value.(*cached).key)
+	}
+}
+
+// dedupCall is spanCall under WithDedup: the first pair with a dedup key makes the call, and pairs with the same
+// key that arrive while it runs, or while its result is cached, take its result instead.
+func (d *dispatcher[K, V, R]) dedupCall(ctx context.Context, k K, v V) (R, exponential.Record, error) {
+	if d.o.flights == nil {
+		return d.spanCall(ctx, k, v)
+	}
+
+	key := d.o.dedup.(func(K, V) any)(k, v)
+	fl, lead := d.o.flights.join(key)
+	if !lead {
+		select {
+		case <-fl.done:
+		case <-ctx.Done():
+			var zero R
+			return zero, exponential.Record{}, ctx.Err()
+		}
+		r, _ := fl.r.(R)
+		return r, fl.rec, fl.err
+	}
+
+	// A panicking ItemFunc still finishes the flight, so the pairs waiting on it are not stranded.
+	finished := false
+	defer func() {
+		if !finished {
+			d.o.flights.finish(key, fl, nil, exponential.Record{}, errors.New("foreach: ItemFunc panicked"))
+		}
+	}()
+	r, rec, err := d.spanCall(ctx, k, v)
+	finished = true
+	d.o.flights.finish(key, fl, r, rec, err)
+	return r, rec, err
+}
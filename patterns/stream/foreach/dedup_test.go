@@ -0,0 +1,139 @@
+package foreach
+
+import (
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gostdlib/base/context"
+	"github.com/gostdlib/base/retry/exponential"
+)
+
+func TestFlights(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	f := newFlights(2, time.Minute)
+	f.now = func() time.Time { return now }
+
+	// join reports whether the caller leads; finish completes a led flight with err.
+	join := func(key string) bool {
+		_, lead := f.join(key)
+		return lead
+	}
+	run := func(key string, err error) {
+		fl, lead := f.join(key)
+		if !lead {
+			t.Fatalf("TestFlights: got a follower for %q, want to lead", key)
+		}
+		f.finish(key, fl, key, exponential.Record{Attempt: 1}, err)
+	}
+
+	fl, lead := f.join("a")
+	if !lead {
+		t.Fatalf("TestFlights: got a follower for the first join, want to lead")
+	}
+	if join("a") {
+		t.Errorf("TestFlights: got to lead a key already in flight, want to follow")
+	}
+	f.finish("a", fl, 1, exponential.Record{Attempt: 1}, nil)
+	select {
+	case <-fl.done:
+	default:
+		t.Fatalf("TestFlights: the flight is not done after finish")
+	}
+
+	steps := []struct {
+		name     string
+		do       func()
+		key      string
+		wantLead bool
+	}{
+		{name: "a success is cached", key: "a", wantLead: false},
+		{name: "an error is not cached", do: func() { run("b", errors.New("boom")) }, key: "b", wantLead: true},
+		{name: "the least recently used is evicted", do: func() { run("c", nil); run("d", nil) }, key: "a", wantLead: true},
+		{name: "a cached result expires", do: func() { now = now.Add(time.Minute) }, key: "c", wantLead: true},
+	}
+	for _, s := range steps {
+		if s.do != nil {
+			s.do()
+		}
+		if got := join(s.key); got != s.wantLead {
+			t.Errorf("TestFlights(%s): got lead == %t, want %t", s.name, got, s.wantLead)
+		}
+	}
+}
+
+// TestWithDedup verifies pairs with the same dedup key share one ItemFunc call while it runs, or while its
+// result is cached, and still each yield a Response under their own key.
+func TestWithDedup(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		opts []Option
+	}{
+		{name: "Success: pairs in flight together share a call"},
+		{name: "Success: with WithDedupCache", opts: []Option{WithDedupCache(10, time.Hour)}},
+	}
+
+	for _, test := range tests {
+		ctx := context.SetPool(t.Context(), context.Pool(t.Context()).Limited(t.Context(), "TestWithDedup", 32))
+
+		var keyCalls, calls atomic.Int64
+		key := func(_ int, v int) int {
+			keyCalls.Add(1)
+			return v % 4
+		}
+		release := make(chan struct{})
+		fn := func(ctx context.Context, _ int, v int) (int, error) {
+			calls.Add(1)
+			<-release
+			return v % 4, nil
+		}
+		go func() {
+			// Hold the calls until every pair has joined one.
+			for keyCalls.Load() < 20 {
+				time.Sleep(time.Millisecond)
+			}
+			time.Sleep(50 * time.Millisecond)
+			close(release)
+		}()
+
+		seen := map[int]bool{}
+		opts := append([]Option{WithDedup(key)}, test.opts...)
+		for k, resp := range Item(ctx, seqOf(ints(20)...), fn, opts...) {
+			if resp.Err != nil || resp.V != k%4 {
+				t.Errorf("TestWithDedup(%s): got (%d, %v) for key %d, want (%d, nil)", test.name, resp.V, resp.Err, k, k%4)
+			}
+			seen[k] = true
+		}
+		if len(seen) != 20 {
+			t.Errorf("TestWithDedup(%s): got Responses for %d keys, want 20", test.name, len(seen))
+		}
+		if got := calls.Load(); got != 4 {
+			t.Errorf("TestWithDedup(%s): got %d ItemFunc calls, want 4", test.name, got)
+		}
+	}
+}
+
+// TestWithDedupCache verifies a cached result is reused by pairs that come after the call finished.
+func TestWithDedupCache(t *testing.T) {
+	t.Parallel()
+
+	// A single worker runs the pairs one after another, so none are in flight together.
+	ctx := context.SetPool(t.Context(), context.Pool(t.Context()).Limited(t.Context(), "TestWithDedupCache", 1))
+	var calls atomic.Int64
+	fn := func(ctx context.Context, _ int, v int) (int, error) {
+		calls.Add(1)
+		return v % 3, nil
+	}
+	key := func(_ int, v int) int { return v % 3 }
+
+	for range Item(ctx, seqOf(ints(30)...), fn, WithDedup(key), WithDedupCache(3, time.Hour)) {
+	}
+	if got := calls.Load(); got != 3 {
+		t.Errorf("TestWithDedupCache: got %d ItemFunc calls, want 3", got)
+	}
+}
@@ -2,7 +2,9 @@ package foreach
 
 import (
 	"errors"
+	"fmt"
 	"iter"
+	"time"
 
 	"github.com/gostdlib/base/concurrency/sync"
 	"github.com/gostdlib/base/concurrency/worker"
@@ -10,6 +12,8 @@ import (
 	"github.com/gostdlib/base/retry/exponential"
 	"github.com/gostdlib/base/telemetry/otel/trace/span"
 	"github.com/gostdlib/concurrency/patterns/stream"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
 )
 
 // dispatcher runs the fan-out side of Item: a puller goroutine ranges seq and hands pairs over, a
@@ -26,7 +30,7 @@ type dispatcher[K, V, R any] struct {
 	cancel func()
 	// deliver hands off the i'th dispatched pair's response; it may block until the consumer takes it
 	// or abandons the range.
-	deliver func(i int, k K, resp stream.Result[R])
+	deliver func(i int, kv keyed[K, R])
 	// finish signals that no more responses will be delivered.
 	finish func()
 	// pull hands pairs from the puller to the dispatch loop.
@@ -37,15 +41,16 @@ type dispatcher[K, V, R any] struct {
 	workers *worker.Pool
 }
 
-// launch starts Item's fan-out and returns at once: it submits the puller (pullSeq) and the dispatch
-// loop (run) to the default pool rather than the possibly-Limited ItemFunc pool, so a coordinator never
-// occupies a worker slot. It runs once per range, on the range goroutine; the caller then ranges the
-// delivery side and joins on d.done.
+// launch starts Item's fan-out and returns at once: it submits the puller (pullSeq), under WithPriority
+// the prioritizer (prioritize), and the dispatch loop (run) to the default pool rather than the
+// possibly-Limited ItemFunc pool, so a coordinator never occupies a worker slot. It runs once per
+// range, on the range goroutine; the caller then ranges the delivery side and joins on d.done.
 //
-// run owns teardown on every path — pullSeq closes d.pull, and run calls d.finish then closes d.done —
-// which holds because neither submit can be declined. A coordinator that failed to start would be the
-// silent-empty-range hazard: with nothing delivered and d.done closed, a dispatch that did start would
-// drain the (closed) pull and finish with zero responses, a run indistinguishable from empty input.
+// run owns teardown on every path — pullSeq (or prioritize) closes d.pull, and run calls d.finish
+// then closes d.done — which holds because no submit can be declined. A coordinator that failed to
+// start would be the silent-empty-range hazard: with nothing delivered and d.done closed, a dispatch
+// that did start would drain the (closed) pull and finish with zero responses, a run
+// indistinguishable from empty input.
 // Two properties remove that failure mode rather than reporting it. The default pool is never Limited,
 // so there is no slot to wait for, and the WithoutCancel submit Context is never cancelled, so the pool
 // never declines the job. The coordinators still honor cancellation through the captured ctx: an
@@ -56,7 +61,13 @@ func (d *dispatcher[K, V, R]) launch(ctx context.Context) {
 	// ItemFunc pool's own handle to the pool the coordinators must not take slots from.
 	p := d.workers.Default()
 	submitCtx := context.WithoutCancel(ctx)
-	_ = p.Submit(submitCtx, func() { d.pullSeq(ctx) })
+	if d.o.priority == nil {
+		_ = p.Submit(submitCtx, func() { d.pullSeq(ctx, d.pull) })
+	} else {
+		raw := make(chan input[K, V])
+		_ = p.Submit(submitCtx, func() { d.pullSeq(ctx, raw) })
+		_ = p.Submit(submitCtx, func() { d.prioritize(ctx, raw) })
+	}
 	_ = p.Submit(submitCtx, func() { d.run(ctx) })
 }
 
@@ -66,46 +77,111 @@ func (d *dispatcher[K, V, R]) launch(ctx context.Context) {
 // dispatch paused. A healthy pair's single attempt never touches the gate, an error wrapping
 // ErrPermanent never closes it, and the backoff's own permanence classification (ErrTransformer,
 // attempt limits) ends the retry without another invocation. The gate reopens on every exit, panics
-// included.
-func (d *dispatcher[K, V, R]) call(ctx context.Context, k K, v V) (R, error) {
+// included. It returns the last attempt's Record, with the attempt's error, for WithDeadLetter.
+func (d *dispatcher[K, V, R]) call(ctx context.Context, k K, v V) (R, exponential.Record, error) {
 	if d.o.gate == nil {
-		return d.fn(ctx, k, v)
+		r, err := d.attempt(ctx, k, v)
+		return r, exponential.Record{Attempt: 1, Err: err}, err
 	}
-	var r R
+	var (
+		r   R
+		rec exponential.Record
+	)
 	closed := false
 	defer func() {
 		if closed {
 			d.o.gate.resume()
 		}
 	}()
-	err := d.o.boff.Retry(ctx, func(ctx context.Context, _ exponential.Record) error {
+	err := d.o.boff.Retry(ctx, func(ctx context.Context, record exponential.Record) error {
 		var rerr error
-		r, rerr = d.fn(ctx, k, v)
+		r, rerr = d.attempt(ctx, k, v)
+		rec = record
+		rec.Err = rerr
 		if rerr != nil && !closed && !errors.Is(rerr, ErrPermanent) {
 			d.o.gate.pause()
 			closed = true
 		}
 		return rerr
 	})
+	return r, rec, err
+}
+
+// spanCall is call, in a child span of its own tagged with the pair's key under WithItemSpans. The span is
+// only made when ctx's span is recording.
+func (d *dispatcher[K, V, R]) spanCall(ctx context.Context, k K, v V) (R, exponential.Record, error) {
+	if !d.o.spans || !span.Get(ctx).IsRecording() {
+		return d.call(ctx, k, v)
+	}
+	ctx, sp := context.NewSpan(ctx, span.WithName(or(d.o.name, "foreach.Item")))
+	defer sp.End()
+	sp.Span.SetAttributes(attribute.String("key", fmt.Sprint(k)))
+
+	r, rec, err := d.call(ctx, k, v)
+	if err != nil {
+		sp.Status(codes.Error, err.Error())
+	}
+	return r, rec, err
+}
+
+// attempt makes one call to fn, through the circuit breaker when there is one: an open breaker fails the
+// attempt with ErrCircuitOpen, which wraps ErrPermanent, so under WithGate the retry ends there too.
+func (d *dispatcher[K, V, R]) attempt(ctx context.Context, k K, v V) (R, error) {
+	if d.o.breaker == nil {
+		return d.invoke(ctx, k, v)
+	}
+	done, err := d.o.breaker.allow()
+	if err != nil {
+		var zero R
+		return zero, err
+	}
+	// A panicking fn still reports, as a failure, so a half-open breaker does not wait forever on its probe.
+	reported := false
+	defer func() {
+		if !reported {
+			done(errors.New("foreach: ItemFunc panicked"))
+		}
+	}()
+	r, err := d.invoke(ctx, k, v)
+	reported = true
+	done(err)
 	return r, err
 }
 
-// pullSeq ranges seq, handing each pair to the dispatch loop. It exists so the dispatch loop can
-// always be interrupted by ctx: a sequence blocked on an external source (say stream.Chan on an idle
-// channel) parks only this puller, keeping cancellation and the end-of-range join bounded. A parked
+// pullSeq ranges seq, handing each pair to the dispatch loop on out (through prioritize under
+// WithPriority). It exists so the dispatch loop can always be interrupted by ctx: a sequence blocked
+// on an external source (say stream.Chan on an idle channel) parks only this puller, keeping
+// cancellation and the end-of-range join bounded. A parked
 // puller unwinds when its source yields, closes or the Context the sequence captured ends; at most
 // one pulled pair is discarded when that happens after cancellation.
-func (d *dispatcher[K, V, R]) pullSeq(ctx context.Context) {
-	defer close(d.pull)
+func (d *dispatcher[K, V, R]) pullSeq(ctx context.Context, out chan<- input[K, V]) {
+	defer close(out)
+	i := 0
 	for k, v := range d.seq {
+		idx := i
+		i++
+		// WithCheckpoint's resumed pairs completed in an earlier run.
+		if idx < d.o.resume {
+			continue
+		}
+		in := input[K, V]{k: k, v: v, idx: idx}
+		if d.o.metrics != nil {
+			in.read = time.Now()
+		}
 		select {
-		case d.pull <- input[K, V]{k: k, v: v}:
+		case out <- in:
 		case <-ctx.Done():
 			return
 		}
 	}
 }
 
+// ledgerEntry is a dispatched pair's place in run's ledger: its input key and the WithWeight slots it holds.
+type ledgerEntry[K any] struct {
+	k     K
+	slots int
+}
+
 // run dispatches until the puller is exhausted, ctx is cancelled or the checkpoint fails, waits for
 // the in-flight work, then finishes delivery and closes done.
 //
@@ -126,10 +202,10 @@ func (d *dispatcher[K, V, R]) run(ctx context.Context) {
 	g := d.workers.Group()
 
 	// ledger tracks dispatched pairs whose fn has not yet delivered, keyed by dispatch index and
-	// holding the pair's input key for the sweep. One lock + insert per dispatch, one lock + delete per
+	// holding what the sweep needs of the pair. One lock + insert per dispatch, one lock + delete per
 	// delivery — trivial against the submit floor.
 	var mu sync.Mutex
-	ledger := map[int]K{}
+	ledger := map[int]ledgerEntry[K]{}
 
 	i := 0
 loop:
@@ -149,16 +225,64 @@ loop:
 			if !ok {
 				break loop
 			}
+			slots := 0
+			if d.o.weights != nil {
+				var err error
+				// A pair pulled but cancelled while waiting for its slots was never dispatched.
+				if slots, err = d.o.weights.acquire(ctx, d.o.weight.(func(K, V) int)(in.k, in.v)); err != nil {
+					break loop
+				}
+			}
+			// The Limiter's slot is taken last, right before dispatch: it may be shared, so it is held no
+			// longer than needed, and a Resize down that came while dispatch waited on the pull is honored.
+			if d.o.limiter != nil {
+				if err := d.o.limiter.acquire(ctx); err != nil {
+					if slots > 0 {
+						d.o.weights.release(slots)
+					}
+					break loop
+				}
+			}
 			insert := i
 			i++
 			mu.Lock()
-			ledger[insert] = in.k
+			ledger[insert] = ledgerEntry[K]{k: in.k, slots: slots}
 			mu.Unlock()
+			if d.o.adapt != nil {
+				d.o.adapt.start()
+			}
 			g.Go(ctx, func(ctx context.Context) error {
 				err := ctx.Err()
 				var r R
+				// done is whether the ItemFunc completed, rather than being cut short, for WithCheckpoint.
+				done := false
 				if err == nil {
-					r, err = d.call(ctx, in.k, in.v)
+					if d.o.metrics != nil {
+						d.o.metrics.QueueWait.Record(ctx, time.Since(in.read).Seconds())
+						d.o.metrics.InFlight.Add(ctx, 1)
+					}
+					start := time.Now()
+					var rec exponential.Record
+					r, rec, err = d.dedupCall(ctx, in.k, in.v)
+					latency := time.Since(start)
+					if d.o.adapt != nil {
+						d.o.adapt.done(latency, err)
+					}
+					if d.o.metrics != nil {
+						d.o.metrics.finished(ctx, latency, err)
+					}
+					// A pair that failed while the range was still live failed for good; one that
+					// failed because the range was cancelled was cut short and is not a dead letter.
+					done = err == nil || ctx.Err() == nil
+					if err != nil && d.o.deadLetter != nil && ctx.Err() == nil {
+						err = d.deadLetter(ctx, in.k, in.v, rec, err)
+					}
+				}
+				if slots > 0 {
+					d.o.weights.release(slots)
+				}
+				if d.o.limiter != nil {
+					d.o.limiter.done()
 				}
 				// With WithStopOnErr, cancel the moment the error is known — before delivery. In
 				// unordered mode deliver can block on the full out channel behind a slow consumer, and
@@ -174,7 +298,7 @@ loop:
 				mu.Lock()
 				delete(ledger, insert)
 				mu.Unlock()
-				d.deliver(insert, in.k, stream.Result[R]{V: r, Err: err})
+				d.deliver(insert, keyed[K, R]{k: in.k, resp: stream.Result[R]{V: r, Err: err}, idx: in.idx, done: done})
 				return err
 			})
 		}
@@ -195,8 +319,16 @@ loop:
 		cause = ctx.Err()
 	}
 	mu.Lock()
-	for insert, k := range ledger {
-		d.deliver(insert, k, stream.Result[R]{Err: cause})
+	for insert, e := range ledger {
+		// The pairs that never ran still hold their WithWeight slots and, in a Limiter that may be shared
+		// with other ranges, theirs: give them back.
+		if e.slots > 0 {
+			d.o.weights.release(e.slots)
+		}
+		if d.o.limiter != nil {
+			d.o.limiter.done()
+		}
+		d.deliver(insert, keyed[K, R]{k: e.k, resp: stream.Result[R]{Err: cause}})
 	}
 	clear(ledger)
 	mu.Unlock()
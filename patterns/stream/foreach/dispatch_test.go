@@ -42,7 +42,7 @@ func TestCall(t *testing.T) {
 	}
 
 	for _, test := range tests {
-		g := &gate{}
+		g := &Gate{}
 		calls := 0
 		d := &dispatcher[int, int, int]{o: options{boff: testBoff(), gate: g}}
 		d.fn = func(_ context.Context, _ int, v int) (int, error) {
@@ -62,7 +62,7 @@ func TestCall(t *testing.T) {
 			defer func() {
 				recover()
 			}()
-			_, err = d.call(t.Context(), 0, 21)
+			_, _, err = d.call(t.Context(), 0, 21)
 		}()
 
 		if !test.fnPanics {
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"sort"
 	"strconv"
+	"strings"
 	"sync/atomic"
 
 	"github.com/gostdlib/base/context"
@@ -112,3 +113,35 @@ func ExampleWithGate() {
 	// 3 130
 	// 4 140
 }
+
+// ExampleItems shows flat-map: each line expands into its words, and with WithOrdered the words stream out in
+// input order, each line's in the order it emitted them.
+func ExampleItems() {
+	ctx := context.Background()
+
+	lines := []string{"the quick", "brown fox", "", "jumps"}
+
+	fn := func(ctx context.Context, _ int, line string, emit func(string) error) error {
+		for _, w := range strings.Fields(line) {
+			if err := emit(w); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	for k, resp := range foreach.Items(ctx, stream.Slice(lines), fn, foreach.WithOrdered()) {
+		if resp.Err != nil {
+			fmt.Println(k, resp.Err)
+			continue
+		}
+		fmt.Println(k, resp.V)
+	}
+
+	// Output:
+	// 0 the
+	// 0 quick
+	// 1 brown
+	// 1 fox
+	// 3 jumps
+}
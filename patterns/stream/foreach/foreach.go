@@ -49,8 +49,53 @@ Failures can retry with backpressure. WithGate takes a base/retry/exponential Ba
 ItemFunc retries with it, and while any pair is retrying, dispatch of new pairs pauses — a dependency
 having a bad moment gets time to recover instead of more traffic. The first attempt of every pair runs
 ungated, an error wrapping ErrPermanent is never retried, and work already dispatched keeps running.
-The gate belongs to a single Item range; bound recovery with the Backoff's Policy or a ctx deadline.
-See the example on WithGate.
+The gate belongs to a single Item range unless shared with WithSharedGate; bound recovery with the
+Backoff's Policy or a ctx deadline. See the example on WithGate.
+
+The number of ItemFuncs in flight can also adapt. WithAdaptiveConcurrency(min, max) starts at min and grows
+while ItemFuncs succeed at the limit, and shrinks when they fail or slow down, so an ItemFunc calling a remote
+service runs as wide as the service can currently take.
+
+A long run can also be scaled by hand. WithLimiter caps the ItemFuncs in flight at a Limiter's limit, which
+Limiter.Set changes while the run goes on: raising it dispatches more at once, and lowering it lets the
+ItemFuncs already running finish rather than interrupting them.
+
+A dependency that stays down is better failed fast than retried. WithCircuitBreaker runs every ItemFunc through
+a CircuitBreaker that opens after enough failures: while it is open, pairs yield an ErrCircuitOpen Response
+without running, and after a cooldown a few probe pairs test whether the dependency has recovered. One
+CircuitBreaker can be shared by every Item call against the same dependency.
+
+A slow ItemFunc should not hold a worker for ever. WithItemTimeout gives each call its own deadline and yields a
+*TimeoutError for a call that misses it, and WithHedge starts a duplicate call for a pair that is taking too
+long and keeps whichever returns first.
+
+Pairs that fail for good need not be lost with their Response. WithDeadLetter hands each one, with its final
+error and retry Record, to a DeadLetterSink: DeadLetterChan, DeadLetterFunc, or a DeadLetterFile of JSON lines
+that ReadDeadLetters reads back for replay.
+
+Pairs are dispatched in input order unless told otherwise. WithPriority lets urgent pairs overtake the ones
+queued ahead of them, within a lookahead window read from the input while dispatch waits, and WithWeight makes
+heavy pairs take more than one of the pool's slots so a few huge pairs cannot crowd out the rest.
+
+Long runs can survive a crash. WithCheckpoint saves, to a Checkpointer such as a FileCheckpointer, the position
+below which every input pair has completed; a run started again over the same deterministic input skips the
+pairs below the saved position.
+
+Retrying runs can protect a dependency together. WithSharedGate puts several Item calls, and fanout runs,
+behind one Gate made with NewGate: a pair retrying in any of them pauses dispatch in all of them, and the
+Gate's Stats report how often and how long it has paused.
+
+Pass WithName to record OTEL metrics with the MeterProvider on the Context: ItemFuncs in flight, queue wait and
+latency histograms, errors by permanence, time spent behind the gate, responses held for ordering and
+WithAdaptiveConcurrency's limit. WithItemSpans runs each pair in a child span tagged with its key.
+
+An input that repeats keys, such as an event stream, need not repeat the work. WithDedup collapses pairs with
+the same dedup key that are in flight together into one ItemFunc call, and WithDedupCache keeps the results for
+a while; every pair still yields its own Response.
+
+Item is one in, one out. When one pair expands into a variable number of results, such as the pages of an API
+listing or the records of a file, use Items with an ItemsFunc that passes each result to an emit callback:
+results stream out as they are emitted or, with WithOrdered, in input order and then emit order.
 */
 package foreach
 
@@ -58,7 +103,9 @@ import (
 	"errors"
 	"fmt"
 	"iter"
+	"reflect"
 	"runtime"
+	"time"
 
 	"github.com/gostdlib/base/concurrency/worker"
 	"github.com/gostdlib/base/context"
@@ -85,7 +132,9 @@ type options struct {
 	boff *exponential.Backoff
 	// gate is internal wiring, not an option: Item installs it when boff is set, and dispatch pauses
 	// while any pair is retrying under it.
-	gate *gate
+	gate *Gate
+	// shared, when non-nil, is WithSharedGate's Gate, installed as gate instead of a range's own. Default nil.
+	shared *Gate
 	// ordered yields responses in input order through the order engine. Default false (completion
 	// order through the delivery buffer).
 	ordered bool
@@ -96,6 +145,57 @@ type options struct {
 	// orderWait is internal wiring, not an option: the ordered path installs it to block while the
 	// order engine holds too many undelivered responses.
 	orderWait func(ctx context.Context) error
+	// minConc and maxConc, when maxConc > 0, bound WithAdaptiveConcurrency's limit. Default 0 (the pool's
+	// worker count is the only limit).
+	minConc, maxConc int
+	// adapt is internal wiring, not an option: Item installs it when maxConc is set, and dispatch pauses
+	// while it has as many ItemFuncs in flight as its limit allows.
+	adapt *adaptive
+	// limiter, when non-nil, is WithLimiter's Limiter, and dispatch pauses while it has as many ItemFuncs in
+	// flight as its limit allows. Default nil. It may be shared with other Item calls.
+	limiter *Limiter
+	// breaker, when non-nil, is asked before every ItemFunc attempt and fails the pair with ErrCircuitOpen
+	// while open. Default nil. It may be shared with other Item calls.
+	breaker *CircuitBreaker
+	// timeout, when > 0, is the deadline of every ItemFunc call. Default 0 (no deadline but ctx's).
+	timeout time.Duration
+	// hedgeAfter and hedgeMax, when hedgeMax > 0, start up to hedgeMax duplicate calls of a slow ItemFunc,
+	// one every hedgeAfter. Default 0 (no duplicates).
+	hedgeAfter time.Duration
+	hedgeMax   int
+	// deadLetter, when non-nil, is WithDeadLetter's DeadLetterSink[K, V]. It is held as an any because options
+	// is not generic; Item checks its type. Default nil.
+	deadLetter any
+	// priority, when non-nil, is WithPriority's func(K, V) int, held as an any like deadLetter; lookahead is
+	// WithLookahead's window. Default nil and 0 (dispatch in input order).
+	priority  any
+	lookahead int
+	// weight, when non-nil, is WithWeight's func(K, V) int, held as an any like deadLetter. Default nil (every
+	// pair takes one slot).
+	weight any
+	// weights is internal wiring, not an option: Item installs it when weight is set, and dispatch waits on it
+	// for a pair's slots.
+	weights *weights
+	// checkpoint, when non-nil, is WithCheckpoint's Checkpointer, saved at most once per checkpointEvery.
+	// Default nil (no checkpoints).
+	checkpoint      Checkpointer
+	checkpointEvery time.Duration
+	// resume is internal wiring, not an option: the number of pairs at the front of the input the puller
+	// skips, from the position WithCheckpoint loaded.
+	resume int
+	// name, when set, is WithName's name, and metrics the instruments Item creates under it. Default "" (no
+	// metrics).
+	name    string
+	metrics *metrics
+	// spans is whether each pair's ItemFunc runs in its own child span. Default false.
+	spans bool
+	// dedup, when non-nil, is WithDedup's key as a func(K, V) any, held as an any like deadLetter. cacheSize and
+	// cacheTTL are WithDedupCache's bounds. Default nil and 0 (every pair runs, nothing is cached).
+	dedup     any
+	cacheSize int
+	cacheTTL  time.Duration
+	// flights is internal wiring, not an option: Item installs it when dedup is set.
+	flights *flights
 }
 
 // resolveOptions applies opts in order over the zero defaults.
@@ -111,10 +211,43 @@ func resolveOptions(opts []Option) (options, error) {
 	return o, nil
 }
 
+// checkOptions checks what resolveOptions cannot: that the options holding functions of K and V were given
+// Item's K and V, and that the options go together.
+func checkOptions[K, V any](o options) error {
+	if o.deadLetter != nil {
+		if _, ok := o.deadLetter.(DeadLetterSink[K, V]); !ok {
+			return fmt.Errorf("foreach.WithDeadLetter: sink is a %T, want a %v: %w", o.deadLetter, reflect.TypeFor[DeadLetterSink[K, V]](), ErrPermanent)
+		}
+	}
+	if o.priority != nil {
+		if _, ok := o.priority.(func(K, V) int); !ok {
+			return fmt.Errorf("foreach.WithPriority: fn is a %T, want a %v: %w", o.priority, reflect.TypeFor[func(K, V) int](), ErrPermanent)
+		}
+		if o.ordered {
+			return fmt.Errorf("foreach.WithPriority: cannot be used with WithOrdered: %w", ErrPermanent)
+		}
+	}
+	if o.dedup != nil {
+		if _, ok := o.dedup.(func(K, V) any); !ok {
+			return fmt.Errorf("foreach.WithDedup: key is not a function of %v and %v: %w", reflect.TypeFor[K](), reflect.TypeFor[V](), ErrPermanent)
+		}
+	}
+	if o.cacheSize > 0 && o.dedup == nil {
+		return fmt.Errorf("foreach.WithDedupCache: requires WithDedup: %w", ErrPermanent)
+	}
+	if o.weight != nil {
+		if _, ok := o.weight.(func(K, V) int); !ok {
+			return fmt.Errorf("foreach.WithWeight: fn is a %T, want a %v: %w", o.weight, reflect.TypeFor[func(K, V) int](), ErrPermanent)
+		}
+	}
+	return nil
+}
+
 // wait is Item's pre-dispatch checkpoint: it blocks until every configured pause condition (gate open,
-// order below its held bound) holds on a single pass. The gate is re-checked after an order wait, so a
-// retry that engages while dispatch is parked at a full order is honored before the next dispatch
-// instead of leaking one ItemFunc through the paused gate.
+// adaptive limit not reached, order below its held bound) holds on a single pass. The gate and the limit are
+// re-checked after the later waits, so a retry that engages, or a limit that drops, while dispatch is parked on
+// one of them is honored before the next dispatch instead of leaking one ItemFunc through. WithLimiter's slot is
+// not waited for here but taken right before dispatch, as a Limiter may be shared.
 func (o options) wait(ctx context.Context) error {
 	for {
 		if o.gate != nil {
@@ -122,12 +255,17 @@ func (o options) wait(ctx context.Context) error {
 				return err
 			}
 		}
+		if o.adapt != nil {
+			if err := o.adapt.wait(ctx); err != nil {
+				return err
+			}
+		}
 		if o.orderWait != nil {
 			if err := o.orderWait(ctx); err != nil {
 				return err
 			}
 		}
-		if o.gate == nil || o.orderWait == nil || o.gate.open() {
+		if (o.gate == nil || o.gate.open()) && (o.adapt == nil || o.adapt.below()) {
 			return nil
 		}
 	}
@@ -175,13 +313,15 @@ func WithStopOnErr() Option {
 // against a dependency that never recovers, bound the recovery with the Policy's MaxAttempts or a ctx
 // deadline or the gate stays closed and the range does not finish. The gate belongs to one Item range
 // and engages only on ItemFunc errors: concurrent Item calls against the same dependency gate
-// independently, so shared protection needs a shared Limited pool instead. A nil boff is an error.
+// independently unless they share a Gate through WithSharedGate, which WithGate overrides when it comes
+// later. A nil boff is an error.
 func WithGate(boff *exponential.Backoff) Option {
 	return func(o options) (options, error) {
 		if boff == nil {
 			return o, fmt.Errorf("foreach.WithGate: boff cannot be nil: %w", ErrPermanent)
 		}
 		o.boff = boff
+		o.shared = nil
 		return o, nil
 	}
 }
@@ -211,10 +351,65 @@ func WithMaxHeld(max int) Option {
 	}
 }
 
+// WithAdaptiveConcurrency lets Item find how many ItemFuncs to run at once, between min and max, from how they
+// fare, instead of always running as many as the pool allows. It suits an ItemFunc that calls a remote service,
+// whose right concurrency moves with the service's load. The limit starts at min and grows by one for every
+// limit's worth of ItemFuncs that succeed while it is reached; it shrinks by a tenth when an ItemFunc fails, or
+// takes more than twice the latency of one that did not queue (the lowest latency seen, which drifts up slowly
+// so a service that gets slower for good becomes the new normal). An error wrapping ErrPermanent or from
+// cancellation leaves the limit alone. Dispatch pauses while the limit is reached, and the pool's worker count
+// still applies, so a max above it has no effect. With WithName, the limit is recorded on the "adaptive.limit"
+// gauge among its metrics. Each Item range starts again from min. min must be > 0 and max >= min.
+func WithAdaptiveConcurrency(min, max int) Option {
+	return func(o options) (options, error) {
+		if min < 1 {
+			return o, fmt.Errorf("foreach.WithAdaptiveConcurrency: min must be > 0, got %d: %w", min, ErrPermanent)
+		}
+		if max < min {
+			return o, fmt.Errorf("foreach.WithAdaptiveConcurrency: max must be >= min, got %d < %d: %w", max, min, ErrPermanent)
+		}
+		o.minConc = min
+		o.maxConc = max
+		return o, nil
+	}
+}
+
+// WithName names the Item call, which turns on its metrics and namespaces them under name. The MeterProvider
+// comes from the Context passed to Item. Item records the ItemFuncs in flight, how long pairs wait to be
+// dispatched, how long ItemFuncs take, the pairs that fail by whether the error was permanent, the time
+// WithGate's gate holds dispatch paused and the responses WithOrdered is holding. Calls sharing a name share
+// their metrics. name cannot be empty.
+func WithName(name string) Option {
+	return func(o options) (options, error) {
+		if name == "" {
+			return o, fmt.Errorf("foreach.WithName: name cannot be empty: %w", ErrPermanent)
+		}
+		o.name = name
+		return o, nil
+	}
+}
+
+// WithItemSpans runs each pair's ItemFunc, retries included, in a child span of the Context's span, tagged with
+// the pair's key as the "key" attribute (formatted with fmt.Sprint) and with an error status if it fails. The
+// span is named after WithName's name, or "foreach.Item". Nothing is done when the Context's span is not
+// recording.
+func WithItemSpans() Option {
+	return func(o options) (options, error) {
+		o.spans = true
+		return o, nil
+	}
+}
+
 // keyed carries a pair's input key alongside its response from the workers to the consumer.
 type keyed[K, R any] struct {
 	k    K
 	resp stream.Result[R]
+	// idx is the pair's place in the input, and done whether its ItemFunc completed rather than being cut
+	// short, for WithCheckpoint.
+	idx  int
+	done bool
+	// skip marks an entry Items sends only to close a pair that succeeded: it has no Result to yield.
+	skip bool
 }
 
 // Item runs fn on every key/value pair yielded by seq, in parallel on the worker pool attached to ctx,
@@ -244,6 +439,12 @@ func Item[K, V, R any](ctx context.Context, in iter.Seq2[K, V], fn ItemFunc[K, V
 		panic("foreach.Item: fn cannot be nil")
 	}
 	o, err := resolveOptions(options)
+	if err == nil {
+		err = checkOptions[K, V](o)
+	}
+	if err == nil && o.name != "" {
+		o.metrics = newMetrics(context.MeterProvider(ctx).Meter(meterName + "/" + o.name))
+	}
 	if err != nil {
 		return func(yield func(K, stream.Result[R]) bool) {
 			var zero K
@@ -265,26 +466,61 @@ func pool(ctx context.Context) *worker.Pool {
 	return p
 }
 
+// wire installs a range's own wiring on o, the range's copy of the resolved options: the gate, adapt, weights
+// and flights its options ask for, and the position WithCheckpoint loads, returned as the range's progress. ctx
+// is the range's Context and p its pool. A Load error is returned for the range to yield.
+func (o *options) wire(ctx context.Context, p *worker.Pool) (*progress, error) {
+	switch {
+	case o.shared != nil:
+		o.gate = o.shared
+	case o.boff != nil:
+		o.gate = newGate(ctx, o.metrics)
+	}
+	if o.maxConc > 0 {
+		o.adapt = newAdaptive(ctx, o.minConc, o.maxConc, o.metrics)
+	}
+	if o.weight != nil {
+		o.weights = newWeights(p.Limit())
+	}
+	if o.dedup != nil {
+		o.flights = newFlights(o.cacheSize, o.cacheTTL)
+	}
+	if o.checkpoint == nil {
+		return nil, nil
+	}
+	prog, err := loadProgress(ctx, o.checkpoint, o.checkpointEvery)
+	if err != nil {
+		return nil, err
+	}
+	o.resume = prog.next
+	return prog, nil
+}
+
 // input carries one pair from the puller to the dispatch loop.
 type input[K, V any] struct {
 	k K
 	v V
+	// idx is the pair's place in the input, and read when it was read from it, for WithName's metrics.
+	idx  int
+	read time.Time
 }
 
 // unorderedSeq streams responses in completion order through a bounded channel; the channel is the
 // backpressure, so no order engine or checkpoint is involved.
 func unorderedSeq[K, V, R any](ctx context.Context, seq iter.Seq2[K, V], fn ItemFunc[K, V, R], o options) iter.Seq2[K, stream.Result[R]] {
 	return func(yield func(K, stream.Result[R]) bool) {
-		// Every range gets its own copy of the resolved options and its own wiring (the gate), so
-		// ranging the returned sequence again — even concurrently — shares nothing with a prior range.
+		// Every range gets its own copy of the resolved options and its own wiring, so ranging the
+		// returned sequence again — even concurrently — shares nothing with a prior range.
 		o := o
-		if o.boff != nil {
-			o.gate = &gate{}
-		}
-
 		ctx, cancel := context.WithCancel(ctx)
-
 		p := pool(ctx)
+		prog, err := o.wire(ctx, p)
+		if err != nil {
+			cancel()
+			var zero K
+			yield(zero, stream.Result[R]{Err: err})
+			return
+		}
 		out := make(chan keyed[K, R], o.held(p))
 		// gone signals that the consumer abandoned the range: it is the only thing that may drop a
 		// delivered response. Cancellation alone must not — the consumer may still be draining.
@@ -297,9 +533,9 @@ func unorderedSeq[K, V, R any](ctx context.Context, seq iter.Seq2[K, V], fn Item
 			pull:    make(chan input[K, V]),
 			done:    make(chan struct{}),
 			workers: p,
-			deliver: func(_ int, k K, resp stream.Result[R]) {
+			deliver: func(_ int, kv keyed[K, R]) {
 				select {
-				case out <- keyed[K, R]{k: k, resp: resp}:
+				case out <- kv:
 				case <-gone:
 				}
 			},
@@ -313,10 +549,18 @@ func unorderedSeq[K, V, R any](ctx context.Context, seq iter.Seq2[K, V], fn Item
 			close(gone)
 			cancel()
 			<-d.done
+			if prog != nil {
+				// The range's Context is cancelled by now; the last position is still worth saving.
+				prog.save(context.WithoutCancel(ctx))
+			}
 		}()
 
 		for kv := range out {
-			if !yield(kv.k, kv.resp) {
+			ok := yield(kv.k, kv.resp)
+			if prog != nil && kv.done {
+				prog.complete(ctx, kv.idx)
+			}
+			if !ok {
 				return
 			}
 		}
@@ -328,46 +572,69 @@ func unorderedSeq[K, V, R any](ctx context.Context, seq iter.Seq2[K, V], fn Item
 // checkpoint while the engine holds too many undelivered responses.
 func orderedSeq[K, V, R any](ctx context.Context, seq iter.Seq2[K, V], fn ItemFunc[K, V, R], o options) iter.Seq2[K, stream.Result[R]] {
 	return func(yield func(K, stream.Result[R]) bool) {
-		// Every range gets its own copy of the resolved options and its own wiring (gate, orderWait),
-		// so ranging the returned sequence again — even concurrently — shares nothing with a prior
-		// range.
-		o := o
-		if o.boff != nil {
-			o.gate = &gate{}
-		}
-
-		ctx, cancel := context.WithCancel(ctx)
+		orderedRange(ctx, seq, fn, o, func(k K, resp stream.Result[R]) (bool, bool) {
+			return yield(k, resp), true
+		})
+	}
+}
 
-		p := pool(ctx)
-		ord := newOrder[keyed[K, R]]()
-		held := o.held(p)
-		o.orderWait = func(ctx context.Context) error { return ord.waitBelow(ctx, held) }
-		d := &dispatcher[K, V, R]{
-			seq:     seq,
-			fn:      fn,
-			o:       o,
-			cancel:  cancel,
-			pull:    make(chan input[K, V]),
-			done:    make(chan struct{}),
-			workers: p,
-			deliver: func(i int, k K, resp stream.Result[R]) {
-				ord.add(i, keyed[K, R]{k: k, resp: resp})
-			},
-			finish: ord.finish,
+// orderedRange is one range of orderedSeq, handing each response to each in input order. each reports whether
+// the range goes on and whether it yielded the whole response: Items yields a response as several results, and
+// a pair the body broke out of part way through has not completed for WithCheckpoint.
+func orderedRange[K, V, R any](ctx context.Context, seq iter.Seq2[K, V], fn ItemFunc[K, V, R], o options, each func(k K, resp stream.Result[R]) (ok, whole bool)) {
+	// o is the range's own copy of the resolved options, and gets its own wiring, orderWait included, so
+	// ranging the returned sequence again — even concurrently — shares nothing with a prior range.
+	ctx, cancel := context.WithCancel(ctx)
+	p := pool(ctx)
+	prog, err := o.wire(ctx, p)
+	if err != nil {
+		cancel()
+		var zero K
+		each(zero, stream.Result[R]{Err: err})
+		return
+	}
+	ord := newOrder[keyed[K, R]]()
+	held := o.held(p)
+	o.orderWait = func(ctx context.Context) error { return ord.waitBelow(ctx, held) }
+	d := &dispatcher[K, V, R]{
+		seq:     seq,
+		fn:      fn,
+		o:       o,
+		cancel:  cancel,
+		pull:    make(chan input[K, V]),
+		done:    make(chan struct{}),
+		workers: p,
+		deliver: func(i int, kv keyed[K, R]) {
+			ord.add(i, kv)
+			if o.metrics != nil {
+				o.metrics.Held.Record(ctx, int64(ord.held()))
+			}
+		},
+		finish: ord.finish,
+	}
+	d.launch(ctx)
+	// The join: returning from the range — normally or by breaking — cancels remaining work and
+	// waits for the dispatched ItemFuncs to finish, so their side effects happen before the range
+	// returns.
+	defer func() {
+		cancel()
+		<-d.done
+		if prog != nil {
+			// The range's Context is cancelled by now; the last position is still worth saving.
+			prog.save(context.WithoutCancel(ctx))
 		}
-		d.launch(ctx)
-		// The join: returning from the range — normally or by breaking — cancels remaining work and
-		// waits for the dispatched ItemFuncs to finish, so their side effects happen before the range
-		// returns.
-		defer func() {
-			cancel()
-			<-d.done
-		}()
+	}()
 
-		for _, kv := range ord.all(ctx) {
-			if !yield(kv.k, kv.resp) {
-				return
-			}
+	for _, kv := range ord.all(ctx) {
+		ok, whole := each(kv.k, kv.resp)
+		if o.metrics != nil {
+			o.metrics.Held.Record(ctx, int64(ord.held()))
+		}
+		if prog != nil && kv.done && whole {
+			prog.complete(ctx, kv.idx)
+		}
+		if !ok {
+			return
 		}
 	}
 }
@@ -121,6 +121,246 @@ func TestItem(t *testing.T) {
 			wantPermanent: true,
 			exact:         true,
 		},
+		{
+			name:     "Success: WithAdaptiveConcurrency processes every value",
+			seq:      seqOf(1, 2, 3),
+			errOn:    never,
+			opts:     []Option{WithAdaptiveConcurrency(1, 4)},
+			wantVals: []int{2, 4, 6},
+			exact:    true,
+		},
+		{
+			name:          "Error: a WithAdaptiveConcurrency min < 1 yields a single Response wrapping ErrPermanent",
+			seq:           seqOf(1, 2, 3),
+			errOn:         never,
+			opts:          []Option{WithAdaptiveConcurrency(0, 4)},
+			wantErrs:      1,
+			wantPermanent: true,
+			exact:         true,
+		},
+		{
+			name:          "Error: a WithAdaptiveConcurrency max < min yields a single Response wrapping ErrPermanent",
+			seq:           seqOf(1, 2, 3),
+			errOn:         never,
+			opts:          []Option{WithAdaptiveConcurrency(4, 2)},
+			wantErrs:      1,
+			wantPermanent: true,
+			exact:         true,
+		},
+		{
+			name:     "Success: WithItemTimeout and WithHedge process every value",
+			seq:      seqOf(1, 2, 3),
+			errOn:    never,
+			opts:     []Option{WithItemTimeout(time.Minute), WithHedge(time.Minute, 1)},
+			wantVals: []int{2, 4, 6},
+			exact:    true,
+		},
+		{
+			name:          "Error: a WithItemTimeout d <= 0 yields a single Response wrapping ErrPermanent",
+			seq:           seqOf(1, 2, 3),
+			errOn:         never,
+			opts:          []Option{WithItemTimeout(0)},
+			wantErrs:      1,
+			wantPermanent: true,
+			exact:         true,
+		},
+		{
+			name:          "Error: a WithHedge after <= 0 yields a single Response wrapping ErrPermanent",
+			seq:           seqOf(1, 2, 3),
+			errOn:         never,
+			opts:          []Option{WithHedge(0, 1)},
+			wantErrs:      1,
+			wantPermanent: true,
+			exact:         true,
+		},
+		{
+			name:          "Error: a WithHedge max < 1 yields a single Response wrapping ErrPermanent",
+			seq:           seqOf(1, 2, 3),
+			errOn:         never,
+			opts:          []Option{WithHedge(time.Millisecond, 0)},
+			wantErrs:      1,
+			wantPermanent: true,
+			exact:         true,
+		},
+		{
+			name:          "Error: a nil WithDeadLetter sink yields a single Response wrapping ErrPermanent",
+			seq:           seqOf(1, 2, 3),
+			errOn:         never,
+			opts:          []Option{WithDeadLetter[int, int](nil)},
+			wantErrs:      1,
+			wantPermanent: true,
+			exact:         true,
+		},
+		{
+			name:          "Error: a WithDeadLetter sink of other types yields a single Response wrapping ErrPermanent",
+			seq:           seqOf(1, 2, 3),
+			errOn:         never,
+			opts:          []Option{WithDeadLetter(DeadLetterChan(make(chan DeadLetter[string, int])))},
+			wantErrs:      1,
+			wantPermanent: true,
+			exact:         true,
+		},
+		{
+			name:          "Error: a nil WithPriority fn yields a single Response wrapping ErrPermanent",
+			seq:           seqOf(1, 2, 3),
+			errOn:         never,
+			opts:          []Option{WithPriority[int, int](nil)},
+			wantErrs:      1,
+			wantPermanent: true,
+			exact:         true,
+		},
+		{
+			name:          "Error: a WithPriority fn of other types yields a single Response wrapping ErrPermanent",
+			seq:           seqOf(1, 2, 3),
+			errOn:         never,
+			opts:          []Option{WithPriority(func(k string, v int) int { return v })},
+			wantErrs:      1,
+			wantPermanent: true,
+			exact:         true,
+		},
+		{
+			name:          "Error: WithPriority with WithOrdered yields a single Response wrapping ErrPermanent",
+			seq:           seqOf(1, 2, 3),
+			errOn:         never,
+			opts:          []Option{WithPriority(func(k, v int) int { return v }), WithOrdered()},
+			wantErrs:      1,
+			wantPermanent: true,
+			exact:         true,
+		},
+		{
+			name:          "Error: a WithLookahead n < 1 yields a single Response wrapping ErrPermanent",
+			seq:           seqOf(1, 2, 3),
+			errOn:         never,
+			opts:          []Option{WithLookahead(0)},
+			wantErrs:      1,
+			wantPermanent: true,
+			exact:         true,
+		},
+		{
+			name:          "Error: a nil WithWeight fn yields a single Response wrapping ErrPermanent",
+			seq:           seqOf(1, 2, 3),
+			errOn:         never,
+			opts:          []Option{WithWeight[int, int](nil)},
+			wantErrs:      1,
+			wantPermanent: true,
+			exact:         true,
+		},
+		{
+			name:          "Error: a WithWeight fn of other types yields a single Response wrapping ErrPermanent",
+			seq:           seqOf(1, 2, 3),
+			errOn:         never,
+			opts:          []Option{WithWeight(func(k int, v string) int { return 1 })},
+			wantErrs:      1,
+			wantPermanent: true,
+			exact:         true,
+		},
+		{
+			name:          "Error: a nil WithCheckpoint cp yields a single Response wrapping ErrPermanent",
+			seq:           seqOf(1, 2, 3),
+			errOn:         never,
+			opts:          []Option{WithCheckpoint(nil, 0)},
+			wantErrs:      1,
+			wantPermanent: true,
+			exact:         true,
+		},
+		{
+			name:          "Error: a WithCheckpoint every < 0 yields a single Response wrapping ErrPermanent",
+			seq:           seqOf(1, 2, 3),
+			errOn:         never,
+			opts:          []Option{WithCheckpoint(NewFileCheckpointer("unused"), -1)},
+			wantErrs:      1,
+			wantPermanent: true,
+			exact:         true,
+		},
+		{
+			name:          "Error: an empty WithName yields a single Response wrapping ErrPermanent",
+			seq:           seqOf(1, 2, 3),
+			errOn:         never,
+			opts:          []Option{WithName("")},
+			wantErrs:      1,
+			wantPermanent: true,
+			exact:         true,
+		},
+		{
+			name:          "Error: a nil WithDedup key yields a single Response wrapping ErrPermanent",
+			seq:           seqOf(1, 2, 3),
+			errOn:         never,
+			opts:          []Option{WithDedup[int, int, int](nil)},
+			wantErrs:      1,
+			wantPermanent: true,
+			exact:         true,
+		},
+		{
+			name:          "Error: a WithDedup key of other types yields a single Response wrapping ErrPermanent",
+			seq:           seqOf(1, 2, 3),
+			errOn:         never,
+			opts:          []Option{WithDedup(func(k string, v int) int { return v })},
+			wantErrs:      1,
+			wantPermanent: true,
+			exact:         true,
+		},
+		{
+			name:          "Error: WithDedupCache without WithDedup yields a single Response wrapping ErrPermanent",
+			seq:           seqOf(1, 2, 3),
+			errOn:         never,
+			opts:          []Option{WithDedupCache(1, time.Minute)},
+			wantErrs:      1,
+			wantPermanent: true,
+			exact:         true,
+		},
+		{
+			name:          "Error: a WithDedupCache size < 1 yields a single Response wrapping ErrPermanent",
+			seq:           seqOf(1, 2, 3),
+			errOn:         never,
+			opts:          []Option{WithDedup(func(k, v int) int { return v }), WithDedupCache(0, time.Minute)},
+			wantErrs:      1,
+			wantPermanent: true,
+			exact:         true,
+		},
+		{
+			name:          "Error: a WithDedupCache ttl <= 0 yields a single Response wrapping ErrPermanent",
+			seq:           seqOf(1, 2, 3),
+			errOn:         never,
+			opts:          []Option{WithDedup(func(k, v int) int { return v }), WithDedupCache(1, 0)},
+			wantErrs:      1,
+			wantPermanent: true,
+			exact:         true,
+		},
+		{
+			name:          "Error: a nil WithLimiter l yields a single Response wrapping ErrPermanent",
+			seq:           seqOf(1, 2, 3),
+			errOn:         never,
+			opts:          []Option{WithLimiter(nil)},
+			wantErrs:      1,
+			wantPermanent: true,
+			exact:         true,
+		},
+		{
+			name:          "Error: a nil WithSharedGate boff yields a single Response wrapping ErrPermanent",
+			seq:           seqOf(1, 2, 3),
+			errOn:         never,
+			opts:          []Option{WithSharedGate(nil, NewGate())},
+			wantErrs:      1,
+			wantPermanent: true,
+			exact:         true,
+		},
+		{
+			name:          "Error: a nil WithSharedGate g yields a single Response wrapping ErrPermanent",
+			seq:           seqOf(1, 2, 3),
+			errOn:         never,
+			opts:          []Option{WithSharedGate(testBoff(), nil)},
+			wantErrs:      1,
+			wantPermanent: true,
+			exact:         true,
+		},
+		{
+			name:     "Success: WithPriority and WithWeight process every value",
+			seq:      seqOf(1, 2, 3),
+			errOn:    never,
+			opts:     []Option{WithPriority(func(k, v int) int { return v }), WithWeight(func(k, v int) int { return v })},
+			wantVals: []int{2, 4, 6},
+			exact:    true,
+		},
 	}
 
 	for _, test := range tests {
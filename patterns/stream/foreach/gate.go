@@ -1,45 +1,131 @@
 package foreach
 
 import (
+	"fmt"
+	"time"
+
 	"github.com/gostdlib/base/concurrency/sync"
 	"github.com/gostdlib/base/context"
+	"github.com/gostdlib/base/retry/exponential"
 )
 
-// gate pauses Item's dispatch of new work while pairs are retrying under WithGate. It is counting —
-// dispatch proceeds only while no pauses are outstanding, so several pairs retrying against the same
-// sick dependency keep dispatch paused until the last one resolves. Each Item range gets its own
-// gate. All methods are safe for concurrent use.
-type gate struct {
+// Gate pauses dispatch of new work while pairs are retrying under WithGate or WithSharedGate. It is counting —
+// dispatch proceeds only while no pauses are outstanding, so several pairs retrying against the same sick
+// dependency keep dispatch paused until the last one resolves. WithGate gives each Item range a Gate of its
+// own; make one with NewGate and pass it to WithSharedGate to share it between ranges, so a pair retrying in
+// any of them pauses them all. All methods are safe for concurrent use.
+type Gate struct {
 	mu sync.Mutex
 	// count is the number of outstanding pauses.
 	count int
 	// opened is created on the transition to paused and closed on the transition back; waiters block on
 	// the channel they observed, then re-check.
 	opened chan struct{}
+	// since is when the gate last paused, and closed, when non-nil, is told how long it stayed paused each time
+	// it opens, for WithName's metrics.
+	since  time.Time
+	closed func(d time.Duration)
+	// pauses, total and longest are the count, summed duration and longest of the pauses that have ended.
+	pauses  int
+	total   time.Duration
+	longest time.Duration
+}
+
+// NewGate returns an open Gate to share between Item calls, and fanout runs, with WithSharedGate.
+func NewGate() *Gate {
+	return &Gate{}
+}
+
+// newGate returns the gate for a range, recording the time it holds dispatch paused on mets when the Item call
+// has metrics.
+func newGate(ctx context.Context, mets *metrics) *Gate {
+	g := &Gate{}
+	if mets != nil {
+		g.closed = func(d time.Duration) { mets.GateClosed.Add(ctx, d.Seconds()) }
+	}
+	return g
+}
+
+// GateStats is a snapshot of a Gate's pauses, as returned by Gate.Stats.
+type GateStats struct {
+	// Paused is whether the gate is holding dispatch paused now, Retrying how many pairs are retrying behind
+	// it, and Since when the current pause began. Since is the zero time when the gate is open.
+	Paused   bool
+	Retrying int
+	Since    time.Time
+	// Pauses is how many pauses have ended, Total their summed duration and Longest the longest of them.
+	Pauses  int
+	Total   time.Duration
+	Longest time.Duration
+}
+
+// Stats returns a snapshot of the gate's pauses.
+func (g *Gate) Stats() GateStats {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	s := GateStats{
+		Paused:   g.count > 0,
+		Retrying: g.count,
+		Pauses:   g.pauses,
+		Total:    g.total,
+		Longest:  g.longest,
+	}
+	if s.Paused {
+		s.Since = g.since
+	}
+	return s
+}
+
+// WithSharedGate is WithGate behind g, a Gate other Item calls and fanout runs may share: a pair retrying in
+// any range using g pauses dispatch in all of them until it resolves, so every run talking to a dependency
+// backs off when one of them finds it struggling. Each range still retries its own pairs with boff. g's Stats
+// report its pauses; WithName's gate.closed metric is only recorded for a range's own gate. Neither boff nor g
+// can be nil.
+func WithSharedGate(boff *exponential.Backoff, g *Gate) Option {
+	return func(o options) (options, error) {
+		if boff == nil {
+			return o, fmt.Errorf("foreach.WithSharedGate: boff cannot be nil: %w", ErrPermanent)
+		}
+		if g == nil {
+			return o, fmt.Errorf("foreach.WithSharedGate: g cannot be nil: %w", ErrPermanent)
+		}
+		o.boff = boff
+		o.shared = g
+		return o, nil
+	}
 }
 
 // pause moves the gate to (or keeps it in) the paused state.
-func (g *gate) pause() {
+func (g *Gate) pause() {
 	g.mu.Lock()
 	defer g.mu.Unlock()
 	if g.count == 0 {
 		g.opened = make(chan struct{})
+		g.since = time.Now()
 	}
 	g.count++
 }
 
 // resume undoes one pause; the gate opens when no pauses remain outstanding.
-func (g *gate) resume() {
+func (g *Gate) resume() {
 	g.mu.Lock()
 	defer g.mu.Unlock()
 	g.count--
 	if g.count == 0 {
 		close(g.opened)
+		d := time.Since(g.since)
+		g.pauses++
+		g.total += d
+		g.longest = max(g.longest, d)
+		if g.closed != nil {
+			g.closed(d)
+		}
 	}
 }
 
 // open reports whether no pauses are outstanding.
-func (g *gate) open() bool {
+func (g *Gate) open() bool {
 	g.mu.Lock()
 	defer g.mu.Unlock()
 	return g.count == 0
@@ -47,7 +133,7 @@ func (g *gate) open() bool {
 
 // wait blocks while the gate is paused. It returns nil when the gate is open and ctx.Err() if ctx is
 // cancelled first.
-func (g *gate) wait(ctx context.Context) error {
+func (g *Gate) wait(ctx context.Context) error {
 	for {
 		g.mu.Lock()
 		if g.count == 0 {
@@ -230,3 +230,84 @@ func TestWithGateContextCancel(t *testing.T) {
 	cancel()
 	<-itemDone // Reaching here is the assertion: cancellation unwound the retry, the gate and the join.
 }
+
+// TestWithSharedGate proves a pair retrying in one range pauses dispatch in another range sharing its Gate,
+// and that the Gate's Stats report the pause.
+func TestWithSharedGate(t *testing.T) {
+	t.Parallel()
+
+	ctx := t.Context()
+	g := NewGate()
+
+	const total = 20
+
+	gateClosed := make(chan struct{})
+	release := make(chan struct{})
+	releaseOnce := sync.OnceFunc(func() { close(release) })
+	t.Cleanup(releaseOnce)
+
+	// The retrying range: its only pair fails once, then blocks in the retry until release.
+	var attempts atomic.Int64
+	retrying := func(ctx context.Context, _ int, v int) (int, error) {
+		if attempts.Add(1) == 1 {
+			return 0, fmt.Errorf("dependency unavailable")
+		}
+		close(gateClosed)
+		select {
+		case <-release:
+			return v, nil
+		case <-ctx.Done():
+			return 0, ctx.Err()
+		}
+	}
+
+	// The other range's pairs only become available once the gate is closed.
+	var seq iter.Seq2[int, int] = func(yield func(int, int) bool) {
+		<-gateClosed
+		for i := range total {
+			if !yield(i, i) {
+				return
+			}
+		}
+	}
+	var started atomic.Int64
+	other := func(ctx context.Context, _ int, v int) (int, error) {
+		started.Add(1)
+		return v, nil
+	}
+
+	retryDone := make(chan struct{})
+	context.Pool(ctx).Submit(ctx, func() {
+		defer close(retryDone)
+		for range Item(ctx, seqOf(0), retrying, WithSharedGate(testBoff(), g)) {
+		}
+	})
+	otherDone := make(chan struct{})
+	context.Pool(ctx).Submit(ctx, func() {
+		defer close(otherDone)
+		for range Item(ctx, seq, other, WithSharedGate(testBoff(), g)) {
+		}
+	})
+
+	<-gateClosed
+	// As in TestWithGatePausesDispatch, one pair may have passed the gate before it closed.
+	time.Sleep(50 * time.Millisecond)
+	if n := started.Load(); n > 1 {
+		t.Fatalf("TestWithSharedGate: got %d pairs dispatched in the other range while the gate was closed, want at most 1", n)
+	}
+	stats := g.Stats()
+	if !stats.Paused || stats.Retrying != 1 || stats.Since.IsZero() {
+		t.Errorf("TestWithSharedGate: got Stats() == %+v while paused, want Paused, 1 Retrying and a Since", stats)
+	}
+
+	releaseOnce()
+	<-retryDone
+	<-otherDone
+	if n := started.Load(); n != total {
+		t.Errorf("TestWithSharedGate: got %d pairs dispatched in the other range, want %d", n, total)
+	}
+	stats = g.Stats()
+	if stats.Paused || stats.Pauses != 1 || stats.Longest < 50*time.Millisecond || stats.Total != stats.Longest {
+		t.Errorf("TestWithSharedGate: got Stats() == %+v after the retry, want 1 pause of at least 50ms", stats)
+	}
+}
@@ -0,0 +1,182 @@
+package foreach
+
+import (
+	"fmt"
+	"iter"
+
+	"github.com/gostdlib/base/context"
+	"github.com/gostdlib/concurrency/patterns/stream"
+)
+
+// ItemsFunc expands one key/value pair from the input sequence into any number of results — zero, one or many,
+// like the pages of an API listing or the records of a file — passing each to emit in the order they should be
+// yielded, and returns the pair's error or nil. Call emit only from the ItemsFunc's own goroutine, before it
+// returns. emit returns an error once the pair has been cancelled or the range abandoned; the ItemsFunc should
+// then stop and return it. Like an ItemFunc, it gets its value by copy and runs concurrently with other pairs'.
+type ItemsFunc[K, V, R any] func(ctx context.Context, k K, v V, emit func(r R) error) error
+
+// Items is Item for an ItemsFunc: it runs fn on every key/value pair yielded by in, in parallel on the worker
+// pool attached to ctx, and yields every result fn emits under the pair's input key, followed, for a pair whose
+// fn failed, by a Response carrying the error. A pair that succeeds without emitting yields nothing. Items takes
+// Item's options, and what Item says about ranging, cancellation and invalid options holds for Items.
+//
+// Without WithOrdered, results are yielded as they are emitted: different pairs' results interleave, each
+// pair's in the order it emitted them, and emit blocks while the range's body is behind, with WithMaxHeld
+// bounding the results waiting for it. A pair that fails may have yielded results before its error, and under
+// WithGate a retried attempt emits again from its first result. Results cannot be taken back once yielded, so
+// WithHedge and WithDedup require WithOrdered.
+//
+// With WithOrdered, results are yielded in input order and, within a pair, in the order emitted. A pair's
+// results are held until it has finished and every earlier pair's have been yielded, so WithMaxHeld bounds the
+// pairs held rather than the results. A pair that fails yields only its error: the results of a failed attempt
+// are dropped, and a retry emits afresh.
+func Items[K, V, R any](ctx context.Context, in iter.Seq2[K, V], fn ItemsFunc[K, V, R], options ...Option) iter.Seq2[K, stream.Result[R]] {
+	if in == nil {
+		panic("foreach.Items: in cannot be nil")
+	}
+	if fn == nil {
+		panic("foreach.Items: fn cannot be nil")
+	}
+	o, err := resolveOptions(options)
+	if err == nil {
+		err = checkOptions[K, V](o)
+	}
+	if err == nil && !o.ordered {
+		switch {
+		case o.hedgeMax > 0:
+			err = fmt.Errorf("foreach.Items: WithHedge requires WithOrdered: %w", ErrPermanent)
+		case o.dedup != nil:
+			err = fmt.Errorf("foreach.Items: WithDedup requires WithOrdered: %w", ErrPermanent)
+		}
+	}
+	if err == nil && o.name != "" {
+		o.metrics = newMetrics(context.MeterProvider(ctx).Meter(meterName + "/" + o.name))
+	}
+	if err != nil {
+		return func(yield func(K, stream.Result[R]) bool) {
+			var zero K
+			yield(zero, stream.Result[R]{Err: err})
+		}
+	}
+	if o.ordered {
+		return func(yield func(K, stream.Result[R]) bool) {
+			orderedRange(ctx, in, collect(fn), o, flatten(yield))
+		}
+	}
+	return itemsSeq(ctx, in, fn, o)
+}
+
+// collect adapts fn to an ItemFunc that returns the results it emits, for WithOrdered, where a pair's results
+// wait for their turn anyway. Each attempt collects its own.
+func collect[K, V, R any](fn ItemsFunc[K, V, R]) ItemFunc[K, V, []R] {
+	return func(ctx context.Context, k K, v V) ([]R, error) {
+		var rs []R
+		err := fn(ctx, k, v, func(r R) error {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			rs = append(rs, r)
+			return nil
+		})
+		return rs, err
+	}
+}
+
+// flatten returns orderedRange's each for Items: it yields a pair's collected results in turn, or its error,
+// and reports whether it yielded them all.
+func flatten[K, R any](yield func(K, stream.Result[R]) bool) func(K, stream.Result[[]R]) (bool, bool) {
+	return func(k K, resp stream.Result[[]R]) (ok, whole bool) {
+		if resp.Err != nil {
+			return yield(k, stream.Result[R]{Err: resp.Err}), true
+		}
+		for i, r := range resp.V {
+			if !yield(k, stream.Result[R]{V: r}) {
+				return false, i == len(resp.V)-1
+			}
+		}
+		return true, true
+	}
+}
+
+// itemsSeq is unorderedSeq for an ItemsFunc: emit sends each result straight to the delivery buffer, and the
+// dispatcher's own Response for the pair only carries its error, or, under WithCheckpoint, closes the pair so
+// the range can count it completed once every result before it has been yielded.
+func itemsSeq[K, V, R any](ctx context.Context, seq iter.Seq2[K, V], fn ItemsFunc[K, V, R], o options) iter.Seq2[K, stream.Result[R]] {
+	return func(yield func(K, stream.Result[R]) bool) {
+		// Every range gets its own copy of the resolved options and its own wiring, so ranging the
+		// returned sequence again — even concurrently — shares nothing with a prior range.
+		o := o
+		ctx, cancel := context.WithCancel(ctx)
+		p := pool(ctx)
+		prog, err := o.wire(ctx, p)
+		if err != nil {
+			cancel()
+			var zero K
+			yield(zero, stream.Result[R]{Err: err})
+			return
+		}
+
+		out := make(chan keyed[K, R], o.held(p))
+		// gone signals that the consumer abandoned the range, as in unorderedSeq.
+		gone := make(chan struct{})
+		each := func(ctx context.Context, k K, v V) (struct{}, error) {
+			return struct{}{}, fn(ctx, k, v, func(r R) error {
+				if err := ctx.Err(); err != nil {
+					return err
+				}
+				select {
+				case out <- keyed[K, R]{k: k, resp: stream.Result[R]{V: r}}:
+					return nil
+				case <-ctx.Done():
+					return ctx.Err()
+				case <-gone:
+					// The range's Context is cancelled right after gone closes.
+					return context.Canceled
+				}
+			})
+		}
+		d := &dispatcher[K, V, struct{}]{
+			seq:     seq,
+			fn:      each,
+			o:       o,
+			cancel:  cancel,
+			pull:    make(chan input[K, V]),
+			done:    make(chan struct{}),
+			workers: p,
+			deliver: func(_ int, kv keyed[K, struct{}]) {
+				skip := kv.resp.Err == nil
+				if skip && prog == nil {
+					return
+				}
+				select {
+				case out <- keyed[K, R]{k: kv.k, resp: stream.Result[R]{Err: kv.resp.Err}, idx: kv.idx, done: kv.done, skip: skip}:
+				case <-gone:
+				}
+			},
+			finish: func() { close(out) },
+		}
+		d.launch(ctx)
+		// The join, as in unorderedSeq.
+		defer func() {
+			close(gone)
+			cancel()
+			<-d.done
+			if prog != nil {
+				prog.save(context.WithoutCancel(ctx))
+			}
+		}()
+
+		for kv := range out {
+			ok := true
+			if !kv.skip {
+				ok = yield(kv.k, kv.resp)
+			}
+			if prog != nil && kv.done {
+				prog.complete(ctx, kv.idx)
+			}
+			if !ok {
+				return
+			}
+		}
+	}
+}
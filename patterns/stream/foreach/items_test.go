@@ -0,0 +1,184 @@
+package foreach
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+
+	"github.com/gostdlib/base/context"
+	"github.com/kylelemons/godebug/pretty"
+)
+
+func TestItems(t *testing.T) {
+	t.Parallel()
+
+	// expand emits "v.0" to "v.(v-1)" for pair v; pair 3 then fails.
+	expand := func(ctx context.Context, _ int, v int, emit func(string) error) error {
+		for i := range v {
+			if err := emit(fmt.Sprintf("%d.%d", v, i)); err != nil {
+				return err
+			}
+		}
+		if v == 3 {
+			return errors.New("boom")
+		}
+		return nil
+	}
+
+	tests := []struct {
+		name string
+		opts []Option
+		// ordered is whether want is the exact sequence yielded, rather than each key's own sequence.
+		ordered bool
+		// want is what each pair yields, "err" standing for an error Response.
+		want map[int][]string
+	}{
+		{
+			name: "Success: unordered",
+			want: map[int][]string{
+				1: {"1.0"},
+				2: {"2.0", "2.1"},
+				3: {"3.0", "3.1", "3.2", "err"},
+				4: {"4.0", "4.1", "4.2", "4.3"},
+			},
+		},
+		{
+			name:    "Success: ordered",
+			opts:    []Option{WithOrdered()},
+			ordered: true,
+			want: map[int][]string{
+				1: {"1.0"},
+				2: {"2.0", "2.1"},
+				3: {"err"},
+				4: {"4.0", "4.1", "4.2", "4.3"},
+			},
+		},
+		{
+			name:    "Success: ordered with WithDedup",
+			opts:    []Option{WithOrdered(), WithDedup(func(_ int, v int) int { return v })},
+			ordered: true,
+			want: map[int][]string{
+				1: {"1.0"},
+				2: {"2.0", "2.1"},
+				3: {"err"},
+				4: {"4.0", "4.1", "4.2", "4.3"},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		got := map[int][]string{}
+		var order []int
+		for k, resp := range Items(t.Context(), seqOf(0, 1, 2, 3, 4), expand, test.opts...) {
+			s := resp.V
+			if resp.Err != nil {
+				s = "err"
+			}
+			got[k] = append(got[k], s)
+			order = append(order, k)
+		}
+		if diff := pretty.Compare(test.want, got); diff != "" {
+			t.Errorf("TestItems(%s): -want/+got:\n%s", test.name, diff)
+		}
+		if test.ordered && !slices.IsSorted(order) {
+			t.Errorf("TestItems(%s): got keys in order %v, want input order", test.name, order)
+		}
+	}
+}
+
+func TestItemsOptions(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		opts []Option
+	}{
+		{name: "Error: WithHedge without WithOrdered", opts: []Option{WithHedge(1, 1)}},
+		{name: "Error: WithDedup without WithOrdered", opts: []Option{WithDedup(func(_ int, v int) int { return v })}},
+		{name: "Error: an invalid option", opts: []Option{WithMaxHeld(-1)}},
+	}
+
+	for _, test := range tests {
+		fn := func(ctx context.Context, _ int, v int, emit func(int) error) error {
+			t.Errorf("TestItemsOptions(%s): ItemsFunc ran", test.name)
+			return nil
+		}
+		n := 0
+		for _, resp := range Items(t.Context(), seqOf(ints(3)...), fn, test.opts...) {
+			n++
+			if !errors.Is(resp.Err, ErrPermanent) {
+				t.Errorf("TestItemsOptions(%s): got err == %v, want ErrPermanent", test.name, resp.Err)
+			}
+		}
+		if n != 1 {
+			t.Errorf("TestItemsOptions(%s): got %d Responses, want 1", test.name, n)
+		}
+	}
+}
+
+// TestItemsBreak verifies breaking out of the range releases an ItemsFunc blocked in emit, which sees an error.
+func TestItemsBreak(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.SetPool(t.Context(), context.Pool(t.Context()).Limited(t.Context(), "TestItemsBreak", 1))
+	var emitErr error
+	fn := func(ctx context.Context, _ int, v int, emit func(int) error) error {
+		for i := 0; ; i++ {
+			if err := emit(i); err != nil {
+				emitErr = err
+				return err
+			}
+		}
+	}
+
+	n := 0
+	for range Items(ctx, seqOf(0), fn, WithMaxHeld(1)) {
+		n++
+		if n == 10 {
+			break
+		}
+	}
+	// The range has joined the ItemsFunc by the time it returns.
+	if !errors.Is(emitErr, context.Canceled) {
+		t.Errorf("TestItemsBreak: got emit error %v, want context.Canceled", emitErr)
+	}
+}
+
+// TestItemsCheckpoint verifies a pair only counts as completed once all its results have been yielded.
+func TestItemsCheckpoint(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		opts []Option
+	}{
+		{name: "Success: unordered"},
+		{name: "Success: ordered", opts: []Option{WithOrdered()}},
+	}
+
+	for _, test := range tests {
+		// A single worker runs the pairs in input order, so the position is exact.
+		ctx := context.SetPool(t.Context(), context.Pool(t.Context()).Limited(t.Context(), "TestItemsCheckpoint", 1))
+		cp := &memCheckpointer{}
+		fn := func(ctx context.Context, _ int, v int, emit func(int) error) error {
+			for i := range 3 {
+				if err := emit(v*10 + i); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+		opts := append([]Option{WithCheckpoint(cp, 0), WithMaxHeld(1)}, test.opts...)
+
+		// Stop on pair 2's second result: pairs 0 and 1 have completed, 2 has not.
+		for _, resp := range Items(ctx, seqOf(ints(5)...), fn, opts...) {
+			if resp.V == 21 {
+				break
+			}
+		}
+		if cp.n != 2 {
+			t.Errorf("TestItemsCheckpoint(%s): got position %d, want 2", test.name, cp.n)
+		}
+	}
+}
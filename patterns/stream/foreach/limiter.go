@@ -0,0 +1,108 @@
+package foreach
+
+import (
+	"fmt"
+
+	"github.com/gostdlib/base/concurrency/sync"
+	"github.com/gostdlib/base/context"
+)
+
+// Limiter caps the ItemFuncs in flight, across every Item range given it with WithLimiter, at a limit that can be
+// changed while they run: an operator scaling a long run up or down, or a control loop. Raising the limit lets
+// dispatch go on at once; lowering it never interrupts an ItemFunc already running, dispatch just waits until
+// enough of them have finished. The pool's worker count still applies, so a limit above it has no effect. All
+// methods are safe for concurrent use.
+type Limiter struct {
+	mu sync.Mutex
+	// limit bounds inflight, the number of ItemFuncs dispatched under the Limiter and not yet done.
+	limit    int
+	inflight int
+	// changed is closed and replaced whenever inflight falls or the limit rises; waiters block on the channel
+	// they observed, then re-check.
+	changed chan struct{}
+}
+
+// NewLimiter returns a Limiter allowing n ItemFuncs in flight. n must be > 0.
+func NewLimiter(n int) (*Limiter, error) {
+	if n < 1 {
+		return nil, fmt.Errorf("foreach.NewLimiter: n must be > 0, got %d: %w", n, ErrPermanent)
+	}
+	return &Limiter{limit: n, changed: make(chan struct{})}, nil
+}
+
+// WithLimiter makes dispatch pause while l has as many ItemFuncs in flight as its limit allows. l may be shared
+// with other Item calls, which then share its limit: each slot is taken by one range alone, so together they
+// never pass it. A nil l is an error.
+func WithLimiter(l *Limiter) Option {
+	return func(o options) (options, error) {
+		if l == nil {
+			return o, fmt.Errorf("foreach.WithLimiter: l cannot be nil: %w", ErrPermanent)
+		}
+		o.limiter = l
+		return o, nil
+	}
+}
+
+// Set changes the limit to n. n must be > 0.
+func (l *Limiter) Set(n int) error {
+	if n < 1 {
+		return fmt.Errorf("foreach.Limiter.Set: n must be > 0, got %d: %w", n, ErrPermanent)
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if n > l.limit {
+		l.wake()
+	}
+	l.limit = n
+	return nil
+}
+
+// Limit returns the limit.
+func (l *Limiter) Limit() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.limit
+}
+
+// InFlight returns the number of ItemFuncs in flight under the Limiter. After the limit is lowered it may be
+// above the limit until enough of them finish.
+func (l *Limiter) InFlight() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.inflight
+}
+
+// acquire blocks until a slot is free and takes it for an ItemFunc about to be dispatched, checking and taking
+// it under one hold of mu, so ranges sharing the Limiter cannot both take the last slot and a limit lowered
+// while dispatch waited elsewhere is honored. It returns ctx.Err() if ctx is cancelled first.
+func (l *Limiter) acquire(ctx context.Context) error {
+	for {
+		l.mu.Lock()
+		if l.inflight < l.limit {
+			l.inflight++
+			l.mu.Unlock()
+			return nil
+		}
+		changed := l.changed
+		l.mu.Unlock()
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// done records a dispatched ItemFunc finishing, or a dispatched pair that never ran being swept.
+func (l *Limiter) done() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.inflight--
+	l.wake()
+}
+
+// wake wakes the waiters. The caller holds mu.
+func (l *Limiter) wake() {
+	close(l.changed)
+	l.changed = make(chan struct{})
+}
@@ -0,0 +1,151 @@
+package foreach
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gostdlib/base/context"
+)
+
+func TestNewLimiter(t *testing.T) {
+	t.Parallel()
+
+	if _, err := NewLimiter(0); err == nil {
+		t.Errorf("TestNewLimiter: got NewLimiter(0) == nil error, want err != nil")
+	}
+	l, err := NewLimiter(2)
+	if err != nil {
+		t.Fatalf("TestNewLimiter: NewLimiter(2): %s", err)
+	}
+	if err := l.Set(0); err == nil {
+		t.Errorf("TestNewLimiter: got Set(0) == nil error, want err != nil")
+	}
+	if l.Limit() != 2 {
+		t.Errorf("TestNewLimiter: got Limit() == %d after a failed Set, want 2", l.Limit())
+	}
+}
+
+// TestWithLimiter verifies the limit bounds the ItemFuncs in flight, that raising it widens dispatch while the
+// range runs, and that lowering it lets the ItemFuncs running finish.
+func TestWithLimiter(t *testing.T) {
+	t.Parallel()
+
+	l, err := NewLimiter(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var running, peak atomic.Int64
+	release := make(chan struct{})
+	fn := func(ctx context.Context, _ int, v int) (int, error) {
+		n := running.Add(1)
+		defer running.Add(-1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		<-release
+		return v, nil
+	}
+
+	// settle waits for the ItemFuncs running to reach want.
+	settle := func(want int64) {
+		t.Helper()
+		deadline := time.Now().Add(5 * time.Second)
+		for running.Load() != want {
+			if time.Now().After(deadline) {
+				t.Fatalf("TestWithLimiter: got %d ItemFuncs running, want %d", running.Load(), want)
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}
+
+	done := make(chan int)
+	go func() {
+		n := 0
+		for _, resp := range Item(t.Context(), seqOf(ints(20)...), fn, WithLimiter(l)) {
+			if resp.Err == nil {
+				n++
+			}
+		}
+		done <- n
+	}()
+
+	settle(2)
+	time.Sleep(20 * time.Millisecond)
+	if got := peak.Load(); got != 2 {
+		t.Errorf("TestWithLimiter: got %d ItemFuncs running under a limit of 2, want 2", got)
+	}
+
+	if err := l.Set(5); err != nil {
+		t.Fatal(err)
+	}
+	settle(5)
+
+	// Lowered, the five running are not interrupted, and no more start until they are down to one.
+	if err := l.Set(1); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(20 * time.Millisecond)
+	if got := l.InFlight(); got != 5 {
+		t.Errorf("TestWithLimiter: got InFlight() == %d after lowering the limit, want the 5 running", got)
+	}
+	close(release)
+
+	if n := <-done; n != 20 {
+		t.Errorf("TestWithLimiter: got %d values, want 20", n)
+	}
+	if got := peak.Load(); got != 5 {
+		t.Errorf("TestWithLimiter: got a peak of %d ItemFuncs running, want 5", got)
+	}
+	if got := l.InFlight(); got != 0 {
+		t.Errorf("TestWithLimiter: got InFlight() == %d after the range, want 0", got)
+	}
+}
+
+// TestLimiterShared verifies ranges sharing a Limiter never run more ItemFuncs between them than its limit.
+func TestLimiterShared(t *testing.T) {
+	t.Parallel()
+
+	l, err := NewLimiter(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var running, peak atomic.Int64
+	fn := func(ctx context.Context, _ int, v int) (int, error) {
+		n := running.Add(1)
+		defer running.Add(-1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		time.Sleep(100 * time.Microsecond)
+		return v, nil
+	}
+
+	const ranges = 4
+	done := make(chan struct{}, ranges)
+	for range ranges {
+		go func() {
+			for range Item(t.Context(), seqOf(ints(50)...), fn, WithLimiter(l)) {
+			}
+			done <- struct{}{}
+		}()
+	}
+	for range ranges {
+		<-done
+	}
+
+	if got := peak.Load(); got != 1 {
+		t.Errorf("TestLimiterShared: got a peak of %d ItemFuncs running across the ranges, want 1", got)
+	}
+	if got := l.InFlight(); got != 0 {
+		t.Errorf("TestLimiterShared: got InFlight() == %d after the ranges, want 0", got)
+	}
+}
@@ -0,0 +1,90 @@
+package foreach
+
+import (
+	"errors"
+	"time"
+
+	"github.com/gostdlib/base/context"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// meterName is the import path of this package, the name of the meter Item records its metrics on.
+const meterName = "github.com/gostdlib/concurrency/patterns/stream/foreach"
+
+// metrics are the OTEL instruments recorded by an Item call that has a WithName.
+type metrics struct {
+	meter metric.Meter
+	// InFlight is the number of ItemFuncs running, retries included.
+	InFlight metric.Int64UpDownCounter
+	// QueueWait is how long a pair waited, in seconds, between being read from the input and its ItemFunc
+	// starting: time spent behind the gate, the adaptive limit, WithWeight's slots, WithPriority's lookahead and
+	// the pool's workers.
+	QueueWait metric.Float64Histogram
+	// Latency is how long a pair's ItemFunc took, in seconds, including any retries.
+	Latency metric.Float64Histogram
+	// Errors is the number of pairs whose ItemFunc failed, after any retries, by the "permanent" attribute: true
+	// for an error wrapping ErrPermanent.
+	Errors metric.Int64Counter
+	// GateClosed is the time, in seconds, WithGate's gate held dispatch paused.
+	GateClosed metric.Float64Counter
+	// Held is the number of responses WithOrdered is holding for an earlier pair's.
+	Held metric.Int64Gauge
+	// AdaptiveLimit is the number of ItemFuncs WithAdaptiveConcurrency lets run at once.
+	AdaptiveLimit metric.Int64Gauge
+}
+
+func newMetrics(m metric.Meter) *metrics {
+	mets := &metrics{meter: m}
+
+	var err error
+	mets.InFlight, err = m.Int64UpDownCounter("inflight", metric.WithDescription("The number of ItemFuncs running."))
+	if err != nil {
+		panic(err)
+	}
+	mets.QueueWait, err = m.Float64Histogram("queue.wait", metric.WithDescription("How long a pair waited between being read and its ItemFunc starting."), metric.WithUnit("s"))
+	if err != nil {
+		panic(err)
+	}
+	mets.Latency, err = m.Float64Histogram("item.latency", metric.WithDescription("How long an ItemFunc took, including retries."), metric.WithUnit("s"))
+	if err != nil {
+		panic(err)
+	}
+	mets.Errors, err = m.Int64Counter("errors", metric.WithDescription("The number of pairs whose ItemFunc failed after any retries."))
+	if err != nil {
+		panic(err)
+	}
+	mets.GateClosed, err = m.Float64Counter("gate.closed", metric.WithDescription("The time the gate held dispatch paused."), metric.WithUnit("s"))
+	if err != nil {
+		panic(err)
+	}
+	mets.Held, err = m.Int64Gauge("order.held", metric.WithDescription("The number of responses held for an earlier pair's."))
+	if err != nil {
+		panic(err)
+	}
+	mets.AdaptiveLimit, err = m.Int64Gauge("adaptive.limit", metric.WithDescription("The number of ItemFuncs WithAdaptiveConcurrency lets run at once."))
+	if err != nil {
+		panic(err)
+	}
+
+	return mets
+}
+
+// permanentAttr and transientAttr label an Errors measurement with whether the error was permanent.
+var (
+	permanentAttr = metric.WithAttributes(attribute.Bool("permanent", true))
+	transientAttr = metric.WithAttributes(attribute.Bool("permanent", false))
+)
+
+// finished records a pair's ItemFunc finishing after latency with err.
+func (m *metrics) finished(ctx context.Context, latency time.Duration, err error) {
+	m.InFlight.Add(ctx, -1)
+	m.Latency.Record(ctx, latency.Seconds())
+	switch {
+	case err == nil:
+	case errors.Is(err, ErrPermanent):
+		m.Errors.Add(ctx, 1, permanentAttr)
+	default:
+		m.Errors.Add(ctx, 1, transientAttr)
+	}
+}
@@ -0,0 +1,146 @@
+package foreach
+
+import (
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"testing"
+
+	"github.com/gostdlib/base/context"
+	baseTrace "github.com/gostdlib/base/telemetry/otel/trace"
+	"github.com/kylelemons/godebug/pretty"
+	"go.opentelemetry.io/otel/codes"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestWithName(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		opts []Option
+		// want maps a metric, with its attributes, to its sum, or its count for a histogram. Gauges and
+		// gate.closed, whose values are times, are only checked to be there.
+		want map[string]int64
+	}{
+		{
+			name: "Success: unordered without retries",
+			want: map[string]int64{
+				"inflight":               0,
+				"queue.wait":             10,
+				"item.latency":           10,
+				"errors permanent=true":  1,
+				"errors permanent=false": 2,
+			},
+		},
+		{
+			name: "Success: ordered with WithGate",
+			opts: []Option{WithOrdered(), WithGate(testBoff())},
+			want: map[string]int64{
+				"inflight":              0,
+				"queue.wait":            10,
+				"item.latency":          10,
+				"errors permanent=true": 1,
+				"gate.closed":           -1,
+				"order.held":            -1,
+			},
+		},
+	}
+
+	for _, test := range tests {
+		reader := sdkmetric.NewManualReader()
+		ctx := context.SetMeterProvider(t.Context(), sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader)))
+
+		// 2 fails permanently; 3 and 4 fail once, which the gate retries.
+		var failed3, failed4 atomic.Bool
+		fn := func(ctx context.Context, _ int, v int) (int, error) {
+			switch {
+			case v == 2:
+				return 0, fmt.Errorf("bad: %w", ErrPermanent)
+			case v == 3 && !failed3.Swap(true), v == 4 && !failed4.Swap(true):
+				return 0, errors.New("unavailable")
+			}
+			return v, nil
+		}
+		opts := append([]Option{WithName("test")}, test.opts...)
+		for range Item(ctx, seqOf(ints(10)...), fn, opts...) {
+		}
+
+		var rm metricdata.ResourceMetrics
+		if err := reader.Collect(ctx, &rm); err != nil {
+			t.Fatalf("TestWithName(%s): could not collect metrics: %s", test.name, err)
+		}
+		got := map[string]int64{}
+		for _, sm := range rm.ScopeMetrics {
+			if sm.Scope.Name != meterName+"/test" {
+				continue
+			}
+			for _, m := range sm.Metrics {
+				switch data := m.Data.(type) {
+				case metricdata.Sum[int64]:
+					for _, dp := range data.DataPoints {
+						name := m.Name
+						if p, ok := dp.Attributes.Value("permanent"); ok {
+							name += fmt.Sprintf(" permanent=%t", p.AsBool())
+						}
+						got[name] += dp.Value
+					}
+				case metricdata.Histogram[float64]:
+					for _, dp := range data.DataPoints {
+						got[m.Name] += int64(dp.Count)
+					}
+				case metricdata.Sum[float64], metricdata.Gauge[int64]:
+					got[m.Name] = -1
+				}
+			}
+		}
+		if diff := pretty.Compare(test.want, got); diff != "" {
+			t.Errorf("TestWithName(%s): -want/+got:\n%s", test.name, diff)
+		}
+	}
+}
+
+func TestWithItemSpans(t *testing.T) {
+	t.Parallel()
+
+	sr := tracetest.NewSpanRecorder()
+	tracer := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(sr)).Tracer("test")
+	ctx, root := tracer.Start(t.Context(), "root")
+	// base's span package finds the tracer on the Context, not the parent span.
+	ctx = context.WithValue(ctx, baseTrace.TracerKey, tracer)
+
+	fn := func(ctx context.Context, _ int, v int) (int, error) {
+		if v == 1 {
+			return 0, errors.New("boom")
+		}
+		return v, nil
+	}
+	for range Item(ctx, seqOf(ints(3)...), fn, WithName("test"), WithItemSpans()) {
+	}
+	root.End()
+
+	// got maps each pair's span, by its key attribute, to whether it has an error status.
+	got := map[string]bool{}
+	n := 0
+	for _, s := range sr.Ended() {
+		if s.Name() != "test" {
+			continue
+		}
+		n++
+		for _, a := range s.Attributes() {
+			if a.Key == "key" {
+				got[a.Value.AsString()] = s.Status().Code == codes.Error
+			}
+		}
+	}
+	want := map[string]bool{"0": false, "1": true, "2": false}
+	if diff := pretty.Compare(want, got); diff != "" {
+		t.Errorf("TestWithItemSpans: -want/+got:\n%s", diff)
+	}
+	if n != 3 {
+		t.Errorf("TestWithItemSpans: got %d spans, want 3", n)
+	}
+}
@@ -0,0 +1,115 @@
+package foreach
+
+import (
+	"container/heap"
+	"fmt"
+
+	"github.com/gostdlib/base/context"
+)
+
+// defaultLookahead is how many pairs WithPriority reads ahead of dispatch when WithLookahead is not set.
+const defaultLookahead = 64
+
+// WithPriority dispatches the most urgent pair read so far first, by fn's result: a higher value is more urgent,
+// and pairs of equal priority go in input order. Only pairs already read from the input compete, and Item reads
+// ahead of dispatch only while dispatch is waiting (for a worker, the gate or a weight), up to WithLookahead
+// pairs (64 by default): an urgent pair jumps the queued ones, not the whole input. fn is called once per pair,
+// on the goroutine reading the input.
+//
+// WithPriority cannot be used with WithOrdered, which yields in input order. fn's K and V must be Item's, or
+// Item yields a single error Response; a nil fn is an error.
+func WithPriority[K, V any](fn func(k K, v V) int) Option {
+	return func(o options) (options, error) {
+		if fn == nil {
+			return o, fmt.Errorf("foreach.WithPriority: fn cannot be nil: %w", ErrPermanent)
+		}
+		o.priority = fn
+		return o, nil
+	}
+}
+
+// WithLookahead sets how many pairs WithPriority reads ahead of dispatch to choose the most urgent from. A
+// larger window lets urgent pairs overtake more queued ones, at the cost of holding more pairs in memory. It has
+// no effect without WithPriority. Defaults to 64; n must be > 0.
+func WithLookahead(n int) Option {
+	return func(o options) (options, error) {
+		if n < 1 {
+			return o, fmt.Errorf("foreach.WithLookahead: n must be > 0, got %d: %w", n, ErrPermanent)
+		}
+		o.lookahead = n
+		return o, nil
+	}
+}
+
+// ranked is a pair read ahead by prioritize, with its priority and its place in the input.
+type ranked[K, V any] struct {
+	in       input[K, V]
+	priority int
+	seq      int
+}
+
+// ranking is a max-heap of ranked pairs: highest priority first, then earliest in the input.
+type ranking[K, V any] []ranked[K, V]
+
+func (r ranking[K, V]) Len() int { return len(r) }
+
+func (r ranking[K, V]) Less(i, j int) bool {
+	if r[i].priority != r[j].priority {
+		return r[i].priority > r[j].priority
+	}
+	return r[i].seq < r[j].seq
+}
+
+func (r ranking[K, V]) Swap(i, j int) { r[i], r[j] = r[j], r[i] }
+
+func (r *ranking[K, V]) Push(x any) { *r = append(*r, x.(ranked[K, V])) }
+
+func (r *ranking[K, V]) Pop() any {
+	old := *r
+	x := old[len(old)-1]
+	old[len(old)-1] = ranked[K, V]{}
+	*r = old[:len(old)-1]
+	return x
+}
+
+// prioritize sits between the puller and the dispatch loop for WithPriority: it reads pairs from raw into a
+// heap while the heap is below the lookahead, and offers the heap's most urgent pair to the dispatch loop. The
+// two happen in one select, so while dispatch is taking pairs as fast as they are read nothing queues and
+// pairs go in input order; only while dispatch waits does the heap fill and the order change. It closes d.pull
+// once raw is closed and the heap is empty, or when ctx is cancelled, dropping the pairs it holds: they were
+// never dispatched.
+func (d *dispatcher[K, V, R]) prioritize(ctx context.Context, raw <-chan input[K, V]) {
+	defer close(d.pull)
+
+	fn := d.o.priority.(func(K, V) int)
+	lookahead := or(d.o.lookahead, defaultLookahead)
+	var h ranking[K, V]
+	seq := 0
+	for raw != nil || h.Len() > 0 {
+		var (
+			in  <-chan input[K, V]
+			out chan<- input[K, V]
+			top input[K, V]
+		)
+		if raw != nil && h.Len() < lookahead {
+			in = raw
+		}
+		if h.Len() > 0 {
+			out = d.pull
+			top = h[0].in
+		}
+		select {
+		case p, ok := <-in:
+			if !ok {
+				raw = nil
+				continue
+			}
+			heap.Push(&h, ranked[K, V]{in: p, priority: fn(p.k, p.v), seq: seq})
+			seq++
+		case out <- top:
+			heap.Pop(&h)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package foreach
+
+import (
+	"container/heap"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/gostdlib/base/context"
+	"github.com/kylelemons/godebug/pretty"
+)
+
+func TestRanking(t *testing.T) {
+	t.Parallel()
+
+	priorities := []int{1, 5, 3, 5, 1, 9}
+	var h ranking[int, int]
+	for i, p := range priorities {
+		heap.Push(&h, ranked[int, int]{in: input[int, int]{k: i}, priority: p, seq: i})
+	}
+	var got []int
+	for h.Len() > 0 {
+		got = append(got, heap.Pop(&h).(ranked[int, int]).in.k)
+	}
+
+	want := []int{5, 1, 3, 2, 0, 4}
+	if diff := pretty.Compare(want, got); diff != "" {
+		t.Errorf("TestRanking: -want/+got:\n%s", diff)
+	}
+}
+
+// TestPrioritize verifies pairs read ahead while dispatch waits are handed over most urgent first, and that
+// no more than the lookahead are read ahead.
+func TestPrioritize(t *testing.T) {
+	t.Parallel()
+
+	d := &dispatcher[int, int, int]{
+		o: options{
+			priority:  func(k, v int) int { return v },
+			lookahead: 4,
+		},
+		pull: make(chan input[int, int]),
+	}
+	raw := make(chan input[int, int])
+	go d.prioritize(t.Context(), raw)
+
+	// Nothing is taking from pull, so every pair sent is read ahead.
+	for i, v := range []int{2, 7, 1, 7} {
+		raw <- input[int, int]{k: i, v: v}
+	}
+	select {
+	case raw <- input[int, int]{k: 4, v: 100}:
+		t.Fatalf("TestPrioritize: read a pair past the lookahead")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	// Taking the most urgent frees a place, so the fifth pair is read ahead and overtakes the rest.
+	got := []int{(<-d.pull).k}
+	raw <- input[int, int]{k: 4, v: 100}
+	close(raw)
+	for in := range d.pull {
+		got = append(got, in.k)
+	}
+
+	want := []int{1, 4, 3, 0, 2}
+	if diff := pretty.Compare(want, got); diff != "" {
+		t.Errorf("TestPrioritize: -want/+got:\n%s", diff)
+	}
+}
+
+// TestWithPriority verifies every pair is processed under WithPriority, with a lookahead smaller than the input.
+func TestWithPriority(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.SetPool(t.Context(), context.Pool(t.Context()).Limited(t.Context(), "TestWithPriority", 2))
+	fn := func(ctx context.Context, _ int, v int) (int, error) {
+		return v, nil
+	}
+	prio := func(k, v int) int { return v % 3 }
+
+	var got []int
+	for _, resp := range Item(ctx, seqOf(ints(100)...), fn, WithPriority(prio), WithLookahead(8)) {
+		if resp.Err != nil {
+			t.Fatalf("TestWithPriority: got err == %s, want nil", resp.Err)
+		}
+		got = append(got, resp.V)
+	}
+	slices.Sort(got)
+	if diff := pretty.Compare(ints(100), got); diff != "" {
+		t.Errorf("TestWithPriority: -want/+got:\n%s", diff)
+	}
+}
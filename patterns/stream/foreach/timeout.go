@@ -0,0 +1,130 @@
+package foreach
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gostdlib/base/context"
+)
+
+// TimeoutError is the Response error of a pair whose ItemFunc did not return within WithItemTimeout's d. It is
+// also the Context cause the ItemFunc sees, via context.Cause, when its deadline passes. It does not wrap
+// context.DeadlineExceeded: a timeout is a failure of the dependency, so WithGate retries it and
+// WithCircuitBreaker and WithAdaptiveConcurrency count it. Check for it with errors.As.
+type TimeoutError struct {
+	// Timeout is the WithItemTimeout the ItemFunc ran over.
+	Timeout time.Duration
+}
+
+// Error implements error.
+func (e *TimeoutError) Error() string {
+	return fmt.Sprintf("foreach: ItemFunc did not return within %s", e.Timeout)
+}
+
+// WithItemTimeout gives every call to the ItemFunc its own deadline d after it starts. A call still running at
+// its deadline yields a *TimeoutError Response, and the worker moves on to the next pair at once: an ItemFunc
+// that ignores its Context keeps running in the background until it returns, but it no longer holds a worker.
+// Under WithGate each attempt gets its own d. d must be > 0.
+func WithItemTimeout(d time.Duration) Option {
+	return func(o options) (options, error) {
+		if d <= 0 {
+			return o, fmt.Errorf("foreach.WithItemTimeout: d must be > 0, got %s: %w", d, ErrPermanent)
+		}
+		o.timeout = d
+		return o, nil
+	}
+}
+
+// WithHedge starts a duplicate call to the ItemFunc for a pair whose call has not returned after after, and
+// another each after that up to max duplicates, and takes whichever call returns first, cancelling the Context
+// of the others. It cuts the tail latency of an ItemFunc whose slow calls are slow by chance rather than by the
+// pair, such as a read from a replicated store, and is only safe for an ItemFunc that can be run more than once
+// for the same pair. The duplicates run outside the pool's worker count, so up to max more ItemFuncs than it
+// allows may run at once. Under WithGate each attempt is hedged. after must be > 0 and max >= 1.
+func WithHedge(after time.Duration, max int) Option {
+	return func(o options) (options, error) {
+		if after <= 0 {
+			return o, fmt.Errorf("foreach.WithHedge: after must be > 0, got %s: %w", after, ErrPermanent)
+		}
+		if max < 1 {
+			return o, fmt.Errorf("foreach.WithHedge: max must be >= 1, got %d: %w", max, ErrPermanent)
+		}
+		o.hedgeAfter = after
+		o.hedgeMax = max
+		return o, nil
+	}
+}
+
+// invoke makes one call to fn, under WithItemTimeout's deadline and with WithHedge's duplicates. Without
+// either it calls fn directly. With either, every call runs on the default pool while the worker waits for the
+// first to return, so a call that outlives its deadline, or loses to a duplicate, can be left behind; its
+// Context is cancelled when invoke returns.
+func (d *dispatcher[K, V, R]) invoke(ctx context.Context, k K, v V) (R, error) {
+	if d.o.timeout == 0 && d.o.hedgeMax == 0 {
+		return d.fn(ctx, k, v)
+	}
+
+	parent := ctx
+	var cancel context.CancelFunc
+	if d.o.timeout > 0 {
+		ctx, cancel = context.WithTimeoutCause(ctx, d.o.timeout, &TimeoutError{Timeout: d.o.timeout})
+	} else {
+		ctx, cancel = context.WithCancel(ctx)
+	}
+	defer cancel()
+
+	type result struct {
+		r   R
+		err error
+	}
+	// Buffered for every call that can be started, so a call that loses never blocks on its send.
+	results := make(chan result, 1+d.o.hedgeMax)
+	start := func() {
+		// Submitted with a Context that is never cancelled so the pool never declines the call: invoke waits
+		// for a result from every call it believes started.
+		_ = d.workers.Default().Submit(context.WithoutCancel(ctx), func() {
+			r, err := d.fn(ctx, k, v)
+			results <- result{r: r, err: err}
+		})
+	}
+	start()
+
+	var hedge <-chan time.Time
+	if d.o.hedgeMax > 0 {
+		t := time.NewTicker(d.o.hedgeAfter)
+		defer t.Stop()
+		hedge = t.C
+	}
+	hedged := 0
+
+	for {
+		select {
+		case res := <-results:
+			// A call that saw its deadline pass and returned the Context's error reports the timeout.
+			if res.err != nil && timedOut(parent, ctx) {
+				res.err = context.Cause(ctx)
+			}
+			return res.r, res.err
+		case <-hedge:
+			start()
+			hedged++
+			if hedged == d.o.hedgeMax {
+				hedge = nil
+			}
+		case <-ctx.Done():
+			var zero R
+			if timedOut(parent, ctx) {
+				return zero, context.Cause(ctx)
+			}
+			return zero, parent.Err()
+		}
+	}
+}
+
+// timedOut reports whether ctx, derived from parent by invoke, ended because its WithItemTimeout deadline
+// passed rather than because parent ended.
+func timedOut(parent, ctx context.Context) bool {
+	var te *TimeoutError
+	return parent.Err() == nil && errors.As(context.Cause(ctx), &te)
+}
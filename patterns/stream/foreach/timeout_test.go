@@ -0,0 +1,160 @@
+package foreach
+
+import (
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gostdlib/base/context"
+)
+
+// TestWithItemTimeout verifies a call that misses its deadline yields a *TimeoutError, whether or not the
+// ItemFunc honors its Context, and that a call that ignores it no longer holds the worker.
+func TestWithItemTimeout(t *testing.T) {
+	t.Parallel()
+
+	release := make(chan struct{})
+	defer close(release)
+
+	tests := []struct {
+		name string
+		fn   ItemFunc[int, int, int]
+	}{
+		{
+			name: "Error: an ItemFunc that honors its Context",
+			fn: func(ctx context.Context, _ int, v int) (int, error) {
+				if v%2 == 0 {
+					return v, nil
+				}
+				<-ctx.Done()
+				return 0, ctx.Err()
+			},
+		},
+		{
+			name: "Error: an ItemFunc that ignores its Context",
+			fn: func(ctx context.Context, _ int, v int) (int, error) {
+				if v%2 == 0 {
+					return v, nil
+				}
+				<-release
+				return 0, nil
+			},
+		},
+	}
+
+	for _, test := range tests {
+		// A single slot: if a timed-out call held it, the pairs after it would never run.
+		ctx := context.SetPool(t.Context(), context.Pool(t.Context()).Limited(t.Context(), "TestWithItemTimeout", 1))
+
+		ok, timeouts := 0, 0
+		for _, resp := range Item(ctx, seqOf(ints(6)...), test.fn, WithItemTimeout(10*time.Millisecond)) {
+			var te *TimeoutError
+			switch {
+			case resp.Err == nil:
+				ok++
+			case errors.As(resp.Err, &te) && te.Timeout == 10*time.Millisecond:
+				timeouts++
+			default:
+				t.Errorf("TestWithItemTimeout(%s): got err == %v, want a *TimeoutError", test.name, resp.Err)
+			}
+		}
+		if ok != 3 || timeouts != 3 {
+			t.Errorf("TestWithItemTimeout(%s): got %d successes and %d timeouts, want 3 and 3", test.name, ok, timeouts)
+		}
+	}
+}
+
+// TestWithItemTimeoutCancel verifies a call cut short by the caller's Context reports the cancellation, not a
+// timeout.
+func TestWithItemTimeoutCancel(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(t.Context())
+	fn := func(ctx context.Context, _ int, v int) (int, error) {
+		cancel()
+		<-ctx.Done()
+		return 0, ctx.Err()
+	}
+
+	for _, resp := range Item(ctx, seqOf(1), fn, WithItemTimeout(time.Hour)) {
+		var te *TimeoutError
+		if errors.As(resp.Err, &te) || !errors.Is(resp.Err, context.Canceled) {
+			t.Errorf("TestWithItemTimeoutCancel: got err == %v, want context.Canceled", resp.Err)
+		}
+	}
+}
+
+// TestWithHedge verifies a slow call is hedged, the first call to return wins, the losers see their Context
+// cancelled, and no more than max duplicates start.
+func TestWithHedge(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		opts []Option
+		// fast is the call, counting from 1, that returns at once; the others wait on their Context.
+		fast      int64
+		wantCalls int64
+		wantErr   bool
+	}{
+		{
+			name:      "Success: the duplicate wins",
+			opts:      []Option{WithHedge(5*time.Millisecond, 1)},
+			fast:      2,
+			wantCalls: 2,
+		},
+		{
+			name:      "Success: the third call wins",
+			opts:      []Option{WithHedge(5*time.Millisecond, 3)},
+			fast:      3,
+			wantCalls: 3,
+		},
+		{
+			name:      "Error: no more than max duplicates before the timeout",
+			opts:      []Option{WithHedge(5*time.Millisecond, 2), WithItemTimeout(50 * time.Millisecond)},
+			fast:      4,
+			wantCalls: 3,
+			wantErr:   true,
+		},
+	}
+
+	for _, test := range tests {
+		var calls, cancelled atomic.Int64
+		losersDone := make(chan struct{}, 10)
+		fn := func(ctx context.Context, _ int, v int) (int, error) {
+			if calls.Add(1) == test.fast {
+				return v, nil
+			}
+			<-ctx.Done()
+			cancelled.Add(1)
+			losersDone <- struct{}{}
+			return 0, ctx.Err()
+		}
+
+		for _, resp := range Item(t.Context(), seqOf(1), fn, test.opts...) {
+			var te *TimeoutError
+			switch {
+			case test.wantErr && !errors.As(resp.Err, &te):
+				t.Errorf("TestWithHedge(%s): got err == %v, want a *TimeoutError", test.name, resp.Err)
+			case !test.wantErr && (resp.Err != nil || resp.V != 1):
+				t.Errorf("TestWithHedge(%s): got (%d, %v), want (1, nil)", test.name, resp.V, resp.Err)
+			}
+		}
+
+		losers := test.wantCalls
+		if !test.wantErr {
+			losers--
+		}
+		for range losers {
+			select {
+			case <-losersDone:
+			case <-time.After(5 * time.Second):
+				t.Fatalf("TestWithHedge(%s): a losing call's Context was not cancelled", test.name)
+			}
+		}
+		if got := calls.Load(); got != test.wantCalls {
+			t.Errorf("TestWithHedge(%s): got %d calls, want %d", test.name, got, test.wantCalls)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package foreach
+
+import (
+	"fmt"
+
+	"github.com/gostdlib/base/concurrency/sync"
+	"github.com/gostdlib/base/context"
+)
+
+// WithWeight makes a pair take fn's result in concurrency slots instead of one, so a few huge pairs cannot crowd
+// the pool: while a pair of weight 4 runs, the pool runs 3 fewer of the others. The slots are the pool's worker
+// count; a weight above it is capped to it, so a huge pair runs alone rather than never, and a weight below 1
+// counts as 1. Dispatch keeps input order: a heavy pair waits for enough slots to free rather than letting the
+// lighter pairs behind it overtake it and starve it. fn is called once per pair, on the dispatch goroutine.
+//
+// fn's K and V must be Item's, or Item yields a single error Response; a nil fn is an error.
+func WithWeight[K, V any](fn func(k K, v V) int) Option {
+	return func(o options) (options, error) {
+		if fn == nil {
+			return o, fmt.Errorf("foreach.WithWeight: fn cannot be nil: %w", ErrPermanent)
+		}
+		o.weight = fn
+		return o, nil
+	}
+}
+
+// weights counts WithWeight's slots in use. Only the dispatch loop acquires, so acquisitions are served in
+// dispatch order; the workers release. Each Item range gets its own weights. All methods are safe for
+// concurrent use.
+type weights struct {
+	mu   sync.Mutex
+	size int
+	used int
+	// freed is closed and replaced whenever slots are released; the dispatch loop blocks on the channel it
+	// observed, then re-checks.
+	freed chan struct{}
+}
+
+// newWeights returns weights with size slots.
+func newWeights(size int) *weights {
+	return &weights{size: size, freed: make(chan struct{})}
+}
+
+// clamp returns the slots a pair of weight n takes.
+func (w *weights) clamp(n int) int {
+	return min(max(n, 1), w.size)
+}
+
+// acquire blocks until n slots are free and takes them, returning the slots taken. It returns ctx.Err() if ctx
+// is cancelled first.
+func (w *weights) acquire(ctx context.Context, n int) (int, error) {
+	n = w.clamp(n)
+	for {
+		w.mu.Lock()
+		if w.used+n <= w.size {
+			w.used += n
+			w.mu.Unlock()
+			return n, nil
+		}
+		freed := w.freed
+		w.mu.Unlock()
+		select {
+		case <-freed:
+		case <-ctx.Done():
+			return 0, ctx.Err()
+		}
+	}
+}
+
+// release returns n slots taken by acquire.
+func (w *weights) release(n int) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.used -= n
+	close(w.freed)
+	w.freed = make(chan struct{})
+}
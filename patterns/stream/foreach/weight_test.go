@@ -0,0 +1,102 @@
+package foreach
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gostdlib/base/context"
+)
+
+func TestWeights(t *testing.T) {
+	t.Parallel()
+
+	w := newWeights(4)
+	for _, test := range []struct {
+		name string
+		n    int
+		want int
+	}{
+		{name: "Success: a weight below 1 takes 1 slot", n: 0, want: 1},
+		{name: "Success: a weight within the size takes it", n: 3, want: 3},
+		{name: "Success: a weight above the size takes them all", n: 10, want: 4},
+	} {
+		if got := w.clamp(test.n); got != test.want {
+			t.Errorf("TestWeights(%s): got %d slots, want %d", test.name, got, test.want)
+		}
+	}
+
+	three, err := w.acquire(t.Context(), 3)
+	if err != nil {
+		t.Fatalf("TestWeights: acquire(3): %s", err)
+	}
+
+	// Two more do not fit until the three are released.
+	acquired := make(chan int)
+	go func() {
+		n, _ := w.acquire(t.Context(), 2)
+		acquired <- n
+	}()
+	select {
+	case <-acquired:
+		t.Fatalf("TestWeights: acquire(2) returned with 3 of 4 slots in use")
+	case <-time.After(20 * time.Millisecond):
+	}
+	w.release(three)
+	if n := <-acquired; n != 2 {
+		t.Errorf("TestWeights: got acquire(2) == %d, want 2", n)
+	}
+
+	// A cancelled wait returns the Context's error.
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+	if _, err := w.acquire(ctx, 4); err == nil {
+		t.Errorf("TestWeights: got acquire on a cancelled Context == nil error, want err != nil")
+	}
+}
+
+// TestWithWeight verifies the pairs running at once never weigh more than the pool's worker count, and that a
+// pair heavier than the pool still runs.
+func TestWithWeight(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.SetPool(t.Context(), context.Pool(t.Context()).Limited(t.Context(), "TestWithWeight", 4))
+	weight := func(_ int, v int) int {
+		switch {
+		case v%10 == 0:
+			return 100
+		case v%5 == 0:
+			return 3
+		}
+		return 1
+	}
+
+	var running, peak atomic.Int64
+	fn := func(ctx context.Context, k int, v int) (int, error) {
+		w := int64(min(weight(k, v), 4))
+		n := running.Add(w)
+		defer running.Add(-w)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		time.Sleep(time.Millisecond)
+		return v, nil
+	}
+
+	n := 0
+	for _, resp := range Item(ctx, seqOf(ints(50)...), fn, WithWeight(weight)) {
+		if resp.Err != nil {
+			t.Fatalf("TestWithWeight: got err == %s, want nil", resp.Err)
+		}
+		n++
+	}
+	if n != 50 {
+		t.Errorf("TestWithWeight: got %d responses, want 50", n)
+	}
+	if got := peak.Load(); got > 4 {
+		t.Errorf("TestWithWeight: got pairs weighing %d running at once, want at most 4", got)
+	}
+}
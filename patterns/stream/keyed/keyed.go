@@ -31,13 +31,24 @@ key can never exceed one lane's throughput; that is inherent to per-key ordering
 Results arrive in completion order under each pair's input key; per-key execution order is always
 preserved regardless of delivery order. WithOrdered instead delivers results in input order across all
 keys, at the cost of buffering finished results behind a slow key. Errors arrive in-band as
-stream.Result.Err. Item is lazy:
+stream.Result.Err.
+
+WithRetry retries a failed pair with an exponential.Backoff in its lane, so the key's later pairs wait
+behind it and still run in order, while other keys' lanes keep flowing. A pair that fails for good is
+skipped by default and the key goes on with its next pair; WithPoison instead fails the key's later
+pairs with ErrPoisoned, for a changelog that must not apply past a change it could not.
+
+Stateful is Item in the actor model: each key's lane hands its StateFunc the key's state, loaded from a
+StateStore when the lane starts and saved when it retires, as the range ends and, with WithSnapshot,
+periodically, so per-key state survives idle gaps and, with a durable store, restarts. A StateFunc that
+fails leaves the state as it was, so a retried pair's changes apply once. Item is lazy:
 no work starts until the returned sequence is ranged, and breaking out of the range (or cancelling
 ctx) stops dispatch of pairs not yet started and waits for those already running.
 */
 package keyed
 
 import (
+	"errors"
 	"fmt"
 	"hash/maphash"
 	"iter"
@@ -61,6 +72,10 @@ var ErrPermanent = exponential.ErrPermanent
 // when the range ends, as a PanicError.
 var ErrTornDown = fmt.Errorf("keyed: run torn down after an ItemFunc panic: %w", ErrPermanent)
 
+// ErrPoisoned is set on a pair's Result, under WithPoison, when an earlier pair with the same partition
+// key failed for good; the pair's ItemFunc is not called. It is permanent.
+var ErrPoisoned = fmt.Errorf("keyed: key poisoned by an earlier failed pair: %w", ErrPermanent)
+
 // PanicError is the value Item re-panics with, on the consumer's goroutine, after an ItemFunc
 // panicked. Value is the original panic and Stack is the stack captured in the lane at recover time —
 // the live re-panic stack is the consumer's, not the lane's, so the captured Stack is how the origin
@@ -118,6 +133,71 @@ func call[K, V, R any](ctx context.Context, td *teardown, fn ItemFunc[K, V, R],
 	return fn(ctx, k, v)
 }
 
+// retrier is the WithRetry and WithPoison state shared by every lane of one Item range. mu guards poisoned,
+// the partition keys whose pairs have failed for good under WithPoison.
+type retrier struct {
+	boff   *exponential.Backoff
+	poison bool
+
+	mu       sync.Mutex
+	poisoned map[string]bool
+}
+
+// newRetrier returns a range's retrier, or nil if the range neither retries nor poisons. Each range gets its
+// own, so ranging Item's sequence again starts with no key poisoned.
+func newRetrier(boff *exponential.Backoff, poison bool) *retrier {
+	if boff == nil && !poison {
+		return nil
+	}
+	return &retrier{boff: boff, poison: poison, poisoned: map[string]bool{}}
+}
+
+// process runs fn on the pair it, in a lane, like call: once, or, under WithRetry, until it succeeds or fails
+// for good, the lane holding the key's later pairs behind it. Under WithPoison a pair that fails for good
+// poisons its key, and a pair whose key is poisoned fails with ErrPoisoned without running. rt may be nil.
+func process[K, V, R any](ctx context.Context, td *teardown, fn ItemFunc[K, V, R], rt *retrier, it laneItem[K, V]) (R, error) {
+	if rt == nil {
+		return call(ctx, td, fn, it.k, it.v)
+	}
+	if rt.isPoisoned(it.p) {
+		var zero R
+		return zero, fmt.Errorf("keyed: key %q: %w", it.p, ErrPoisoned)
+	}
+	if rt.boff == nil {
+		v, err := call(ctx, td, fn, it.k, it.v)
+		rt.failed(ctx, it.p, err)
+		return v, err
+	}
+
+	var v R
+	// An ItemFunc panic ends the retries: ErrTornDown is permanent.
+	err := rt.boff.Retry(ctx, func(ctx context.Context, _ exponential.Record) error {
+		var err error
+		v, err = call(ctx, td, fn, it.k, it.v)
+		return err
+	})
+	rt.failed(ctx, it.p, err)
+	return v, err
+}
+
+// isPoisoned reports whether key is poisoned.
+func (rt *retrier) isPoisoned(key string) bool {
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+	return rt.poisoned[key]
+}
+
+// failed poisons key, under WithPoison, if its pair failed for good with err. A pair cut short because the run
+// is stopping has not failed for good, so it leaves the key alone.
+func (rt *retrier) failed(ctx context.Context, key string, err error) {
+	if err == nil || !rt.poison || ctx.Err() != nil {
+		return
+	}
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+	rt.poisoned[key] = true
+}
+
 // laneBuffer is the per-lane inbox capacity. It must be > 0 so the single dispatcher can enqueue to
 // one lane and move on to others instead of blocking on a busy lane; a full inbox (a hot lane)
 // applies backpressure that pauses the dispatcher rather than growing memory without bound.
@@ -156,6 +236,12 @@ type options struct {
 	idle time.Duration
 	// ordered selects WithOrdered: deliver results in input order rather than completion order.
 	ordered bool
+	// boff is WithRetry's backoff; nil means a failed pair is not retried.
+	boff *exponential.Backoff
+	// poison selects WithPoison: a pair that fails for good fails its key's later pairs.
+	poison bool
+	// snapshot is WithSnapshot's interval; 0 means Stateful only saves a lane's state as the lane ends.
+	snapshot time.Duration
 }
 
 // validate checks option combinations that no single With* can. The two lane strategies are
@@ -204,9 +290,9 @@ func WithFixedLanes(n int) Option {
 // goroutine and inbox), so no two keys ever block each other — the actor model, where a lane owns
 // per-key state that its ItemFuncs touch without locking. A lane retires after idle with no new pair
 // (idle 0 uses a default of 500ms), freeing its goroutine; a later pair for that key starts a fresh lane, so
-// state does not survive an idle gap. Use this for bounded, long-lived keys (a session, a connection,
-// an owned shard); for high-cardinality transient keys prefer WithFixedLanes. It is mutually
-// exclusive with WithFixedLanes. idle must be >= 0.
+// state does not survive an idle gap unless Stateful keeps it in a StateStore. Use this for bounded,
+// long-lived keys (a session, a connection, an owned shard); for high-cardinality transient keys prefer
+// WithFixedLanes. It is mutually exclusive with WithFixedLanes. idle must be >= 0.
 func WithLanePerKey(idle time.Duration) Option {
 	return func(o options) (options, error) {
 		if idle < 0 {
@@ -232,6 +318,34 @@ func WithOrdered() Option {
 	}
 }
 
+// WithRetry retries a pair whose ItemFunc fails with boff, until it succeeds, fails with an error wrapping
+// ErrPermanent, or boff gives up; the Result carries the last attempt's value and error. The pair keeps its
+// lane while it retries, so the key's later pairs wait and still run in input order, and other lanes' keys keep
+// flowing. Under WithFixedLanes that includes the other keys hashed onto the retrying pair's lane, which wait
+// too; WithLanePerKey holds only the key. What happens to a key whose pair fails for good is up to WithPoison.
+// boff cannot be nil.
+func WithRetry(boff *exponential.Backoff) Option {
+	return func(o options) (options, error) {
+		if boff == nil {
+			return o, fmt.Errorf("keyed.WithRetry: boff cannot be nil: %w", ErrPermanent)
+		}
+		o.boff = boff
+		return o, nil
+	}
+}
+
+// WithPoison poisons a key once one of its pairs fails for good — after WithRetry's retries, if set: each of
+// the key's later pairs then yields a Result whose Err wraps ErrPoisoned, without its ItemFunc being called,
+// while other keys go on. Without it, a failed pair's error is yielded and the key goes on with its next pair.
+// A key stays poisoned for the rest of the range, across WithLanePerKey's idle retirement. Use it where a
+// key's pairs depend on the ones before, as the changes in a changelog do.
+func WithPoison() Option {
+	return func(o options) (options, error) {
+		o.poison = true
+		return o, nil
+	}
+}
+
 // emit carries a pair's input key alongside its result from a lane to the consumer. seq is the pair's
 // 0-based input position, used only by WithOrdered to reorder results into input order at delivery.
 type emit[K, R any] struct {
@@ -241,11 +355,13 @@ type emit[K, R any] struct {
 }
 
 // laneItem is one pair handed to a lane for processing. seq is the pair's 0-based input position,
-// carried through so WithOrdered can reorder results into input order at delivery.
+// carried through so WithOrdered can reorder results into input order at delivery. p is its partition
+// key, for WithPoison.
 type laneItem[K, V any] struct {
 	k   K
 	seq int
 	v   V
+	p   string
 }
 
 // Item runs fn over every key/value pair yielded by in, serializing pairs by the partition key that
@@ -270,14 +386,14 @@ func Item[K, V, R any](ctx context.Context, in iter.Seq2[K, V], key KeyFunc[K, V
 	}
 
 	o, err := resolveOptions(options)
+	if err == nil && o.snapshot > 0 {
+		err = fmt.Errorf("keyed.Item: WithSnapshot requires Stateful: %w", ErrPermanent)
+	}
 	if err != nil {
-		return func(yield func(K, Result[R]) bool) {
-			var zero K
-			yield(zero, Result[R]{Err: err})
-		}
+		return failed[K, R](err)
 	}
 
-	r := run[K, V, R]{ctx: ctx, in: in, key: key, fn: fn, ordered: o.ordered}
+	r := run[K, V, R]{ctx: ctx, in: in, key: key, fn: fn, ordered: o.ordered, boff: o.boff, poison: o.poison}
 	if o.perKey {
 		idle := o.idle
 		if idle <= 0 {
@@ -293,6 +409,15 @@ func Item[K, V, R any](ctx context.Context, in iter.Seq2[K, V], key KeyFunc[K, V
 	return r.fixedLanes(lanes)
 }
 
+// failed returns the sequence for a call whose options are invalid: a single Result under K's zero value
+// carrying err.
+func failed[K, R any](err error) Seq[K, R] {
+	return func(yield func(K, Result[R]) bool) {
+		var zero K
+		yield(zero, Result[R]{Err: err})
+	}
+}
+
 // run bundles one Item call's inputs so the lane-strategy methods keep a short signature. K and V
 // are the input pair types and R is the ItemFunc result type.
 type run[K, V, R any] struct {
@@ -301,12 +426,18 @@ type run[K, V, R any] struct {
 	key     KeyFunc[K, V]
 	fn      ItemFunc[K, V, R]
 	ordered bool
+	boff    *exponential.Backoff
+	poison  bool
+	// open and snapshot are Stateful's: open gives a new per-key lane its key's laneState.
+	open     func(key string) *laneState[K, V, R]
+	snapshot time.Duration
 }
 
 // deliver drains out to the consumer and is shared by both lane strategies. In completion order
 // (ordered false) it yields each emit as it arrives. In input order (ordered true) it buffers emits by
 // their seq and releases the contiguous run starting at the next-expected position, so results yield in
-// input order across keys. On a broken range it cancels the run, drains out so the lanes unblock and
+// input order across keys; an emit with a seq of -1, Stateful's failed save, belongs to no pair and is
+// yielded as it arrives. On a broken range it cancels the run, drains out so the lanes unblock and
 // the closer can close it, and re-raises any recorded panic; it also re-raises once out is exhausted.
 func deliver[K, R any](out chan emit[K, R], ordered bool, td *teardown, yield func(K, Result[R]) bool) {
 	stop := func() {
@@ -333,6 +464,13 @@ func deliver[K, R any](out chan emit[K, R], ordered bool, td *teardown, yield fu
 	pending := map[int]emit[K, R]{}
 	next := 0
 	for it := range out {
+		if it.seq < 0 {
+			if !yield(it.k, it.resp) {
+				stop()
+				return
+			}
+			continue
+		}
 		pending[it.seq] = it
 		for {
 			e, ok := pending[next]
@@ -360,6 +498,7 @@ func (r run[K, V, R]) fixedLanes(n int) Seq[K, R] {
 		defer cancel()
 
 		td := &teardown{cancel: cancel}
+		rt := newRetrier(r.boff, r.poison)
 		out := make(chan emit[K, R], n)
 		inboxes := make([]chan laneItem[K, V], n)
 
@@ -371,7 +510,7 @@ func (r run[K, V, R]) fixedLanes(n int) Seq[K, R] {
 			inbox := inboxes[i]
 			g.Go(ctx, func(ctx context.Context) error {
 				for it := range inbox {
-					v, ferr := call(ctx, td, r.fn, it.k, it.v)
+					v, ferr := process(ctx, td, r.fn, rt, it)
 					select {
 					case out <- emit[K, R]{k: it.k, seq: it.seq, resp: Result[R]{V: v, Err: ferr}}:
 					case <-ctx.Done():
@@ -405,9 +544,10 @@ func (r run[K, V, R]) fixedLanes(n int) Seq[K, R] {
 				}()
 				seq := 0
 				for k, v := range r.in {
-					idx := int(maphash.String(seed, r.key(k, v)) % uint64(n))
+					p := r.key(k, v)
+					idx := int(maphash.String(seed, p) % uint64(n))
 					select {
-					case inboxes[idx] <- laneItem[K, V]{k: k, seq: seq, v: v}:
+					case inboxes[idx] <- laneItem[K, V]{k: k, seq: seq, v: v, p: p}:
 					case <-ctx.Done():
 						return
 					}
@@ -438,10 +578,14 @@ type perLane[K, V any] struct {
 // the idle timeout. Bundling these lets the lane goroutine method keep a short signature. mu guards
 // lanes and every perLane.inflight.
 type keyReg[K, V, R any] struct {
-	fn   ItemFunc[K, V, R]
-	out  chan emit[K, R]
-	idle time.Duration
-	td   *teardown
+	fn    ItemFunc[K, V, R]
+	out   chan emit[K, R]
+	idle  time.Duration
+	td    *teardown
+	retry *retrier
+	// open and snapshot are Stateful's, as in run; open is nil for Item.
+	open     func(key string) *laneState[K, V, R]
+	snapshot time.Duration
 
 	mu    sync.Mutex
 	lanes map[string]*perLane[K, V]
@@ -458,11 +602,14 @@ func (r run[K, V, R]) perKeyLanes(idle time.Duration) Seq[K, R] {
 		defer cancel()
 
 		reg := &keyReg[K, V, R]{
-			fn:    r.fn,
-			out:   make(chan emit[K, R], runtime.NumCPU()),
-			idle:  idle,
-			td:    &teardown{cancel: cancel},
-			lanes: map[string]*perLane[K, V]{},
+			fn:       r.fn,
+			out:      make(chan emit[K, R], runtime.NumCPU()),
+			idle:     idle,
+			td:       &teardown{cancel: cancel},
+			retry:    newRetrier(r.boff, r.poison),
+			open:     r.open,
+			snapshot: r.snapshot,
+			lanes:    map[string]*perLane[K, V]{},
 		}
 		g := sync.Group{}
 		submitCtx := context.WithoutCancel(ctx)
@@ -495,7 +642,7 @@ func (r run[K, V, R]) perKeyLanes(idle time.Duration) Seq[K, R] {
 					reg.mu.Unlock()
 
 					select {
-					case lane.inbox <- laneItem[K, V]{k: k, seq: seq, v: v}:
+					case lane.inbox <- laneItem[K, V]{k: k, seq: seq, v: v, p: p}:
 					case <-ctx.Done():
 						return
 					}
@@ -524,18 +671,40 @@ func (r run[K, V, R]) perKeyLanes(idle time.Duration) Seq[K, R] {
 // runLane is one per-key lane's goroutine: it drains inbox and runs fn serially, and retires when it
 // has been idle for the idle timeout with nothing in flight. Retiring deletes the lane under mu, so a
 // dispatcher that reserved it (inflight > 0) keeps it alive and a later pair for the key gets a fresh
-// lane. It exits at once when the dispatcher closes inbox (end of input) or ctx is cancelled.
+// lane. It exits at once when the dispatcher closes inbox (end of input) or ctx is cancelled. Under
+// Stateful it runs the laneState's fn and saves the key's state on every snapshot tick, before it
+// retires, and as it exits.
 func (reg *keyReg[K, V, R]) runLane(ctx context.Context, key string, lane *perLane[K, V]) {
 	timer := time.NewTimer(reg.idle)
 	defer timer.Stop()
 
+	fn := reg.fn
+	var (
+		st   *laneState[K, V, R]
+		tick <-chan time.Time
+	)
+	if reg.open != nil {
+		st = reg.open(key)
+		fn = st.fn
+		if reg.snapshot > 0 {
+			ticker := time.NewTicker(reg.snapshot)
+			defer ticker.Stop()
+			tick = ticker.C
+		}
+	}
+	// A lane retiring has already saved; any other exit saves here.
+	defer reg.flush(ctx, key, st)
+
 	for {
 		select {
 		case it, ok := <-lane.inbox:
 			if !ok {
 				return
 			}
-			v, ferr := call(ctx, reg.td, reg.fn, it.k, it.v)
+			v, ferr := process(ctx, reg.td, fn, reg.retry, it)
+			if st != nil {
+				st.torn = st.torn || errors.Is(ferr, ErrTornDown)
+			}
 			select {
 			case reg.out <- emit[K, R]{k: it.k, seq: it.seq, resp: Result[R]{V: v, Err: ferr}}:
 			case <-ctx.Done():
@@ -545,7 +714,15 @@ func (reg *keyReg[K, V, R]) runLane(ctx context.Context, key string, lane *perLa
 			lane.inflight--
 			reg.mu.Unlock()
 			timer.Reset(reg.idle)
+		case <-tick:
+			reg.flush(ctx, key, st)
 		case <-timer.C:
+			// Save before giving up the key, so a fresh lane for it loads what this one leaves; a lane whose
+			// save failed holds on to the state and tries again after the next idle timeout.
+			if !reg.flush(ctx, key, st) {
+				timer.Reset(reg.idle)
+				continue
+			}
 			reg.mu.Lock()
 			if lane.inflight == 0 {
 				delete(reg.lanes, key)
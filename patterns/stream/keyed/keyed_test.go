@@ -1,14 +1,18 @@
 package keyed_test
 
 import (
+	"errors"
 	"fmt"
+	"slices"
 	"testing"
 	"time"
 
 	"github.com/gostdlib/base/concurrency/sync"
 	"github.com/gostdlib/base/context"
+	"github.com/gostdlib/base/retry/exponential"
 	"github.com/gostdlib/concurrency/patterns/stream"
 	"github.com/gostdlib/concurrency/patterns/stream/keyed"
+	"github.com/kylelemons/godebug/pretty"
 )
 
 // item is a keyed test payload: Key is the partition key, N the 0-based index within that key.
@@ -218,6 +222,106 @@ func TestItemErrors(t *testing.T) {
 	}
 }
 
+// TestItemRetry verifies WithRetry and WithPoison for both lane strategies. Each key's pair 1 fails its
+// first two attempts and a/2 fails for good, so a retried pair must hold its key's later pairs back and a
+// poisoned key must fail its later pairs without running them. want is each key's outcomes in input order.
+func TestItemRetry(t *testing.T) {
+	t.Parallel()
+
+	boff := exponential.Must(exponential.New(exponential.WithTesting()))
+	with := func(opts ...keyed.Option) []keyed.Option { return opts }
+
+	tests := []struct {
+		name string
+		opts []keyed.Option
+		want map[string][]string
+	}{
+		{
+			name: "Success: fixed lanes without retry",
+			want: map[string][]string{"a": {"ok", "err", "permanent", "ok"}, "b": {"ok", "err", "ok", "ok"}},
+		},
+		{
+			name: "Success: fixed lanes retry and skip",
+			opts: with(keyed.WithRetry(boff)),
+			want: map[string][]string{"a": {"ok", "ok", "permanent", "ok"}, "b": {"ok", "ok", "ok", "ok"}},
+		},
+		{
+			name: "Success: per-key lanes retry and skip",
+			opts: append(with(keyed.WithRetry(boff)), perKeyOpts()...),
+			want: map[string][]string{"a": {"ok", "ok", "permanent", "ok"}, "b": {"ok", "ok", "ok", "ok"}},
+		},
+		{
+			name: "Success: fixed lanes retry and poison",
+			opts: with(keyed.WithRetry(boff), keyed.WithPoison()),
+			want: map[string][]string{"a": {"ok", "ok", "permanent", "poisoned"}, "b": {"ok", "ok", "ok", "ok"}},
+		},
+		{
+			name: "Success: per-key lanes retry and poison",
+			opts: append(with(keyed.WithRetry(boff), keyed.WithPoison()), perKeyOpts()...),
+			want: map[string][]string{"a": {"ok", "ok", "permanent", "poisoned"}, "b": {"ok", "ok", "ok", "ok"}},
+		},
+		{
+			name: "Success: poison without retry",
+			opts: with(keyed.WithPoison(), keyed.WithOrdered()),
+			want: map[string][]string{"a": {"ok", "err", "poisoned", "poisoned"}, "b": {"ok", "err", "poisoned", "poisoned"}},
+		},
+		{
+			name: "Error: WithRetry with a nil backoff",
+			opts: with(keyed.WithRetry(nil)),
+			want: map[string][]string{"a": {"permanent"}},
+		},
+	}
+
+	for _, test := range tests {
+		var in []item
+		for n := 0; n < 4; n++ {
+			for _, k := range []string{"a", "b"} {
+				in = append(in, item{Key: k, N: n})
+			}
+		}
+
+		var mu sync.Mutex
+		attempts := map[item]int{}
+		ran := map[string][]int{}
+		fn := func(ctx context.Context, _ int, it item) (int, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			attempts[it]++
+			ran[it.Key] = append(ran[it.Key], it.N)
+			switch {
+			case it.N == 1 && attempts[it] < 3:
+				return 0, fmt.Errorf("transient %s/%d", it.Key, it.N)
+			case it.Key == "a" && it.N == 2:
+				return 0, fmt.Errorf("bad %s/%d: %w", it.Key, it.N, keyed.ErrPermanent)
+			}
+			return it.N, nil
+		}
+
+		got := map[string][]string{}
+		for k, resp := range keyed.Item(t.Context(), stream.Slice(in), itemKey, fn, test.opts...) {
+			s := "ok"
+			switch {
+			case errors.Is(resp.Err, keyed.ErrPoisoned):
+				s = "poisoned"
+			case errors.Is(resp.Err, keyed.ErrPermanent):
+				s = "permanent"
+			case resp.Err != nil:
+				s = "err"
+			}
+			got[in[k].Key] = append(got[in[k].Key], s)
+		}
+
+		if diff := pretty.Compare(test.want, got); diff != "" {
+			t.Errorf("TestItemRetry(%s): -want/+got:\n%s", test.name, diff)
+		}
+		for k, ns := range ran {
+			if !slices.IsSorted(ns) {
+				t.Errorf("TestItemRetry(%s): key %s ran pairs %v, want input order", test.name, k, ns)
+			}
+		}
+	}
+}
+
 // TestLanePerKeyReuse verifies correctness across an idle gap: a key's lane retires while the source
 // pauses longer than the idle timeout, and a fresh lane processes the key's later pairs in order. A
 // channel source drives the gap. Nothing is lost and order 0..N-1 is preserved across the retire.
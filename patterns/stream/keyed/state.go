@@ -0,0 +1,204 @@
+package keyed
+
+import (
+	"fmt"
+	"iter"
+	"time"
+
+	"github.com/gostdlib/base/concurrency/sync"
+	"github.com/gostdlib/base/context"
+)
+
+// StateStore keeps each partition key's state for Stateful between the lanes that serve the key, so the state
+// survives a lane's idle retirement and, for a store that persists it, a process restart. Its methods may be
+// called concurrently for different keys, never for the same key.
+type StateStore[S any] interface {
+	// Load returns key's saved state, or the zero S if it has none.
+	Load(ctx context.Context, key string) (S, error)
+	// Save records state as key's state.
+	Save(ctx context.Context, key string, state S) error
+}
+
+// StateFunc is Stateful's ItemFunc: it processes one key/value pair into a result, reading and changing its
+// partition key's state through state. StateFuncs for the same key never run concurrently, so state needs no
+// locking, and it must not be kept after the StateFunc returns. state points to a copy of the key's state that
+// becomes the key's state only if the StateFunc returns a nil error, so a failed call, or a failed attempt
+// under WithRetry, leaves the state as it was. The copy is shallow: a StateFunc that may fail must not change
+// what maps, slices or pointers in the state refer to, only replace them.
+type StateFunc[K, V, S, R any] func(ctx context.Context, k K, v V, state *S) (R, error)
+
+// StateError reports a StateStore failing to load or save a key's state. A failed load is the Err of the pair
+// that needed the state; a failed save is yielded by Stateful as a Result of its own under K's zero value.
+type StateError struct {
+	// Key is the partition key whose state it is.
+	Key string
+	// Op is "load" or "save".
+	Op string
+	// Err is the StateStore's error.
+	Err error
+}
+
+// Error implements error.
+func (e *StateError) Error() string {
+	return fmt.Sprintf("keyed: %s state for key %q: %s", e.Op, e.Key, e.Err)
+}
+
+// Unwrap returns the StateStore's error.
+func (e *StateError) Unwrap() error {
+	return e.Err
+}
+
+// MemStore is a StateStore that keeps state in memory: it survives idle retirement and later ranges in the
+// same process, but not a restart. It is safe for concurrent use.
+type MemStore[S any] struct {
+	mu sync.Mutex
+	m  map[string]S
+}
+
+// NewMemStore returns an empty MemStore.
+func NewMemStore[S any]() *MemStore[S] {
+	return &MemStore[S]{m: map[string]S{}}
+}
+
+// Load implements StateStore.
+func (m *MemStore[S]) Load(ctx context.Context, key string) (S, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.m[key], nil
+}
+
+// Save implements StateStore.
+func (m *MemStore[S]) Save(ctx context.Context, key string, state S) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.m[key] = state
+	return nil
+}
+
+// WithSnapshot makes Stateful save a lane's state every interval while the lane has processed pairs since its
+// last save, rather than only when it retires or the range ends, bounding what a crash can lose. Item does not
+// take it. every must be > 0.
+func WithSnapshot(every time.Duration) Option {
+	return func(o options) (options, error) {
+		if every <= 0 {
+			return o, fmt.Errorf("keyed.WithSnapshot: every must be > 0, got %s: %w", every, ErrPermanent)
+		}
+		o.snapshot = every
+		return o, nil
+	}
+}
+
+// Stateful is Item in the actor model: each partition key's lane owns the key's state, which fn gets along with
+// every pair, and store keeps the state between lanes. A lane loads its key's state from store before its first
+// pair and saves it when the lane retires after WithLanePerKey's idle timeout, when the input ends or the range
+// is stopped, and every WithSnapshot interval if set, so the state survives idle gaps and, with a store that
+// persists it, restarts. Only a pair whose StateFunc succeeds changes the state; a lane with no such pair since
+// its last save does not save again.
+//
+// Stateful always runs a lane per key, so it takes WithLanePerKey for the idle timeout but not WithFixedLanes,
+// and otherwise takes Item's options; what Item says about ranging, cancellation, panics and invalid options holds
+// for Stateful. A failed load fails the pair with a *StateError and is tried again with the key's next pair, or
+// with the next attempt under WithRetry. A failed save is yielded as a *StateError under K's zero value; a lane
+// whose save fails as it would retire stays, holding the state, and tries again after the next idle timeout.
+// A lane whose StateFunc panicked does not save. A nil in, key, store or fn panics.
+func Stateful[K, V, S, R any](ctx context.Context, in iter.Seq2[K, V], key KeyFunc[K, V], store StateStore[S], fn StateFunc[K, V, S, R], options ...Option) Seq[K, R] {
+	if in == nil {
+		panic("keyed.Stateful: in cannot be nil")
+	}
+	if key == nil {
+		panic("keyed.Stateful: key cannot be nil")
+	}
+	if store == nil {
+		panic("keyed.Stateful: store cannot be nil")
+	}
+	if fn == nil {
+		panic("keyed.Stateful: fn cannot be nil")
+	}
+
+	o, err := resolveOptions(options)
+	if err == nil && o.fixedLanes > 0 {
+		err = fmt.Errorf("keyed.Stateful: WithFixedLanes cannot be used, every key has a lane of its own: %w", ErrPermanent)
+	}
+	if err != nil {
+		return failed[K, R](err)
+	}
+
+	r := run[K, V, R]{ctx: ctx, in: in, key: key, ordered: o.ordered, boff: o.boff, poison: o.poison, snapshot: o.snapshot}
+	r.open = func(p string) *laneState[K, V, R] {
+		var (
+			s      S
+			loaded bool
+		)
+		st := &laneState[K, V, R]{}
+		st.fn = func(ctx context.Context, k K, v V) (R, error) {
+			if !loaded {
+				var err error
+				if s, err = store.Load(ctx, p); err != nil {
+					var zero R
+					return zero, &StateError{Key: p, Op: "load", Err: err}
+				}
+				loaded = true
+			}
+			// fn changes a copy, kept only if it succeeds, so a failed attempt's changes are not retried on
+			// or saved.
+			c := s
+			out, err := fn(ctx, k, v, &c)
+			if err == nil {
+				s = c
+				st.dirty = true
+			}
+			return out, err
+		}
+		st.save = func(ctx context.Context) error {
+			if !loaded {
+				return nil
+			}
+			return store.Save(ctx, p, s)
+		}
+		return st
+	}
+	idle := o.idle
+	if idle <= 0 {
+		idle = defaultIdle
+	}
+	return r.perKeyLanes(idle)
+}
+
+// laneState is a per-key lane's hold on its key's state under Stateful. fn is the lane's ItemFunc, with the
+// state bound, and save stores the state. dirty is whether a pair has changed the state since the last save
+// and torn whether fn panicked, leaving a state not worth saving. Only the lane's goroutine touches it.
+type laneState[K, V, R any] struct {
+	fn    ItemFunc[K, V, R]
+	save  func(ctx context.Context) error
+	dirty bool
+	torn  bool
+}
+
+// flush saves st's state if the lane has processed a pair since the last save, reporting a failure as a
+// *StateError for key, and reports whether the state is saved. A nil st has nothing to save.
+func (reg *keyReg[K, V, R]) flush(ctx context.Context, key string, st *laneState[K, V, R]) bool {
+	if st == nil || !st.dirty || st.torn {
+		return true
+	}
+	// Save even when the range is stopping, so the state the lane holds is not lost with it.
+	if err := reg.save(context.WithoutCancel(ctx), st); err != nil {
+		select {
+		case reg.out <- emit[K, R]{seq: -1, resp: Result[R]{Err: &StateError{Key: key, Op: "save", Err: err}}}:
+		case <-ctx.Done():
+		}
+		return false
+	}
+	st.dirty = false
+	return true
+}
+
+// save calls st.save under a recover, tearing the run down like call if the StateStore panics.
+func (reg *keyReg[K, V, R]) save(ctx context.Context, st *laneState[K, V, R]) (err error) {
+	defer func() {
+		if rec := recover(); rec != nil {
+			reg.td.onPanic(rec)
+			err = ErrTornDown
+		}
+	}()
+	return st.save(ctx)
+}
@@ -0,0 +1,293 @@
+package keyed_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gostdlib/base/concurrency/sync"
+	"github.com/gostdlib/base/context"
+	"github.com/gostdlib/base/retry/exponential"
+	"github.com/gostdlib/concurrency/patterns/stream"
+	"github.com/gostdlib/concurrency/patterns/stream/keyed"
+	"github.com/kylelemons/godebug/pretty"
+)
+
+// count is the StateFunc the Stateful tests use: it counts its key's pairs in state and returns the count.
+func count(_ context.Context, _ int, _ item, state *int) (int, error) {
+	*state++
+	return *state, nil
+}
+
+// countStore is a StateStore over a MemStore that counts its loads and saves and can be made to fail them.
+type countStore struct {
+	*keyed.MemStore[int]
+
+	mu           sync.Mutex
+	loads, saves int
+	// failLoads fails that many loads first; failSaves fails every save.
+	failLoads int
+	failSaves bool
+}
+
+func (c *countStore) Load(ctx context.Context, key string) (int, error) {
+	c.mu.Lock()
+	c.loads++
+	fail := c.loads <= c.failLoads
+	c.mu.Unlock()
+	if fail {
+		return 0, errors.New("load failed")
+	}
+	return c.MemStore.Load(ctx, key)
+}
+
+func (c *countStore) Save(ctx context.Context, key string, state int) error {
+	c.mu.Lock()
+	c.saves++
+	fail := c.failSaves
+	c.mu.Unlock()
+	if fail {
+		return errors.New("save failed")
+	}
+	return c.MemStore.Save(ctx, key, state)
+}
+
+func (c *countStore) counts() (loads, saves int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.loads, c.saves
+}
+
+// TestStateful verifies a key's state is loaded into its lane and saved when the range ends, so a second range
+// over the same store carries every key's count on from where the first left it.
+func TestStateful(t *testing.T) {
+	t.Parallel()
+
+	boff := exponential.Must(exponential.New(exponential.WithTesting()))
+
+	tests := []struct {
+		name string
+		opts []keyed.Option
+	}{
+		{name: "Success: default"},
+		{name: "Success: ordered", opts: []keyed.Option{keyed.WithOrdered()}},
+		{name: "Success: with idle timeout and snapshots", opts: []keyed.Option{keyed.WithLanePerKey(time.Second), keyed.WithSnapshot(time.Millisecond)}},
+		{name: "Success: with retry", opts: []keyed.Option{keyed.WithRetry(boff)}},
+	}
+
+	for _, test := range tests {
+		store := keyed.NewMemStore[int]()
+		var in []item
+		for n := 0; n < 3; n++ {
+			for _, k := range []string{"a", "b"} {
+				in = append(in, item{Key: k, N: n})
+			}
+		}
+
+		got := map[string][]int{}
+		for range 2 {
+			for k, resp := range keyed.Stateful(t.Context(), stream.Slice(in), itemKey, store, count, test.opts...) {
+				if resp.Err != nil {
+					t.Fatalf("TestStateful(%s): got err == %s, want nil", test.name, resp.Err)
+				}
+				got[in[k].Key] = append(got[in[k].Key], resp.V)
+			}
+		}
+
+		want := map[string][]int{"a": {1, 2, 3, 4, 5, 6}, "b": {1, 2, 3, 4, 5, 6}}
+		if diff := pretty.Compare(want, got); diff != "" {
+			t.Errorf("TestStateful(%s): -want/+got:\n%s", test.name, diff)
+		}
+		for _, k := range []string{"a", "b"} {
+			if n, _ := store.Load(t.Context(), k); n != 6 {
+				t.Errorf("TestStateful(%s): got key %s saved as %d, want 6", test.name, k, n)
+			}
+		}
+	}
+}
+
+// TestStatefulIdle verifies a key's state survives its lane retiring across an idle gap: the fresh lane loads
+// what the retired one saved and the count goes on.
+func TestStatefulIdle(t *testing.T) {
+	t.Parallel()
+
+	ctx := t.Context()
+	const idle = 30 * time.Millisecond
+	store := &countStore{MemStore: keyed.NewMemStore[int]()}
+
+	ch := make(chan item, 5)
+	context.Pool(ctx).Submit(ctx, func() {
+		defer close(ch)
+		for n := 0; n < 5; n++ {
+			ch <- item{Key: "k", N: n}
+		}
+		time.Sleep(idle + 120*time.Millisecond) // let the lane go idle and retire
+		for n := 5; n < 10; n++ {
+			ch <- item{Key: "k", N: n}
+		}
+	})
+
+	var got []int
+	for _, resp := range keyed.Stateful(ctx, stream.Chan(ctx, ch), itemKey, store, count, keyed.WithLanePerKey(idle)) {
+		if resp.Err != nil {
+			t.Fatalf("TestStatefulIdle: got err == %s, want nil", resp.Err)
+		}
+		got = append(got, resp.V)
+	}
+
+	if diff := pretty.Compare([]int{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}, got); diff != "" {
+		t.Errorf("TestStatefulIdle: -want/+got:\n%s", diff)
+	}
+	if loads, saves := store.counts(); loads != 2 || saves != 2 {
+		t.Errorf("TestStatefulIdle: got %d loads and %d saves, want 2 of each (one lane retired)", loads, saves)
+	}
+}
+
+// TestStatefulSnapshot verifies WithSnapshot saves a live lane's state without waiting for it to retire.
+func TestStatefulSnapshot(t *testing.T) {
+	t.Parallel()
+
+	ctx := t.Context()
+	store := &countStore{MemStore: keyed.NewMemStore[int]()}
+
+	ch := make(chan item)
+	context.Pool(ctx).Submit(ctx, func() {
+		defer close(ch)
+		ch <- item{Key: "k", N: 0}
+		// The lane cannot retire within the test, so only a snapshot can save it.
+		for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(time.Millisecond) {
+			if _, saves := store.counts(); saves > 0 {
+				break
+			}
+		}
+		ch <- item{Key: "k", N: 1}
+	})
+
+	opts := []keyed.Option{keyed.WithLanePerKey(time.Minute), keyed.WithSnapshot(5 * time.Millisecond)}
+	for _, resp := range keyed.Stateful(ctx, stream.Chan(ctx, ch), itemKey, store, count, opts...) {
+		if resp.Err != nil {
+			t.Fatalf("TestStatefulSnapshot: got err == %s, want nil", resp.Err)
+		}
+	}
+
+	// A snapshot after the first pair, then a save as the input ends, or a snapshot before it, after the second.
+	if _, saves := store.counts(); saves < 2 {
+		t.Errorf("TestStatefulSnapshot: got %d saves, want at least 2", saves)
+	}
+	if n, _ := store.Load(ctx, "k"); n != 2 {
+		t.Errorf("TestStatefulSnapshot: got state saved as %d, want 2", n)
+	}
+}
+
+// TestStatefulStoreErrors verifies a failed load fails the pair that needed the state and is tried again with
+// the next, and a failed save is yielded as a Result of its own.
+func TestStatefulStoreErrors(t *testing.T) {
+	t.Parallel()
+
+	store := &countStore{MemStore: keyed.NewMemStore[int](), failLoads: 1, failSaves: true}
+	in := []item{{Key: "k", N: 0}, {Key: "k", N: 1}, {Key: "k", N: 2}}
+
+	var got []string
+	for _, resp := range keyed.Stateful(t.Context(), stream.Slice(in), itemKey, store, count) {
+		var serr *keyed.StateError
+		switch {
+		case errors.As(resp.Err, &serr):
+			got = append(got, serr.Op+" "+serr.Key)
+		case resp.Err != nil:
+			t.Fatalf("TestStatefulStoreErrors: got err == %s, want a *StateError", resp.Err)
+		default:
+			got = append(got, "ok")
+		}
+	}
+
+	if diff := pretty.Compare([]string{"load k", "ok", "ok", "save k"}, got); diff != "" {
+		t.Errorf("TestStatefulStoreErrors: -want/+got:\n%s", diff)
+	}
+}
+
+// TestStatefulFailedCall verifies a StateFunc's changes to the state are kept only when it succeeds: a failed
+// call, or a failed attempt under WithRetry, leaves the state as it was.
+func TestStatefulFailedCall(t *testing.T) {
+	t.Parallel()
+
+	boff := exponential.Must(exponential.New(exponential.WithTesting()))
+
+	tests := []struct {
+		name string
+		opts []keyed.Option
+		// want is the count each pair yields; 0 for the pair that fails.
+		want []int
+		// wantSaved is the count saved when the range ends.
+		wantSaved int
+	}{
+		{name: "Success: a retried pair counts once", opts: []keyed.Option{keyed.WithRetry(boff)}, want: []int{1, 2, 3}, wantSaved: 3},
+		{name: "Error: a failed pair does not count", want: []int{1, 0, 2}, wantSaved: 2},
+	}
+
+	for _, test := range tests {
+		store := keyed.NewMemStore[int]()
+		in := []item{{Key: "k", N: 0}, {Key: "k", N: 1}, {Key: "k", N: 2}}
+
+		// fn counts like count, but its first call for N 1 fails after changing the state.
+		failed := false
+		fn := func(ctx context.Context, k int, it item, state *int) (int, error) {
+			*state++
+			if it.N == 1 && !failed {
+				failed = true
+				return 0, errors.New("failed")
+			}
+			return *state, nil
+		}
+
+		var got []int
+		for _, resp := range keyed.Stateful(t.Context(), stream.Slice(in), itemKey, store, fn, append(test.opts, keyed.WithOrdered())...) {
+			got = append(got, resp.V)
+		}
+
+		if diff := pretty.Compare(test.want, got); diff != "" {
+			t.Errorf("TestStatefulFailedCall(%s): -want/+got:\n%s", test.name, diff)
+		}
+		if n, _ := store.Load(t.Context(), "k"); n != test.wantSaved {
+			t.Errorf("TestStatefulFailedCall(%s): got state saved as %d, want %d", test.name, n, test.wantSaved)
+		}
+	}
+}
+
+// TestStatefulOptions verifies the options Stateful and Item reject are reported as a single permanent error.
+func TestStatefulOptions(t *testing.T) {
+	t.Parallel()
+
+	fn := func(ctx context.Context, _ int, it item) (int, error) { return it.N, nil }
+	in := []item{{Key: "k"}}
+
+	tests := []struct {
+		name string
+		seq  keyed.Seq[int, int]
+	}{
+		{
+			name: "Error: Stateful with WithFixedLanes",
+			seq:  keyed.Stateful(t.Context(), stream.Slice(in), itemKey, keyed.NewMemStore[int](), count, keyed.WithFixedLanes(2)),
+		},
+		{
+			name: "Error: WithSnapshot of 0",
+			seq:  keyed.Stateful(t.Context(), stream.Slice(in), itemKey, keyed.NewMemStore[int](), count, keyed.WithSnapshot(0)),
+		},
+		{
+			name: "Error: Item with WithSnapshot",
+			seq:  keyed.Item(t.Context(), stream.Slice(in), itemKey, fn, keyed.WithSnapshot(time.Second)),
+		},
+	}
+
+	for _, test := range tests {
+		n := 0
+		for _, resp := range test.seq {
+			n++
+			if !errors.Is(resp.Err, keyed.ErrPermanent) {
+				t.Errorf("TestStatefulOptions(%s): got err == %v, want ErrPermanent", test.name, resp.Err)
+			}
+		}
+		if n != 1 {
+			t.Errorf("TestStatefulOptions(%s): got %d Results, want 1", test.name, n)
+		}
+	}
+}
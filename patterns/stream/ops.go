@@ -0,0 +1,390 @@
+package stream
+
+import (
+	"iter"
+	"time"
+
+	"github.com/gostdlib/base/concurrency/sync"
+	"github.com/gostdlib/base/context"
+)
+
+// The operators below are lazy: nothing is read from a sequence until the one returned is ranged, and breaking out
+// of the range stops reading. Operators that only look at one pair at a time (Transform, Filter, Take, Skip,
+// Distinct, Zip) run in the ranging goroutine and need no Context. Operators that wait on a clock or on several
+// sequences at once (Batch, Tumbling, Sliding, Merge) read their input on the default pool and take a Context:
+// cancelling it ends the iteration, and so does breaking out of it. Their input is abandoned at its next yield,
+// so an input that can block forever between yields, such as Chan, should be given the same Context.
+//
+// None of the operators look inside a value, so a Result carrying an Err passes through like any other value;
+// TransformResult is Transform for a sequence of Results that passes errors through untouched.
+
+// Pair is a key and value from an iter.Seq2, for operators that gather several of them into one value.
+type Pair[K, V any] struct {
+	K K
+	V V
+}
+
+// Transform returns an iter.Seq2 of every key in seq with its value replaced by what fn returns for the pair. It
+// is what other stream libraries call Map; stream.Map adapts a Go map.
+func Transform[K, V, R any](seq iter.Seq2[K, V], fn func(K, V) R) iter.Seq2[K, R] {
+	return func(yield func(K, R) bool) {
+		for k, v := range seq {
+			if !yield(k, fn(k, v)) {
+				return
+			}
+		}
+	}
+}
+
+// TransformResult is Transform for a sequence of Results: fn is called with the value of every Result without an
+// Err, and what it returns becomes the new Result. A Result with an Err is passed through without calling fn.
+func TransformResult[K, V, R any](seq iter.Seq2[K, Result[V]], fn func(K, V) (R, error)) iter.Seq2[K, Result[R]] {
+	return func(yield func(K, Result[R]) bool) {
+		for k, r := range seq {
+			var out Result[R]
+			if r.Err != nil {
+				out.Err = r.Err
+			} else {
+				out.V, out.Err = fn(k, r.V)
+			}
+			if !yield(k, out) {
+				return
+			}
+		}
+	}
+}
+
+// Filter returns an iter.Seq2 of the pairs in seq that keep returns true for. To let errors flow past a Filter
+// over Results, have keep return true for a Result with an Err.
+func Filter[K, V any](seq iter.Seq2[K, V], keep func(K, V) bool) iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		for k, v := range seq {
+			if !keep(k, v) {
+				continue
+			}
+			if !yield(k, v) {
+				return
+			}
+		}
+	}
+}
+
+// Take returns an iter.Seq2 of the first n pairs in seq. seq is not read past the nth pair.
+func Take[K, V any](seq iter.Seq2[K, V], n int) iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		if n <= 0 {
+			return
+		}
+		i := 0
+		for k, v := range seq {
+			if !yield(k, v) {
+				return
+			}
+			i++
+			if i == n {
+				return
+			}
+		}
+	}
+}
+
+// Skip returns an iter.Seq2 of the pairs in seq after the first n.
+func Skip[K, V any](seq iter.Seq2[K, V], n int) iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		i := 0
+		for k, v := range seq {
+			if i < n {
+				i++
+				continue
+			}
+			if !yield(k, v) {
+				return
+			}
+		}
+	}
+}
+
+// Distinct returns an iter.Seq2 of the pairs in seq whose key, as key computes it, has not been seen before: the
+// first pair with a key is kept and the rest are dropped. Every key seen is remembered until the iteration ends.
+func Distinct[K, V any, D comparable](seq iter.Seq2[K, V], key func(K, V) D) iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		seen := map[D]struct{}{}
+		for k, v := range seq {
+			d := key(k, v)
+			if _, ok := seen[d]; ok {
+				continue
+			}
+			seen[d] = struct{}{}
+			if !yield(k, v) {
+				return
+			}
+		}
+	}
+}
+
+// Zip returns an iter.Seq2 that pairs the nth pair of a with the nth pair of b. It ends when either one ends.
+func Zip[K1, V1, K2, V2 any](a iter.Seq2[K1, V1], b iter.Seq2[K2, V2]) iter.Seq2[Pair[K1, V1], Pair[K2, V2]] {
+	return func(yield func(Pair[K1, V1], Pair[K2, V2]) bool) {
+		next, stop := iter.Pull2(b)
+		defer stop()
+
+		for k1, v1 := range a {
+			k2, v2, ok := next()
+			if !ok {
+				return
+			}
+			if !yield(Pair[K1, V1]{K: k1, V: v1}, Pair[K2, V2]{K: k2, V: v2}) {
+				return
+			}
+		}
+	}
+}
+
+// Merge returns an iter.Seq2 of every pair in every one of seqs, in the order they arrive. Each sequence is read
+// on its own goroutine, so pairs from different sequences interleave and a slow sequence does not hold up the
+// others. It ends once every sequence has ended or ctx is cancelled.
+func Merge[K, V any](ctx context.Context, seqs ...iter.Seq2[K, V]) iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+
+		out := make(chan Pair[K, V])
+		g := sync.Group{}
+		for _, seq := range seqs {
+			g.Go(ctx, func(ctx context.Context) error {
+				send(ctx, seq, out)
+				return nil
+			})
+		}
+		// The closer runs on the default pool, never the Context's pool, which may be Limited: it only waits, so a
+		// limited slot it held would be one fewer for real work. It waits on a WithoutCancel ctx so out is not
+		// closed under a sender that has yet to see ctx is done.
+		_ = context.Pool(ctx).Default().Submit(context.WithoutCancel(ctx), func() {
+			_ = g.Wait(context.WithoutCancel(ctx))
+			close(out)
+		})
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case p, ok := <-out:
+				if !ok {
+					return
+				}
+				if !yield(p.K, p.V) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// Batch returns an iter.Seq2 of the pairs in seq gathered into batches, keyed by a zero-based batch index. A
+// batch is yielded once it holds size pairs or, if wait > 0, once wait has passed since its first pair arrived,
+// whichever comes first; size <= 0 means only wait ends a batch. The last batch is yielded when seq ends, however
+// few pairs it holds. A batch that is still being gathered when ctx is cancelled is dropped. Either size or wait
+// must be > 0.
+func Batch[K, V any](ctx context.Context, seq iter.Seq2[K, V], size int, wait time.Duration) iter.Seq2[int, []Pair[K, V]] {
+	if size <= 0 && wait <= 0 {
+		panic("stream.Batch: size or wait must be > 0")
+	}
+	return func(yield func(int, []Pair[K, V]) bool) {
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+
+		in := feed(ctx, seq)
+		var (
+			batch   []Pair[K, V]
+			i       int
+			timer   *time.Timer
+			timeout <-chan time.Time
+		)
+		defer func() {
+			if timer != nil {
+				timer.Stop()
+			}
+		}()
+		flush := func() bool {
+			if timer != nil {
+				timer.Stop()
+				timeout = nil
+			}
+			b := batch
+			batch = nil
+			if !yield(i, b) {
+				return false
+			}
+			i++
+			return true
+		}
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-timeout:
+				timeout = nil
+				if !flush() {
+					return
+				}
+			case p, ok := <-in:
+				if !ok {
+					if len(batch) > 0 && ctx.Err() == nil {
+						flush()
+					}
+					return
+				}
+				batch = append(batch, p)
+				if len(batch) == 1 && wait > 0 {
+					if timer == nil {
+						timer = time.NewTimer(wait)
+					} else {
+						timer.Reset(wait)
+					}
+					timeout = timer.C
+				}
+				if size > 0 && len(batch) >= size {
+					if !flush() {
+						return
+					}
+				}
+			}
+		}
+	}
+}
+
+// Tumbling returns an iter.Seq2 of the pairs in seq gathered into back-to-back windows of length size, by the time
+// each pair arrives. Each window is keyed by the time it started; the first starts when the iteration does. Every
+// pair is in exactly one window, and a window no pair arrived in is not yielded. The window open when seq ends is
+// yielded then. size must be > 0.
+func Tumbling[K, V any](ctx context.Context, seq iter.Seq2[K, V], size time.Duration) iter.Seq2[time.Time, []Pair[K, V]] {
+	if size <= 0 {
+		panic("stream.Tumbling: size must be > 0")
+	}
+	return func(yield func(time.Time, []Pair[K, V]) bool) {
+		t := time.NewTicker(size)
+		defer t.Stop()
+		windows(ctx, seq, size, size, t.C, time.Now)(yield)
+	}
+}
+
+// Sliding returns an iter.Seq2 of the pairs in seq gathered into overlapping windows of length size, by the time
+// each pair arrives: every period, the pairs that arrived in the last size are yielded, keyed by the time that
+// window started. A pair is in every window that covers its arrival, and a window no pair arrived in is not
+// yielded. When seq ends, a last window is yielded if a pair arrived since the one before it. size and every must
+// be > 0.
+func Sliding[K, V any](ctx context.Context, seq iter.Seq2[K, V], size, every time.Duration) iter.Seq2[time.Time, []Pair[K, V]] {
+	if size <= 0 || every <= 0 {
+		panic("stream.Sliding: size and every must be > 0")
+	}
+	return func(yield func(time.Time, []Pair[K, V]) bool) {
+		t := time.NewTicker(every)
+		defer t.Stop()
+		windows(ctx, seq, size, every, t.C, time.Now)(yield)
+	}
+}
+
+// stamped is a pair and when it arrived.
+type stamped[K, V any] struct {
+	p  Pair[K, V]
+	at time.Time
+}
+
+// windows yields a window of length size ending at each tick, holding the pairs from seq that arrived in it. Pairs
+// are kept only as long as a later window can still cover them. The clock is passed in so tests can drive it.
+func windows[K, V any](ctx context.Context, seq iter.Seq2[K, V], size, every time.Duration, tick <-chan time.Time, now func() time.Time) iter.Seq2[time.Time, []Pair[K, V]] {
+	return func(yield func(time.Time, []Pair[K, V]) bool) {
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+
+		in := feed(ctx, seq)
+		var (
+			held []stamped[K, V]
+			// fresh is whether a pair arrived since the last window was yielded.
+			fresh bool
+			// last is when the last window ended, or when the iteration started.
+			last = now()
+		)
+		// emit yields the window ending at end and forgets the pairs the next window, ending a period later,
+		// will not cover.
+		emit := func(end time.Time) bool {
+			start := end.Add(-size)
+			if every == size {
+				// Tumbling windows start where the last one ended. A tick dropped because the consumer was slow
+				// then makes one longer window rather than losing the pairs of the window it would have ended.
+				start = last
+			}
+			last = end
+			var w []Pair[K, V]
+			for _, s := range held {
+				if s.at.After(start) && !s.at.After(end) {
+					w = append(w, s.p)
+				}
+			}
+			next := end.Add(every).Add(-size)
+			if every == size {
+				next = end
+			}
+			keep := held[:0]
+			for _, s := range held {
+				if s.at.After(next) {
+					keep = append(keep, s)
+				}
+			}
+			clear(held[len(keep):])
+			held = keep
+			fresh = false
+
+			if len(w) == 0 {
+				return true
+			}
+			return yield(start, w)
+		}
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case end := <-tick:
+				if !emit(end) {
+					return
+				}
+			case p, ok := <-in:
+				if !ok {
+					if fresh && ctx.Err() == nil {
+						emit(now())
+					}
+					return
+				}
+				held = append(held, stamped[K, V]{p: p, at: now()})
+				fresh = true
+			}
+		}
+	}
+}
+
+// feed ranges seq on the default pool and sends every pair on the returned channel, which it closes when seq ends
+// or ctx is cancelled.
+func feed[K, V any](ctx context.Context, seq iter.Seq2[K, V]) <-chan Pair[K, V] {
+	out := make(chan Pair[K, V])
+	// The reader runs on the default pool, never the Context's pool, which may be Limited: it lives as long as
+	// seq, so a limited slot it held would be one fewer for real work. Submitted on a WithoutCancel ctx so Submit
+	// never declines; it honors cancellation through the captured ctx.
+	_ = context.Pool(ctx).Default().Submit(context.WithoutCancel(ctx), func() {
+		defer close(out)
+		send(ctx, seq, out)
+	})
+	return out
+}
+
+// send sends every pair in seq on out until seq ends or ctx is cancelled.
+func send[K, V any](ctx context.Context, seq iter.Seq2[K, V], out chan<- Pair[K, V]) {
+	for k, v := range seq {
+		select {
+		case out <- Pair[K, V]{K: k, V: v}:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
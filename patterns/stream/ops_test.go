@@ -0,0 +1,325 @@
+package stream
+
+import (
+	"errors"
+	"iter"
+	"slices"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gostdlib/base/context"
+	"github.com/kylelemons/godebug/pretty"
+)
+
+func TestOperators(t *testing.T) {
+	t.Parallel()
+
+	in := Slice([]int{1, 2, 2, 3, 4, 4, 5})
+
+	tests := []struct {
+		name string
+		seq  iter.Seq2[int, int]
+		want []pair[int, int]
+	}{
+		{
+			name: "Success: Transform replaces every value",
+			seq:  Transform(Take(in, 3), func(k, v int) int { return v * 10 }),
+			want: []pair[int, int]{{0, 10}, {1, 20}, {2, 20}},
+		},
+		{
+			name: "Success: Filter keeps the pairs keep returns true for",
+			seq:  Filter(in, func(k, v int) bool { return v%2 == 0 }),
+			want: []pair[int, int]{{1, 2}, {2, 2}, {4, 4}, {5, 4}},
+		},
+		{
+			name: "Success: Take yields the first n pairs",
+			seq:  Take(in, 2),
+			want: []pair[int, int]{{0, 1}, {1, 2}},
+		},
+		{
+			name: "Success: Take with n <= 0 yields nothing",
+			seq:  Take(in, 0),
+			want: nil,
+		},
+		{
+			name: "Success: Skip yields the pairs after the first n",
+			seq:  Skip(in, 5),
+			want: []pair[int, int]{{5, 4}, {6, 5}},
+		},
+		{
+			name: "Success: Distinct keeps the first pair with each key",
+			seq:  Distinct(in, func(k, v int) int { return v }),
+			want: []pair[int, int]{{0, 1}, {1, 2}, {3, 3}, {4, 4}, {6, 5}},
+		},
+		{
+			name: "Success: operators compose",
+			seq:  Take(Skip(Distinct(in, func(k, v int) int { return v }), 1), 2),
+			want: []pair[int, int]{{1, 2}, {3, 3}},
+		},
+	}
+
+	for _, test := range tests {
+		if diff := pretty.Compare(test.want, collect(test.seq)); diff != "" {
+			t.Errorf("TestOperators(%s): -want/+got:\n%s", test.name, diff)
+		}
+	}
+}
+
+func TestTakeStopsReading(t *testing.T) {
+	t.Parallel()
+
+	read := 0
+	seq := func(yield func(int, int) bool) {
+		for i := 0; ; i++ {
+			read++
+			if !yield(i, i) {
+				return
+			}
+		}
+	}
+	for range Take(seq, 3) {
+	}
+	if read != 3 {
+		t.Errorf("TestTakeStopsReading: got %d pairs read, want 3", read)
+	}
+}
+
+func TestTransformResult(t *testing.T) {
+	t.Parallel()
+
+	boom := errors.New("boom")
+	in := Slice([]Result[string]{{V: "1"}, {Err: boom}, {V: "x"}})
+
+	results := collectResults(TransformResult(in, func(k int, v string) (int, error) {
+		return strconv.Atoi(v)
+	}))
+	want := []got[int, int]{{K: 0, V: 1}, {K: 1, Err: true}, {K: 2, Err: true}}
+	if diff := pretty.Compare(want, results); diff != "" {
+		t.Errorf("TestTransformResult: -want/+got:\n%s", diff)
+	}
+}
+
+func TestZip(t *testing.T) {
+	t.Parallel()
+
+	var got []pair[Pair[int, string], Pair[string, int]]
+	for a, b := range Zip(Slice([]string{"a", "b", "c"}), Map(map[string]int{"x": 1})) {
+		got = append(got, pair[Pair[int, string], Pair[string, int]]{a, b})
+	}
+	want := []pair[Pair[int, string], Pair[string, int]]{{Pair[int, string]{0, "a"}, Pair[string, int]{"x", 1}}}
+	if diff := pretty.Compare(want, got); diff != "" {
+		t.Errorf("TestZip: -want/+got:\n%s", diff)
+	}
+}
+
+func TestMerge(t *testing.T) {
+	t.Parallel()
+
+	var got []int
+	for _, v := range Merge(t.Context(), Slice([]int{1, 2}), Slice([]int{3}), Slice([]int{4, 5, 6})) {
+		got = append(got, v)
+	}
+	slices.Sort(got)
+	if diff := pretty.Compare([]int{1, 2, 3, 4, 5, 6}, got); diff != "" {
+		t.Errorf("TestMerge: -want/+got:\n%s", diff)
+	}
+
+	// Breaking out early must not leave a reader blocked on a send.
+	endless := func(yield func(int, int) bool) {
+		for i := 0; yield(i, i); i++ {
+		}
+	}
+	for range Merge(t.Context(), endless, endless) {
+		break
+	}
+}
+
+func TestBatch(t *testing.T) {
+	t.Parallel()
+
+	var got [][]Pair[int, int]
+	for _, b := range Batch(t.Context(), Slice([]int{10, 11, 12, 13, 14}), 2, 0) {
+		got = append(got, b)
+	}
+	want := [][]Pair[int, int]{{{0, 10}, {1, 11}}, {{2, 12}, {3, 13}}, {{4, 14}}}
+	if diff := pretty.Compare(want, got); diff != "" {
+		t.Errorf("TestBatch(by size): -want/+got:\n%s", diff)
+	}
+}
+
+// TestBatchWait verifies a batch that does not fill is yielded once wait passes. The source holds back its last
+// pair until the first batch arrives, so only the timer can have ended that batch.
+func TestBatchWait(t *testing.T) {
+	t.Parallel()
+
+	first := make(chan struct{})
+	seq := func(yield func(int, int) bool) {
+		if !yield(0, 0) || !yield(1, 1) {
+			return
+		}
+		<-first
+		yield(2, 2)
+	}
+
+	var got [][]Pair[int, int]
+	for i, b := range Batch(t.Context(), seq, 10, 10*time.Millisecond) {
+		got = append(got, b)
+		if i == 0 {
+			close(first)
+		}
+	}
+	want := [][]Pair[int, int]{{{0, 0}, {1, 1}}, {{2, 2}}}
+	if diff := pretty.Compare(want, got); diff != "" {
+		t.Errorf("TestBatchWait: -want/+got:\n%s", diff)
+	}
+}
+
+// testClock is a clock for windows that tells the test every time it is read, so the test knows a pair has been
+// stamped before it moves time on.
+type testClock struct {
+	t    time.Time
+	read chan struct{}
+}
+
+func (c *testClock) now() time.Time {
+	// Read the time before telling the test, which moves it on as soon as it hears.
+	t := c.t
+	c.read <- struct{}{}
+	return t
+}
+
+// window is a collected window.
+type window struct {
+	Start time.Duration
+	Pairs []Pair[int, string]
+}
+
+func TestWindows(t *testing.T) {
+	t.Parallel()
+
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	// step is something that happens to the windows: a pair arrives at a time, or the clock ticks at a time.
+	type step struct {
+		at   time.Duration
+		v    string
+		tick bool
+	}
+
+	tests := []struct {
+		name  string
+		size  time.Duration
+		every time.Duration
+		steps []step
+		want  []window
+	}{
+		{
+			name:  "Success: tumbling windows hold each pair once and skip empty windows",
+			size:  10 * time.Second,
+			every: 10 * time.Second,
+			steps: []step{
+				{at: 1 * time.Second, v: "a"},
+				{at: 9 * time.Second, v: "b"},
+				{at: 10 * time.Second, tick: true},
+				{at: 20 * time.Second, tick: true},
+				{at: 25 * time.Second, v: "c"},
+			},
+			want: []window{
+				{Start: 0, Pairs: []Pair[int, string]{{0, "a"}, {1, "b"}}},
+				{Start: 20 * time.Second, Pairs: []Pair[int, string]{{2, "c"}}},
+			},
+		},
+		{
+			name:  "Success: a dropped tick makes one longer tumbling window",
+			size:  10 * time.Second,
+			every: 10 * time.Second,
+			steps: []step{
+				{at: 1 * time.Second, v: "a"},
+				{at: 15 * time.Second, v: "b"},
+				{at: 20 * time.Second, tick: true},
+			},
+			want: []window{
+				{Start: 0, Pairs: []Pair[int, string]{{0, "a"}, {1, "b"}}},
+			},
+		},
+		{
+			name:  "Success: sliding windows hold every pair they cover",
+			size:  10 * time.Second,
+			every: 5 * time.Second,
+			steps: []step{
+				{at: 1 * time.Second, v: "a"},
+				{at: 5 * time.Second, tick: true},
+				{at: 7 * time.Second, v: "b"},
+				{at: 10 * time.Second, tick: true},
+				{at: 15 * time.Second, tick: true},
+				{at: 20 * time.Second, tick: true},
+			},
+			want: []window{
+				{Start: -5 * time.Second, Pairs: []Pair[int, string]{{0, "a"}}},
+				{Start: 0, Pairs: []Pair[int, string]{{0, "a"}, {1, "b"}}},
+				{Start: 5 * time.Second, Pairs: []Pair[int, string]{{1, "b"}}},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		clk := &testClock{t: start, read: make(chan struct{}, 1)}
+		tick := make(chan time.Time)
+		c := make(chan string)
+		ctx, cancel := context.WithCancel(t.Context())
+
+		var got []window
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			for s, w := range windows(ctx, Chan(ctx, c), test.size, test.every, tick, clk.now) {
+				got = append(got, window{Start: s.Sub(start), Pairs: w})
+			}
+		}()
+
+		<-clk.read // The iteration starting.
+		for _, s := range test.steps {
+			clk.t = start.Add(s.at)
+			if s.tick {
+				tick <- clk.t
+				continue
+			}
+			c <- s.v
+			<-clk.read // The pair being stamped.
+		}
+		close(c)
+		// The last window, if a pair arrived after the last tick, reads the clock once more.
+		go func() {
+			for range clk.read {
+			}
+		}()
+		<-done
+		cancel()
+		close(clk.read)
+
+		if diff := pretty.Compare(test.want, got); diff != "" {
+			t.Errorf("TestWindows(%s): -want/+got:\n%s", test.name, diff)
+		}
+	}
+}
+
+func TestWindowsPublic(t *testing.T) {
+	t.Parallel()
+
+	// Tumbling and Sliding only wire a real ticker to windows; check that a source that ends yields its pairs.
+	var got []Pair[int, int]
+	for _, w := range Tumbling(t.Context(), Slice([]int{1, 2}), time.Hour) {
+		got = append(got, w...)
+	}
+	if diff := pretty.Compare([]Pair[int, int]{{0, 1}, {1, 2}}, got); diff != "" {
+		t.Errorf("TestWindowsPublic(Tumbling): -want/+got:\n%s", diff)
+	}
+
+	got = nil
+	for _, w := range Sliding(t.Context(), Slice([]int{1, 2}), time.Hour, time.Minute) {
+		got = append(got, w...)
+	}
+	if diff := pretty.Compare([]Pair[int, int]{{0, 1}, {1, 2}}, got); diff != "" {
+		t.Errorf("TestWindowsPublic(Sliding): -want/+got:\n%s", diff)
+	}
+}
@@ -0,0 +1,48 @@
+package pipeline
+
+import (
+	"go.opentelemetry.io/otel/metric"
+)
+
+// meterName is the import path of this package. A Pipeline's name is appended to this to namespace its metrics.
+const meterName = "github.com/gostdlib/concurrency/patterns/stream/pipeline"
+
+// stageMetrics are the OTEL instruments a Pipeline's stages record, each labeled with the stage's name in the
+// "stage" attribute.
+type stageMetrics struct {
+	meter metric.Meter
+	// Pairs is the number of calls to a stage's function. A stage that retries (foreach.WithGate) counts every
+	// attempt.
+	Pairs metric.Int64Counter
+	// Errors is the number of calls to a stage's function that returned an error.
+	Errors metric.Int64Counter
+	// Passed is the number of pairs that failed in an earlier stage and passed through this one without a call.
+	Passed metric.Int64Counter
+	// Latency is how long a call to a stage's function took, in seconds. For a Keyed stage this includes waiting
+	// for a slot in the stage's share of the budget.
+	Latency metric.Float64Histogram
+}
+
+func newStageMetrics(m metric.Meter) *stageMetrics {
+	mets := &stageMetrics{meter: m}
+
+	var err error
+	mets.Pairs, err = m.Int64Counter("stage.pairs", metric.WithDescription("The number of calls to a stage's function."))
+	if err != nil {
+		panic(err)
+	}
+	mets.Errors, err = m.Int64Counter("stage.errors", metric.WithDescription("The number of calls to a stage's function that failed."))
+	if err != nil {
+		panic(err)
+	}
+	mets.Passed, err = m.Int64Counter("stage.passed", metric.WithDescription("The number of pairs that failed in an earlier stage and passed through."))
+	if err != nil {
+		panic(err)
+	}
+	mets.Latency, err = m.Float64Histogram("stage.latency", metric.WithDescription("How long a call to a stage's function took."), metric.WithUnit("s"))
+	if err != nil {
+		panic(err)
+	}
+
+	return mets
+}
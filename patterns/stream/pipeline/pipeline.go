@@ -0,0 +1,339 @@
+/*
+Package pipeline composes the parallel stream stages — foreach, keyed and fanout — into one lazily run graph.
+Chaining foreach.Item into keyed.Item by hand works, but each stage then sizes its own pool, is cancelled on its
+own, and is measured (if at all) on its own. A Pipeline gives every stage the same three things:
+
+  - A concurrency budget drawn from one pool. New takes a name and a budget, and each stage reserves part of it.
+    A stage's ItemFuncs run on a pool Limited to its share, which is itself Limited to the budget and to the
+    Context's pool, so the pipeline as a whole never runs more than budget ItemFuncs at once.
+  - End-to-end cancellation. Every stage runs on the Pipeline's Context; Cancel (or cancelling the Context New
+    was given) stops dispatch in every stage at once, and breaking out of the range over the last stage stops
+    them all the same way.
+  - Per-stage metrics. Each stage records its pairs, errors, passed-through errors and latency on the
+    Pipeline's meter, labeled with the stage's name, and each stage's pool records its own pool metrics.
+
+Stages are typed: a Stage[K, V] is a sequence of Results, and each stage function takes the Stage before it and
+returns the next one, so a mismatch between stages is a compile error:
+
+	p := pipeline.New(ctx, "ingest", 16)
+	defer p.Cancel()
+
+	parsed := pipeline.ForEach(pipeline.From(p, stream.Lines(p.Context(), r)), "parse", 4, parse)
+	applied := pipeline.Keyed(parsed, "apply", 8, byAccount, apply)
+	written := pipeline.FanOut(applied, "write", 4, write)
+
+	if err := stream.FirstErr(written.All()); err != nil {
+		// Handle the error.
+	}
+
+Nothing runs until the last stage is ranged. A pair that failed in one stage is not given to the next: its
+error passes through every later stage, unchanged and without being retried, to the range at the end.
+*/
+package pipeline
+
+import (
+	"errors"
+	"fmt"
+	"iter"
+	"strings"
+	"time"
+
+	"github.com/gostdlib/base/concurrency/sync"
+	"github.com/gostdlib/base/concurrency/worker"
+	"github.com/gostdlib/base/context"
+	"github.com/gostdlib/base/retry/exponential"
+	"github.com/gostdlib/concurrency/patterns/stream"
+	"github.com/gostdlib/concurrency/patterns/stream/fanout"
+	"github.com/gostdlib/concurrency/patterns/stream/foreach"
+	"github.com/gostdlib/concurrency/patterns/stream/keyed"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// Pipeline holds what the stages of one pipeline share: the Context they run on, the concurrency budget they
+// reserve their share of, and the metrics they record. Build one with New.
+type Pipeline struct {
+	// ctx is the Context every stage runs on. Its pool is the caller's pool Limited to the budget.
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	mu sync.Mutex
+	// free is the part of the budget no stage has reserved yet.
+	free int
+
+	metrics *stageMetrics
+}
+
+// New returns a Pipeline named name whose stages together run at most budget ItemFuncs at once, on a pool
+// Limited to budget (and named name) derived from ctx's pool. The name also namespaces the Pipeline's metrics.
+// An empty name, a budget < 1 or a budget above the Limit of ctx's pool panics. Call Cancel once the Pipeline is
+// no longer needed.
+func New(ctx context.Context, name string, budget int) *Pipeline {
+	if name == "" {
+		panic("pipeline.New: name cannot be empty")
+	}
+	if budget < 1 {
+		panic("pipeline.New: cannot have a budget < 1")
+	}
+	if limit := context.Pool(ctx).Limit(); limit != 0 && limit < budget {
+		panic(fmt.Sprintf("pipeline.New: budget %d exceeds the Context pool's limit of %d", budget, limit))
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	ctx = context.SetPool(ctx, context.Pool(ctx).Limited(ctx, name, budget))
+	return &Pipeline{
+		ctx:     ctx,
+		cancel:  cancel,
+		free:    budget,
+		metrics: newStageMetrics(context.MeterProvider(ctx).Meter(meterName + "/" + name)),
+	}
+}
+
+// Context returns the Context the stages run on. Give it to a source that can block, such as stream.Chan, so
+// Cancel releases the source as well as the stages.
+func (p *Pipeline) Context() context.Context {
+	return p.ctx
+}
+
+// Cancel cancels the Pipeline's Context, which stops dispatch in every stage. Pairs already running finish, and
+// a range over the last stage ends once they have been delivered.
+func (p *Pipeline) Cancel() {
+	p.cancel()
+}
+
+// reserve takes size of the free budget for the stage name and returns a pool Limited to it. Every stage is
+// given a share of its own, rather than all of them competing for the whole budget, because a foreach Worker
+// holds its slot while it hands a result to the stage after it: stages competing for the same slots could fill
+// them all with Workers waiting on a stage that has none left to run on.
+func (p *Pipeline) reserve(fn, name string, size int) *worker.Pool {
+	if name == "" {
+		panic(fmt.Sprintf("pipeline.%s: name cannot be empty", fn))
+	}
+	if size < 1 {
+		panic(fmt.Sprintf("pipeline.%s(%s): cannot have a size < 1", fn, name))
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if size > p.free {
+		panic(fmt.Sprintf("pipeline.%s(%s): size %d exceeds the %d left of the Pipeline's budget", fn, name, size, p.free))
+	}
+	p.free -= size
+	return context.Pool(p.ctx).Limited(p.ctx, name, size)
+}
+
+// Stage is one stage of a Pipeline: a lazy sequence of Results under each pair's key. Start a Pipeline with From
+// or FromResults, add stages with ForEach, Keyed and FanOut, and range the last one with All.
+type Stage[K, V any] struct {
+	p   *Pipeline
+	seq iter.Seq2[K, stream.Result[V]]
+}
+
+// All returns the Stage's sequence. Ranging it runs this stage and every stage before it; breaking out of the
+// range stops them all. Range it once: ranging it again processes the input again.
+func (s Stage[K, V]) All() iter.Seq2[K, stream.Result[V]] {
+	return s.seq
+}
+
+// From starts p with seq as its source. A nil p or seq panics.
+func From[K, V any](p *Pipeline, seq iter.Seq2[K, V]) Stage[K, V] {
+	if p == nil {
+		panic("pipeline.From: p cannot be nil")
+	}
+	if seq == nil {
+		panic("pipeline.From: seq cannot be nil")
+	}
+	return FromResults(p, func(yield func(K, stream.Result[V]) bool) {
+		for k, v := range seq {
+			if !yield(k, stream.Result[V]{V: v}) {
+				return
+			}
+		}
+	})
+}
+
+// FromResults starts p with a source that can fail, such as stream.JSONL. A Result with an Err passes through
+// every stage to the end. A nil p or seq panics.
+func FromResults[K, V any](p *Pipeline, seq iter.Seq2[K, stream.Result[V]]) Stage[K, V] {
+	if p == nil {
+		panic("pipeline.FromResults: p cannot be nil")
+	}
+	if seq == nil {
+		panic("pipeline.FromResults: seq cannot be nil")
+	}
+	return Stage[K, V]{p: p, seq: seq}
+}
+
+// ForEach adds a stage named name that runs fn on every pair in parallel with foreach.Item, on size of the
+// Pipeline's budget. options are passed to foreach.Item; with foreach.WithStopOnErr an error passed through from
+// an earlier stage stops this stage just like one from fn. An empty name, a size < 1 or more than is left of the
+// budget, or a nil fn panics.
+func ForEach[K, V, R any](s Stage[K, V], name string, size int, fn foreach.ItemFunc[K, V, R], options ...foreach.Option) Stage[K, R] {
+	if fn == nil {
+		panic("pipeline.ForEach: fn cannot be nil")
+	}
+	pool := s.p.reserve("ForEach", name, size)
+	ctx := context.SetPool(s.p.ctx, pool)
+
+	return Stage[K, R]{p: s.p, seq: unpass(foreach.Item(ctx, s.seq, wrap(s.p, name, fn), options...))}
+}
+
+// Keyed adds a stage named name that runs fn on every pair with keyed.Item, so pairs with the same key from
+// key run one at a time and in order. keyed runs each lane on a goroutine of its own, so the stage also runs
+// each call to fn on size of the Pipeline's budget, which bounds it however many lanes options ask for. An error
+// passed through from an earlier stage goes through a lane of its own, never one of key's, so it neither waits
+// behind a key's work nor is retried or poisons a key under keyed.WithRetry and keyed.WithPoison. An empty name,
+// a size < 1 or more than is left of the budget, or a nil key or fn panics.
+func Keyed[K, V, R any](s Stage[K, V], name string, size int, key keyed.KeyFunc[K, V], fn keyed.ItemFunc[K, V, R], options ...keyed.Option) Stage[K, R] {
+	if key == nil {
+		panic("pipeline.Keyed: key cannot be nil")
+	}
+	if fn == nil {
+		panic("pipeline.Keyed: fn cannot be nil")
+	}
+	pool := s.p.reserve("Keyed", name, size)
+
+	wrapped := wrap(s.p, name, func(ctx context.Context, k K, v V) (R, error) {
+		return onPool(ctx, pool, func() (R, error) { return fn(ctx, k, v) })
+	})
+	// A passed error is carried out in the value rather than returned, so keyed sees the pair succeed and
+	// neither retries it nor poisons the lane with it.
+	carried := func(ctx context.Context, k K, r stream.Result[V]) (stream.Result[R], error) {
+		out, err := wrapped(ctx, k, r)
+		if p, ok := err.(*passed); ok {
+			return stream.Result[R]{Err: p.err}, nil
+		}
+		return stream.Result[R]{V: out}, err
+	}
+	keyFn := func(k K, r stream.Result[V]) string {
+		if r.Err != nil {
+			return passedKey
+		}
+		return partition(key(k, r.V))
+	}
+	return Stage[K, R]{p: s.p, seq: uncarry(keyed.Item(s.p.ctx, s.seq, keyFn, carried, options...))}
+}
+
+// passedKey is the partition key of every pair a Keyed stage passes an error through for. partition never returns
+// it, so those pairs never share a lane with a key's pairs.
+const passedKey = "\x00"
+
+// partition returns the partition key for key: key itself, unless it starts with a NUL, which gets another in
+// front. Every key still has a partition of its own, and none of them is passedKey.
+func partition(key string) string {
+	if strings.HasPrefix(key, "\x00") {
+		return "\x00" + key
+	}
+	return key
+}
+
+// uncarry yields the Result a Keyed stage's ItemFunc carried out in its value, or keyed's own error.
+func uncarry[K, R any](seq iter.Seq2[K, stream.Result[stream.Result[R]]]) iter.Seq2[K, stream.Result[R]] {
+	return func(yield func(K, stream.Result[R]) bool) {
+		for k, r := range seq {
+			out := r.V
+			if r.Err != nil {
+				out = stream.Result[R]{Err: r.Err}
+			}
+			if !yield(k, out) {
+				return
+			}
+		}
+	}
+}
+
+// FanOut adds a stage named name that runs w on every pair in parallel for its side effects, like
+// fanout.Limited, on size of the Pipeline's budget. Unlike fanout.Limited it is a Stage: each pair yields a
+// Result whose Err is w's error (or one passed through from an earlier stage), so ranging the stage is how to
+// wait for it and how to learn what failed. An invalid option, an empty name, a size < 1 or more than is left
+// of the budget, or a nil w panics.
+func FanOut[K, V any](s Stage[K, V], name string, size int, w fanout.Worker[K, V], options ...fanout.Option) Stage[K, struct{}] {
+	if w == nil {
+		panic("pipeline.FanOut: w cannot be nil")
+	}
+	opts := make([]foreach.Option, 0, len(options))
+	for _, o := range options {
+		if o == nil {
+			panic("pipeline.FanOut: cannot have a nil Option")
+		}
+		opt, err := o()
+		if err != nil {
+			panic(err)
+		}
+		opts = append(opts, opt)
+	}
+
+	return ForEach(s, name, size, func(ctx context.Context, k K, v V) (struct{}, error) {
+		return struct{}{}, w(ctx, k, v)
+	}, opts...)
+}
+
+// onPool runs fn on pool and waits for it. If pool declines fn because ctx is cancelled, fn does not run and
+// ctx's error is returned.
+func onPool[R any](ctx context.Context, pool *worker.Pool, fn func() (R, error)) (R, error) {
+	var (
+		r    R
+		err  error
+		done = make(chan struct{})
+	)
+	if !pool.Submit(ctx, func() {
+		defer close(done)
+		r, err = fn()
+	}) {
+		return r, ctx.Err()
+	}
+	<-done
+	return r, err
+}
+
+// passed carries an error from an earlier stage through a stage's ItemFunc without calling the stage's function.
+// It also unwraps to ErrPermanent, so a stage that retries (foreach.WithGate) gives it up at once, and unpass
+// takes it off again before the stage yields it.
+type passed struct {
+	err error
+}
+
+func (p *passed) Error() string {
+	return p.err.Error()
+}
+
+func (p *passed) Unwrap() []error {
+	return []error{p.err, exponential.ErrPermanent}
+}
+
+// wrap adapts fn to a stage's ItemFunc over Results: a Result with an Err is passed through without calling fn,
+// and every call to fn is measured under the stage's name.
+func wrap[K, V, R any](p *Pipeline, name string, fn func(ctx context.Context, k K, v V) (R, error)) func(ctx context.Context, k K, r stream.Result[V]) (R, error) {
+	attr := metric.WithAttributes(attribute.String("stage", name))
+	return func(ctx context.Context, k K, r stream.Result[V]) (R, error) {
+		if r.Err != nil {
+			p.metrics.Passed.Add(ctx, 1, attr)
+			var zero R
+			return zero, &passed{err: r.Err}
+		}
+
+		start := time.Now()
+		out, err := fn(ctx, k, r.V)
+		p.metrics.Latency.Record(ctx, time.Since(start).Seconds(), attr)
+		p.metrics.Pairs.Add(ctx, 1, attr)
+		if err != nil {
+			p.metrics.Errors.Add(ctx, 1, attr)
+		}
+		return out, err
+	}
+}
+
+// unpass replaces an error passed through a stage with the error it carries.
+func unpass[K, R any](seq iter.Seq2[K, stream.Result[R]]) iter.Seq2[K, stream.Result[R]] {
+	return func(yield func(K, stream.Result[R]) bool) {
+		for k, r := range seq {
+			var p *passed
+			if errors.As(r.Err, &p) {
+				r.Err = p.err
+			}
+			if !yield(k, r) {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,346 @@
+package pipeline
+
+import (
+	"errors"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gostdlib/base/concurrency/sync"
+	"github.com/gostdlib/base/context"
+	"github.com/gostdlib/base/retry/exponential"
+	"github.com/gostdlib/concurrency/patterns/stream"
+	"github.com/gostdlib/concurrency/patterns/stream/fanout"
+	"github.com/gostdlib/concurrency/patterns/stream/foreach"
+	"github.com/gostdlib/concurrency/patterns/stream/keyed"
+
+	"github.com/kylelemons/godebug/pretty"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+// build is a three stage pipeline over in: parse each string as an int, add up the ints by parity, and write
+// each running total to written. parse keeps input order so the running totals are added up in input order.
+func build(p *Pipeline, in []string, written *sync.ShardedMap[int, int]) Stage[int, struct{}] {
+	parsed := ForEach(From(p, stream.Slice(in)), "parse", 2, func(ctx context.Context, k int, v string) (int, error) {
+		return strconv.Atoi(v)
+	}, foreach.WithOrdered())
+
+	// Keyed runs each parity one at a time, so the running totals need no lock.
+	totals := map[string]int{}
+	summed := Keyed(parsed, "sum", 2,
+		func(k int, v int) string { return strconv.Itoa(v % 2) },
+		func(ctx context.Context, k int, v int) (int, error) {
+			key := strconv.Itoa(v % 2)
+			totals[key] += v
+			return totals[key], nil
+		},
+		keyed.WithFixedLanes(2),
+	)
+
+	return FanOut(summed, "write", 1, func(ctx context.Context, k int, v int) error {
+		if v > 100 {
+			return errors.New("too big")
+		}
+		written.Set(k, v)
+		return nil
+	})
+}
+
+func TestPipeline(t *testing.T) {
+	t.Parallel()
+
+	p := New(t.Context(), "test", 5)
+	defer p.Cancel()
+
+	written := &sync.ShardedMap[int, int]{}
+	in := []string{"1", "x", "2", "3", "200", "4"}
+
+	errs := map[int]error{}
+	for k, r := range build(p, in, written).All() {
+		if r.Err != nil {
+			errs[k] = r.Err
+		}
+	}
+
+	// The pairs are summed by parity in input order: odd 1, 1+3; even 2, 2+200 (too big), 2+200+4.
+	got := map[int]int{}
+	for k, v := range written.All() {
+		got[k] = v
+	}
+	if diff := pretty.Compare(map[int]int{0: 1, 2: 2, 3: 4}, got); diff != "" {
+		t.Errorf("TestPipeline(written): -want/+got:\n%s", diff)
+	}
+
+	if len(errs) != 3 {
+		t.Fatalf("TestPipeline: got errors for %d pairs, want 3: %v", len(errs), errs)
+	}
+	// The parse error passed through the later stages unchanged.
+	var numErr *strconv.NumError
+	if !errors.As(errs[1], &numErr) || errs[1].Error() != numErr.Error() {
+		t.Errorf("TestPipeline: got errs[1] == %v, want the strconv.NumError from parse", errs[1])
+	}
+	for _, k := range []int{4, 5} {
+		if errs[k] == nil || errs[k].Error() != "too big" {
+			t.Errorf("TestPipeline: got errs[%d] == %v, want too big", k, errs[k])
+		}
+	}
+}
+
+func TestNewPanics(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name  string
+		build func()
+	}{
+		{
+			name:  "Error: empty pipeline name",
+			build: func() { New(t.Context(), "", 1) },
+		},
+		{
+			name:  "Error: budget < 1",
+			build: func() { New(t.Context(), "test", 0) },
+		},
+		{
+			name: "Error: budget above the Context pool's limit",
+			build: func() {
+				ctx := context.SetPool(t.Context(), context.Pool(t.Context()).Limited(t.Context(), "", 2))
+				New(ctx, "test", 3)
+			},
+		},
+		{
+			name: "Error: empty stage name",
+			build: func() {
+				ForEach(From(New(t.Context(), "test", 1), stream.Slice([]int{1})), "", 1, double)
+			},
+		},
+		{
+			name: "Error: stage size < 1",
+			build: func() {
+				ForEach(From(New(t.Context(), "test", 1), stream.Slice([]int{1})), "double", 0, double)
+			},
+		},
+		{
+			name: "Error: stages reserve more than the budget",
+			build: func() {
+				s := ForEach(From(New(t.Context(), "test", 3), stream.Slice([]int{1})), "double", 2, double)
+				Keyed(s, "again", 2, func(k, v int) string { return "" }, keyed.ItemFunc[int, int, int](double))
+			},
+		},
+		{
+			name: "Error: invalid FanOut option",
+			build: func() {
+				FanOut(From(New(t.Context(), "test", 1), stream.Slice([]int{1})), "write", 1,
+					func(ctx context.Context, k, v int) error { return nil }, fanout.WithGate(nil))
+			},
+		},
+	}
+
+	for _, test := range tests {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("TestNewPanics(%s): got no panic, want one", test.name)
+				}
+			}()
+			test.build()
+		}()
+	}
+}
+
+func double(ctx context.Context, k, v int) (int, error) {
+	return v * 2, nil
+}
+
+// TestBudget verifies each stage runs no more ItemFuncs at once than its share of the budget, however many
+// lanes a Keyed stage has.
+func TestBudget(t *testing.T) {
+	t.Parallel()
+
+	// busy reports how many calls are running at once into peak.
+	busy := func(running, peak *atomic.Int64) func(ctx context.Context, k, v int) (int, error) {
+		return func(ctx context.Context, k, v int) (int, error) {
+			n := running.Add(1)
+			defer running.Add(-1)
+			for {
+				p := peak.Load()
+				if n <= p || peak.CompareAndSwap(p, n) {
+					break
+				}
+			}
+			time.Sleep(time.Millisecond)
+			return v, nil
+		}
+	}
+
+	var running1, peak1, running2, peak2 atomic.Int64
+	p := New(t.Context(), "test", 3)
+	defer p.Cancel()
+
+	in := make([]int, 50)
+	s := ForEach(From(p, stream.Slice(in)), "each", 2, busy(&running1, &peak1))
+	s = Keyed(s, "keyed", 1, func(k, v int) string { return strconv.Itoa(k) }, busy(&running2, &peak2), keyed.WithFixedLanes(8))
+	n := 0
+	for _, r := range s.All() {
+		if r.Err != nil {
+			t.Fatalf("TestBudget: got err == %s, want err == nil", r.Err)
+		}
+		n++
+	}
+
+	if n != len(in) {
+		t.Errorf("TestBudget: got %d results, want %d", n, len(in))
+	}
+	if got := peak1.Load(); got > 2 {
+		t.Errorf("TestBudget: ForEach ran %d at once, want at most 2", got)
+	}
+	if got := peak2.Load(); got > 1 {
+		t.Errorf("TestBudget: Keyed ran %d at once, want at most 1", got)
+	}
+}
+
+// TestPassedNotRetried verifies an error from an earlier stage is neither given to a later stage's function nor
+// retried by its gate, and comes out as the same error.
+func TestPassedNotRetried(t *testing.T) {
+	t.Parallel()
+
+	boom := errors.New("boom")
+	p := New(t.Context(), "test", 1)
+	defer p.Cancel()
+
+	calls := 0
+	src := FromResults(p, stream.Slice([]stream.Result[int]{{V: 1}, {Err: boom}}))
+	s := ForEach(src, "each", 1, func(ctx context.Context, k, v int) (int, error) {
+		calls++
+		return v, nil
+	}, foreach.WithGate(exponential.Must(exponential.New(exponential.WithTesting()))))
+
+	var errs []error
+	for _, r := range s.All() {
+		if r.Err != nil {
+			errs = append(errs, r.Err)
+		}
+	}
+	if calls != 1 {
+		t.Errorf("TestPassedNotRetried: got %d calls, want 1", calls)
+	}
+	if len(errs) != 1 || errs[0] != boom {
+		t.Errorf("TestPassedNotRetried: got errs == %v, want [boom]", errs)
+	}
+}
+
+// TestKeyedPassed verifies a Keyed stage passes an error from an earlier stage through without sharing the lane
+// of a key, even the empty one, and without poisoning anything under keyed.WithPoison.
+func TestKeyedPassed(t *testing.T) {
+	t.Parallel()
+
+	boom, fail := errors.New("boom"), errors.New("fail")
+	p := New(t.Context(), "test", 1)
+	defer p.Cancel()
+
+	src := FromResults(p, stream.Slice([]stream.Result[int]{{Err: boom}, {V: 1}, {V: 2}, {Err: boom}}))
+	s := Keyed(src, "keyed", 1, func(k, v int) string { return "" }, func(ctx context.Context, k, v int) (int, error) {
+		if v == 1 {
+			return 0, fail
+		}
+		return v, nil
+	}, keyed.WithOrdered(), keyed.WithPoison())
+
+	var got []string
+	for _, r := range s.All() {
+		switch {
+		case errors.Is(r.Err, keyed.ErrPoisoned):
+			got = append(got, "poisoned")
+		case r.Err != nil:
+			got = append(got, r.Err.Error())
+		default:
+			got = append(got, strconv.Itoa(r.V))
+		}
+	}
+	if diff := pretty.Compare([]string{"boom", "fail", "poisoned", "boom"}, got); diff != "" {
+		t.Errorf("TestKeyedPassed: -want/+got:\n%s", diff)
+	}
+}
+
+func TestCancel(t *testing.T) {
+	t.Parallel()
+
+	ch := make(chan int)
+	p := New(t.Context(), "test", 1)
+	s := ForEach(From(p, stream.Chan(p.Context(), ch)), "double", 1, double)
+
+	// Nothing ever closes ch, so only Cancel can end the range.
+	done := make(chan struct{})
+	var got []int
+	go func() {
+		defer close(done)
+		for _, r := range s.All() {
+			got = append(got, r.V)
+		}
+	}()
+	ch <- 1
+	ch <- 2
+	p.Cancel()
+
+	select {
+	case <-done:
+	case <-time.After(15 * time.Second):
+		t.Fatal("TestCancel: the range did not end after Cancel")
+	}
+	if len(got) > 2 {
+		t.Errorf("TestCancel: got %v, want at most [2 4]", got)
+	}
+}
+
+func TestStageMetrics(t *testing.T) {
+	reader := sdkmetric.NewManualReader()
+	ctx := context.SetMeterProvider(t.Context(), sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader)))
+
+	p := New(ctx, "test", 5)
+	defer p.Cancel()
+	for range build(p, []string{"1", "x", "2", "3", "200", "4"}, &sync.ShardedMap[int, int]{}).All() {
+	}
+
+	var rm metricdata.ResourceMetrics
+	if err := reader.Collect(ctx, &rm); err != nil {
+		t.Fatalf("TestStageMetrics: could not collect metrics: %s", err)
+	}
+	got := map[string]int64{}
+	for _, sm := range rm.ScopeMetrics {
+		if sm.Scope.Name != meterName+"/test" {
+			continue
+		}
+		for _, item := range sm.Metrics {
+			switch data := item.Data.(type) {
+			case metricdata.Sum[int64]:
+				for _, dp := range data.DataPoints {
+					stage, _ := dp.Attributes.Value("stage")
+					got[item.Name+" "+stage.AsString()] += dp.Value
+				}
+			case metricdata.Histogram[float64]:
+				for _, dp := range data.DataPoints {
+					stage, _ := dp.Attributes.Value("stage")
+					got[item.Name+" "+stage.AsString()] += int64(dp.Count)
+				}
+			}
+		}
+	}
+
+	want := map[string]int64{
+		"stage.pairs parse":   6,
+		"stage.errors parse":  1,
+		"stage.latency parse": 6,
+		"stage.pairs sum":     5,
+		"stage.passed sum":    1,
+		"stage.latency sum":   5,
+		"stage.pairs write":   5,
+		"stage.errors write":  2,
+		"stage.passed write":  1,
+		"stage.latency write": 5,
+	}
+	if diff := pretty.Compare(want, got); diff != "" {
+		t.Errorf("TestStageMetrics: -want/+got:\n%s", diff)
+	}
+}
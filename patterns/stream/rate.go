@@ -0,0 +1,239 @@
+package stream
+
+import (
+	"errors"
+	"iter"
+	"time"
+
+	"github.com/gostdlib/base/concurrency/sync"
+	"github.com/gostdlib/base/context"
+)
+
+// Limiter is a token bucket that paces work to a rate with a burst. The bucket holds up to burst tokens and
+// refills at rate tokens a second; each pair (or each call to Wait) takes one token, waiting for it to refill if
+// the bucket is empty. A Limiter is safe for concurrent use, so one Limiter shared by several sequences, or by a
+// sequence and the ItemFuncs downstream of it, paces them all to the same rate.
+//
+// With WithAdaptive the rate also reacts to errors passed to Report (or seen by Observe): an error halves the
+// rate, down to a floor, and a success raises it by a fixed step, back up to the rate it was made with. This is
+// additive-increase/multiplicative-decrease, the scheme TCP uses to find what a network will carry, applied to
+// whatever is downstream of the Limiter.
+type Limiter struct {
+	mu sync.Mutex
+	// max is the rate the Limiter was made with, in tokens a second. rate never goes above it.
+	max float64
+	// rate is the current rate in tokens a second. It only differs from max when adaptive.
+	rate float64
+	// burst is the most tokens the bucket holds.
+	burst float64
+	// tokens is the number of tokens in the bucket as of last. It goes negative while callers are waiting on
+	// tokens they have already been promised.
+	tokens float64
+	// last is when tokens was last brought up to date.
+	last time.Time
+	// lastDecrease is when Report last lowered rate.
+	lastDecrease time.Time
+
+	opts limitOptions
+	// now is time.Now, replaced in tests.
+	now func() time.Time
+}
+
+// limitOptions are the options for NewLimiter.
+type limitOptions struct {
+	// adaptive makes Report move the rate. Default false: Report does nothing.
+	adaptive bool
+	// min is the floor an adaptive Limiter's rate is lowered to, in tokens a second.
+	min float64
+	// step is how much a success raises an adaptive Limiter's rate, in tokens a second.
+	step float64
+	// throttled says whether an error passed to Report should lower the rate. Default nil: every error does.
+	throttled func(err error) bool
+}
+
+// LimitOption is an option for NewLimiter.
+type LimitOption func(o limitOptions) (limitOptions, error)
+
+// WithAdaptive makes the Limiter adjust its rate from what is passed to Report. Each error halves the rate, but
+// not below min, and not more than once a second, so a burst of failures from work that was already in flight
+// counts as a single signal. Each success raises the rate by step, but not above the rate the Limiter was made
+// with. min and step are in tokens a second, and min must not be more than the Limiter's rate.
+func WithAdaptive(min, step float64) LimitOption {
+	return func(o limitOptions) (limitOptions, error) {
+		if min <= 0 {
+			return o, errors.New("stream.WithAdaptive: min must be > 0")
+		}
+		if step <= 0 {
+			return o, errors.New("stream.WithAdaptive: step must be > 0")
+		}
+		o.adaptive = true
+		o.min = min
+		o.step = step
+		return o, nil
+	}
+}
+
+// WithThrottledOn limits the errors that lower an adaptive Limiter's rate to those fn returns true for, such as
+// an HTTP 429 or a quota error; any other error is treated like a success. By default every error lowers the
+// rate. It has no effect without WithAdaptive.
+func WithThrottledOn(fn func(err error) bool) LimitOption {
+	return func(o limitOptions) (limitOptions, error) {
+		if fn == nil {
+			return o, errors.New("stream.WithThrottledOn: fn cannot be nil")
+		}
+		o.throttled = fn
+		return o, nil
+	}
+}
+
+// NewLimiter returns a Limiter that allows rate tokens a second with bursts of up to burst tokens. The bucket
+// starts full, so the first burst tokens are taken without waiting.
+func NewLimiter(rate float64, burst int, options ...LimitOption) (*Limiter, error) {
+	if rate <= 0 {
+		return nil, errors.New("stream.NewLimiter: rate must be > 0")
+	}
+	if burst < 1 {
+		return nil, errors.New("stream.NewLimiter: burst must be >= 1")
+	}
+
+	opts := limitOptions{}
+	for _, o := range options {
+		var err error
+		opts, err = o(opts)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if opts.adaptive && opts.min > rate {
+		return nil, errors.New("stream.NewLimiter: WithAdaptive min cannot be more than rate")
+	}
+
+	return &Limiter{
+		max:    rate,
+		rate:   rate,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		opts:   opts,
+		now:    time.Now,
+	}, nil
+}
+
+// Rate returns the current rate in tokens a second. Without WithAdaptive this is always the rate the Limiter was
+// made with.
+func (l *Limiter) Rate() float64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.rate
+}
+
+// Wait takes a token, blocking until one is available. It returns ctx.Err() if ctx is cancelled first, in which
+// case the token is given back for the next caller.
+func (l *Limiter) Wait(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	d := l.reserve()
+	if d <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		l.cancel()
+		return ctx.Err()
+	}
+}
+
+// reserve takes a token, which may leave the bucket owing one, and returns how long the caller must wait before
+// the token it took has refilled.
+func (l *Limiter) reserve() time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.refill()
+	l.tokens--
+	if l.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-l.tokens / l.rate * float64(time.Second))
+}
+
+// cancel gives back a token taken by reserve that was not used.
+func (l *Limiter) cancel() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.refill()
+	l.tokens = min(l.tokens+1, l.burst)
+}
+
+// refill brings tokens up to date with the time passed since last, at the current rate. l.mu must be held.
+func (l *Limiter) refill() {
+	now := l.now()
+	if !l.last.IsZero() {
+		l.tokens = min(l.tokens+now.Sub(l.last).Seconds()*l.rate, l.burst)
+	}
+	l.last = now
+}
+
+// Report tells an adaptive Limiter how a piece of work downstream of it went: a nil err is a success and raises
+// the rate, an error (that WithThrottledOn accepts) lowers it. Report does nothing for a Limiter made without
+// WithAdaptive.
+func (l *Limiter) Report(err error) {
+	if !l.opts.adaptive {
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	// Bring tokens up to date at the old rate before it changes.
+	l.refill()
+	if err != nil && (l.opts.throttled == nil || l.opts.throttled(err)) {
+		if !l.lastDecrease.IsZero() && l.last.Sub(l.lastDecrease) < time.Second {
+			return
+		}
+		l.lastDecrease = l.last
+		l.rate = max(l.rate/2, l.opts.min)
+		return
+	}
+	l.rate = min(l.rate+l.opts.step, l.max)
+}
+
+// Limit paces seq to l: each pair is yielded only once a token has been taken from l, so a consumer ranging
+// the result, such as foreach.Item, keyed.Item or a fanout feeding loop, sees pairs no faster than l allows.
+// Iteration ends when seq ends, when the consumer stops early, or when ctx is cancelled, including while waiting
+// on a token; a pair read from seq but not yet yielded at cancellation is dropped.
+func Limit[K, V any](ctx context.Context, seq iter.Seq2[K, V], l *Limiter) iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		if ctx.Err() != nil {
+			return
+		}
+		for k, v := range seq {
+			if l.Wait(ctx) != nil {
+				return
+			}
+			if !yield(k, v) {
+				return
+			}
+		}
+	}
+}
+
+// Observe passes seq through unchanged, reporting the Err of every Result to l. Ranging the output of
+// foreach.Item through Observe, with the input paced by Limit on the same adaptive Limiter, closes the loop:
+// errors from the work slow the source, and successes speed it back up.
+func Observe[K, V any](seq iter.Seq2[K, Result[V]], l *Limiter) iter.Seq2[K, Result[V]] {
+	return func(yield func(K, Result[V]) bool) {
+		for k, r := range seq {
+			l.Report(r.Err)
+			if !yield(k, r) {
+				return
+			}
+		}
+	}
+}
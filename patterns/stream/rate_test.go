@@ -0,0 +1,248 @@
+package stream
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gostdlib/base/context"
+	"github.com/kylelemons/godebug/pretty"
+)
+
+func TestNewLimiter(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		rate    float64
+		burst   int
+		opts    []LimitOption
+		wantErr bool
+	}{
+		{
+			name:  "Success: rate and burst",
+			rate:  10,
+			burst: 1,
+		},
+		{
+			name:  "Success: adaptive",
+			rate:  10,
+			burst: 1,
+			opts:  []LimitOption{WithAdaptive(1, 1), WithThrottledOn(func(error) bool { return true })},
+		},
+		{
+			name:    "Error: rate <= 0",
+			burst:   1,
+			wantErr: true,
+		},
+		{
+			name:    "Error: burst < 1",
+			rate:    10,
+			wantErr: true,
+		},
+		{
+			name:    "Error: WithAdaptive min <= 0",
+			rate:    10,
+			burst:   1,
+			opts:    []LimitOption{WithAdaptive(0, 1)},
+			wantErr: true,
+		},
+		{
+			name:    "Error: WithAdaptive step <= 0",
+			rate:    10,
+			burst:   1,
+			opts:    []LimitOption{WithAdaptive(1, 0)},
+			wantErr: true,
+		},
+		{
+			name:    "Error: WithAdaptive min above rate",
+			rate:    10,
+			burst:   1,
+			opts:    []LimitOption{WithAdaptive(20, 1)},
+			wantErr: true,
+		},
+		{
+			name:    "Error: WithThrottledOn nil",
+			rate:    10,
+			burst:   1,
+			opts:    []LimitOption{WithThrottledOn(nil)},
+			wantErr: true,
+		},
+	}
+
+	for _, test := range tests {
+		_, err := NewLimiter(test.rate, test.burst, test.opts...)
+		switch {
+		case err == nil && test.wantErr:
+			t.Errorf("TestNewLimiter(%s): got err == nil, want err != nil", test.name)
+		case err != nil && !test.wantErr:
+			t.Errorf("TestNewLimiter(%s): got err == %s, want err == nil", test.name, err)
+		}
+	}
+}
+
+// fakeNow is a clock a test moves by hand.
+type fakeNow struct {
+	t time.Time
+}
+
+func (f *fakeNow) now() time.Time {
+	return f.t
+}
+
+func TestLimiterReserve(t *testing.T) {
+	t.Parallel()
+
+	clk := &fakeNow{t: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)}
+	l, err := NewLimiter(10, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.now = clk.now
+
+	// step takes a token after moving the clock on by after, and says how long the taker must wait.
+	type step struct {
+		after time.Duration
+		want  time.Duration
+	}
+	steps := []step{
+		{want: 0},                      // The bucket starts full.
+		{want: 0},                      // Still within the burst.
+		{want: 100 * time.Millisecond}, // Empty: wait for one token.
+		{want: 200 * time.Millisecond}, // Queued behind the last.
+		{after: time.Second, want: 0},  // Refilled, but only up to the burst.
+		{want: 0},                      // The burst's second token.
+		{after: 50 * time.Millisecond, want: 50 * time.Millisecond}, // Half a token refilled.
+	}
+	for i, s := range steps {
+		clk.t = clk.t.Add(s.after)
+		if got := l.reserve(); got != s.want {
+			t.Errorf("TestLimiterReserve(step %d): got wait %v, want %v", i, got, s.want)
+		}
+	}
+
+	// A cancelled wait gives its token back.
+	l.cancel()
+	if got := l.reserve(); got != 50*time.Millisecond {
+		t.Errorf("TestLimiterReserve(after cancel): got wait %v, want 50ms", got)
+	}
+}
+
+func TestLimiterReport(t *testing.T) {
+	t.Parallel()
+
+	errThrottled := errors.New("throttled")
+	errOther := errors.New("other")
+
+	// report is an error (or nil for a success) passed to Report after moving the clock on by after.
+	type report struct {
+		after time.Duration
+		err   error
+	}
+
+	tests := []struct {
+		name    string
+		opts    []LimitOption
+		reports []report
+		want    []float64
+	}{
+		{
+			name: "Success: not adaptive ignores reports",
+			reports: []report{
+				{err: errThrottled},
+				{after: time.Second, err: errThrottled},
+			},
+			want: []float64{10, 10},
+		},
+		{
+			name: "Success: errors halve the rate once a second down to min, successes step it back up to the max",
+			opts: []LimitOption{WithAdaptive(2, 3)},
+			reports: []report{
+				{err: errThrottled},
+				{after: 500 * time.Millisecond, err: errThrottled},
+				{after: 500 * time.Millisecond, err: errThrottled},
+				{after: time.Second, err: errThrottled},
+				{},
+				{},
+				{},
+				{},
+			},
+			want: []float64{5, 5, 2.5, 2, 5, 8, 10, 10},
+		},
+		{
+			name: "Success: WithThrottledOn treats other errors as successes",
+			opts: []LimitOption{WithAdaptive(1, 1), WithThrottledOn(func(err error) bool { return err == errThrottled })},
+			reports: []report{
+				{err: errThrottled},
+				{err: errOther},
+			},
+			want: []float64{5, 6},
+		},
+	}
+
+	for _, test := range tests {
+		clk := &fakeNow{t: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)}
+		l, err := NewLimiter(10, 1, test.opts...)
+		if err != nil {
+			t.Fatalf("TestLimiterReport(%s): %s", test.name, err)
+		}
+		l.now = clk.now
+
+		var got []float64
+		for _, r := range test.reports {
+			clk.t = clk.t.Add(r.after)
+			l.Report(r.err)
+			got = append(got, l.Rate())
+		}
+		if diff := pretty.Compare(test.want, got); diff != "" {
+			t.Errorf("TestLimiterReport(%s): -want/+got:\n%s", test.name, diff)
+		}
+	}
+}
+
+func TestLimit(t *testing.T) {
+	t.Parallel()
+
+	fast, err := NewLimiter(1e9, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := pretty.Compare([]pair[int, int]{{0, 1}, {1, 2}, {2, 3}}, collect(Limit(t.Context(), Slice([]int{1, 2, 3}), fast))); diff != "" {
+		t.Errorf("TestLimit: -want/+got:\n%s", diff)
+	}
+
+	// A token an hour: the first pair uses the burst, and the second waits until ctx is cancelled.
+	slow, err := NewLimiter(1.0/3600, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(t.Context())
+	var got []pair[int, int]
+	for k, v := range Limit(ctx, Slice([]int{1, 2, 3}), slow) {
+		got = append(got, pair[int, int]{k, v})
+		cancel()
+	}
+	if diff := pretty.Compare([]pair[int, int]{{0, 1}}, got); diff != "" {
+		t.Errorf("TestLimit(cancelled): -want/+got:\n%s", diff)
+	}
+	if err := slow.Wait(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("TestLimit(cancelled): got Wait err == %v, want context.Canceled", err)
+	}
+}
+
+func TestObserve(t *testing.T) {
+	t.Parallel()
+
+	l, err := NewLimiter(10, 1, WithAdaptive(1, 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	in := Slice([]Result[int]{{V: 1}, {Err: errA}})
+	if got := collectResults(Observe(in, l)); len(got) != 2 {
+		t.Errorf("TestObserve: got %d results, want 2", len(got))
+	}
+	if got := l.Rate(); got != 5 {
+		t.Errorf("TestObserve: got rate %v, want 5", got)
+	}
+}
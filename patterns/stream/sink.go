@@ -0,0 +1,112 @@
+package stream
+
+import (
+	"errors"
+	"iter"
+
+	"github.com/gostdlib/base/context"
+)
+
+// The sinks below range a sequence to its end, or until they have what they need, and hand back what it held.
+// A sink that stops early (FirstErr, Reduce, ToChan) breaks out of the range, which a sequence from foreach.Item
+// or keyed.Item takes as the signal to stop dispatching work.
+
+// Collect returns the value of every Result in seq without an Err, in the order seq yielded them, and every Err
+// joined with errors.Join. seq is ranged to its end.
+func Collect[K, V any](seq iter.Seq2[K, Result[V]]) ([]V, error) {
+	var (
+		vals []V
+		errs []error
+	)
+	for _, r := range seq {
+		if r.Err != nil {
+			errs = append(errs, r.Err)
+			continue
+		}
+		vals = append(vals, r.V)
+	}
+	return vals, errors.Join(errs...)
+}
+
+// CollectMap returns a map of the key and value of every Result in seq without an Err, and every Err joined with
+// errors.Join. A key yielded more than once keeps its last value. seq is ranged to its end.
+func CollectMap[K comparable, V any](seq iter.Seq2[K, Result[V]]) (map[K]V, error) {
+	var (
+		m    = map[K]V{}
+		errs []error
+	)
+	for k, r := range seq {
+		if r.Err != nil {
+			errs = append(errs, r.Err)
+			continue
+		}
+		m[k] = r.V
+	}
+	return m, errors.Join(errs...)
+}
+
+// FirstErr ranges seq, discarding values, until a Result has an Err, and returns that Err. It breaks out of the
+// range there, so nothing after the first failure is waited on. It returns nil if seq ends without an error.
+func FirstErr[K, V any](seq iter.Seq2[K, Result[V]]) error {
+	for _, r := range seq {
+		if r.Err != nil {
+			return r.Err
+		}
+	}
+	return nil
+}
+
+// Partition ranges seq to its end and splits it into the pairs whose Result has no Err, with their value, and the
+// pairs whose Result has one, with their Err. Both keep the order seq yielded them in.
+func Partition[K, V any](seq iter.Seq2[K, Result[V]]) (vals []Pair[K, V], errs []Pair[K, error]) {
+	for k, r := range seq {
+		if r.Err != nil {
+			errs = append(errs, Pair[K, error]{K: k, V: r.Err})
+			continue
+		}
+		vals = append(vals, Pair[K, V]{K: k, V: r.V})
+	}
+	return vals, errs
+}
+
+// ToChan sends every pair in seq on ch, blocking on each send until it is received, so a slow receiver slows the
+// range rather than letting pairs pile up. It returns ctx.Err() if ctx is cancelled before seq ends, breaking out
+// of the range, and nil once seq ends. ToChan does not close ch.
+func ToChan[K, V any](ctx context.Context, seq iter.Seq2[K, V], ch chan<- Pair[K, V]) error {
+	for k, v := range seq {
+		select {
+		case ch <- Pair[K, V]{K: k, V: v}:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return nil
+}
+
+// Fold ranges seq to its end, calling fn with the result of the previous call, starting at init, and each pair,
+// and returns the result of the last call. It returns init if seq is empty.
+func Fold[K, V, A any](seq iter.Seq2[K, V], init A, fn func(acc A, k K, v V) A) A {
+	acc := init
+	for k, v := range seq {
+		acc = fn(acc, k, v)
+	}
+	return acc
+}
+
+// Reduce is Fold for a sequence of Results that stops at the first error: fn is called with the value of every
+// Result, and the range ends at the first Result with an Err or the first error fn returns. That error is
+// returned along with the result of the last call to fn that succeeded.
+func Reduce[K, V, A any](seq iter.Seq2[K, Result[V]], init A, fn func(acc A, k K, v V) (A, error)) (A, error) {
+	acc := init
+	for k, r := range seq {
+		if r.Err != nil {
+			return acc, r.Err
+		}
+		next, err := fn(acc, k, r.V)
+		if err != nil {
+			return acc, err
+		}
+		acc = next
+	}
+	return acc, nil
+}
@@ -0,0 +1,198 @@
+package stream
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/gostdlib/base/context"
+	"github.com/kylelemons/godebug/pretty"
+)
+
+var (
+	errA = errors.New("a")
+	errB = errors.New("b")
+)
+
+// results is a sequence with two failures among three values.
+var results = Slice([]Result[int]{{V: 1}, {Err: errA}, {V: 2}, {Err: errB}, {V: 3}})
+
+// counted wraps seq and counts the pairs read from it, so a test can tell where a sink stopped.
+func counted[K, V any](seq func(yield func(K, V) bool), n *int) func(yield func(K, V) bool) {
+	return func(yield func(K, V) bool) {
+		for k, v := range seq {
+			*n++
+			if !yield(k, v) {
+				return
+			}
+		}
+	}
+}
+
+func TestCollect(t *testing.T) {
+	t.Parallel()
+
+	vals, err := Collect(results)
+	if diff := pretty.Compare([]int{1, 2, 3}, vals); diff != "" {
+		t.Errorf("TestCollect: -want/+got:\n%s", diff)
+	}
+	if !errors.Is(err, errA) || !errors.Is(err, errB) {
+		t.Errorf("TestCollect: got err == %v, want errA and errB joined", err)
+	}
+
+	vals, err = Collect(Slice([]Result[int]{{V: 1}}))
+	if err != nil || len(vals) != 1 {
+		t.Errorf("TestCollect: got (%v, %v), want ([1], nil)", vals, err)
+	}
+}
+
+func TestCollectMap(t *testing.T) {
+	t.Parallel()
+
+	m, err := CollectMap(results)
+	if diff := pretty.Compare(map[int]int{0: 1, 2: 2, 4: 3}, m); diff != "" {
+		t.Errorf("TestCollectMap: -want/+got:\n%s", diff)
+	}
+	if !errors.Is(err, errA) || !errors.Is(err, errB) {
+		t.Errorf("TestCollectMap: got err == %v, want errA and errB joined", err)
+	}
+}
+
+func TestFirstErr(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		in       []Result[int]
+		wantErr  error
+		wantRead int
+	}{
+		{
+			name:     "Success: no error ranges to the end",
+			in:       []Result[int]{{V: 1}, {V: 2}},
+			wantRead: 2,
+		},
+		{
+			name:     "Error: stops at the first error",
+			in:       []Result[int]{{V: 1}, {Err: errA}, {Err: errB}},
+			wantErr:  errA,
+			wantRead: 2,
+		},
+	}
+
+	for _, test := range tests {
+		read := 0
+		err := FirstErr(counted(Slice(test.in), &read))
+		if err != test.wantErr {
+			t.Errorf("TestFirstErr(%s): got err == %v, want %v", test.name, err, test.wantErr)
+		}
+		if read != test.wantRead {
+			t.Errorf("TestFirstErr(%s): got %d read, want %d", test.name, read, test.wantRead)
+		}
+	}
+}
+
+func TestPartition(t *testing.T) {
+	t.Parallel()
+
+	vals, errs := Partition(results)
+	if diff := pretty.Compare([]Pair[int, int]{{0, 1}, {2, 2}, {4, 3}}, vals); diff != "" {
+		t.Errorf("TestPartition(vals): -want/+got:\n%s", diff)
+	}
+	if len(errs) != 2 || errs[0] != (Pair[int, error]{1, errA}) || errs[1] != (Pair[int, error]{3, errB}) {
+		t.Errorf("TestPartition(errs): got %v, want [{1 a} {3 b}]", errs)
+	}
+}
+
+func TestToChan(t *testing.T) {
+	t.Parallel()
+
+	ch := make(chan Pair[int, string])
+	done := make(chan error, 1)
+	go func() {
+		done <- ToChan(t.Context(), Slice([]string{"a", "b"}), ch)
+	}()
+
+	var got []Pair[int, string]
+	for range 2 {
+		got = append(got, <-ch)
+	}
+	if err := <-done; err != nil {
+		t.Errorf("TestToChan: got err == %s, want err == nil", err)
+	}
+	if diff := pretty.Compare([]Pair[int, string]{{0, "a"}, {1, "b"}}, got); diff != "" {
+		t.Errorf("TestToChan: -want/+got:\n%s", diff)
+	}
+
+	// Nothing receives, so only cancellation can end the send.
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+	read := 0
+	if err := ToChan(ctx, counted(Slice([]string{"a", "b"}), &read), make(chan Pair[int, string])); !errors.Is(err, context.Canceled) {
+		t.Errorf("TestToChan(cancelled): got err == %v, want context.Canceled", err)
+	}
+	if read != 1 {
+		t.Errorf("TestToChan(cancelled): got %d read, want 1", read)
+	}
+}
+
+func TestFold(t *testing.T) {
+	t.Parallel()
+
+	got := Fold(Slice([]int{1, 2, 3}), "", func(acc string, k, v int) string {
+		return acc + string(rune('a'+v-1))
+	})
+	if got != "abc" {
+		t.Errorf("TestFold: got %q, want %q", got, "abc")
+	}
+}
+
+func TestReduce(t *testing.T) {
+	t.Parallel()
+
+	sum := func(acc int, k, v int) (int, error) {
+		if v < 0 {
+			return acc, errB
+		}
+		return acc + v, nil
+	}
+
+	tests := []struct {
+		name     string
+		in       []Result[int]
+		want     int
+		wantErr  error
+		wantRead int
+	}{
+		{
+			name:     "Success: every value is reduced",
+			in:       []Result[int]{{V: 1}, {V: 2}, {V: 3}},
+			want:     6,
+			wantRead: 3,
+		},
+		{
+			name:     "Error: a Result with an Err stops the reduce",
+			in:       []Result[int]{{V: 1}, {Err: errA}, {V: 3}},
+			want:     1,
+			wantErr:  errA,
+			wantRead: 2,
+		},
+		{
+			name:     "Error: an error from fn stops the reduce",
+			in:       []Result[int]{{V: 1}, {V: -1}, {V: 3}},
+			want:     1,
+			wantErr:  errB,
+			wantRead: 2,
+		},
+	}
+
+	for _, test := range tests {
+		read := 0
+		got, err := Reduce(counted(Slice(test.in), &read), 0, sum)
+		if err != test.wantErr {
+			t.Errorf("TestReduce(%s): got err == %v, want %v", test.name, err, test.wantErr)
+		}
+		if got != test.want || read != test.wantRead {
+			t.Errorf("TestReduce(%s): got (%d, %d read), want (%d, %d read)", test.name, got, read, test.want, test.wantRead)
+		}
+	}
+}
@@ -0,0 +1,177 @@
+package stream
+
+import (
+	"bufio"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"iter"
+
+	"github.com/gostdlib/base/context"
+
+	"github.com/go-json-experiment/json"
+	"github.com/jszwec/csvutil"
+)
+
+// The sources below read a record at a time from an io.Reader or a file system and report what goes wrong in-band,
+// as a Result with Err set, so their output can be handed straight to foreach.Item or keyed.Item. A record that
+// cannot be decoded is reported and skipped, as the next record may be fine. A failure to read ends the iteration
+// after it is reported, as nothing after it can be trusted.
+//
+// ctx is checked before every record is read, and iteration ends without an error once it is cancelled. A Read
+// that is blocked, say on a socket, cannot see ctx: close the reader to unblock it.
+
+// ErrMalformed is wrapped by the error JSONL or CSV yields for a record that was read but cannot be decoded, or a
+// line too long to be read whole. Reading the record again fails the same way, where any other error is the reader
+// failing and may not.
+var ErrMalformed = errors.New("stream: malformed record")
+
+// Scanner adapts a bufio.Scanner into an iter.Seq2 whose key is a zero-based index counting the tokens scanned and
+// whose value is the token as a string. Use it over Lines to scan with a different split function or a larger
+// buffer. A scan error is yielded under the index of the token that failed, and ends the iteration.
+func Scanner(ctx context.Context, s *bufio.Scanner) iter.Seq2[int, Result[string]] {
+	return func(yield func(int, Result[string]) bool) {
+		i := 0
+		for {
+			if ctx.Err() != nil {
+				return
+			}
+			if !s.Scan() {
+				if err := s.Err(); err != nil {
+					yield(i, Result[string]{Err: err})
+				}
+				return
+			}
+			if !yield(i, Result[string]{V: s.Text()}) {
+				return
+			}
+			i++
+		}
+	}
+}
+
+// Lines adapts r into an iter.Seq2 whose key is a zero-based line index and whose value is the line, without its
+// line ending. A line longer than bufio.MaxScanTokenSize is a read error; use Scanner with a larger buffer to
+// read longer lines.
+func Lines(ctx context.Context, r io.Reader) iter.Seq2[int, Result[string]] {
+	return Scanner(ctx, bufio.NewScanner(r))
+}
+
+// JSONL adapts r, a stream of JSON Lines, into an iter.Seq2 whose key is a zero-based line index and whose value
+// is the T decoded from that line. Blank lines are skipped, though they still count toward the index. A line that
+// does not decode into a T is yielded as an error wrapping ErrMalformed and skipped. A line longer than
+// bufio.MaxScanTokenSize is yielded as one too, and ends the iteration, as the lines after it cannot be found.
+func JSONL[T any](ctx context.Context, r io.Reader) iter.Seq2[int, Result[T]] {
+	return func(yield func(int, Result[T]) bool) {
+		for i, line := range Lines(ctx, r) {
+			if line.Err != nil {
+				err := line.Err
+				if errors.Is(err, bufio.ErrTooLong) {
+					err = fmt.Errorf("line %d: %w: %w", i, err, ErrMalformed)
+				}
+				yield(i, Result[T]{Err: fmt.Errorf("stream.JSONL: %w", err)})
+				return
+			}
+			if line.V == "" {
+				continue
+			}
+			var v T
+			if err := json.Unmarshal([]byte(line.V), &v); err != nil {
+				if !yield(i, Result[T]{Err: fmt.Errorf("stream.JSONL: line %d: %w: %w", i, err, ErrMalformed)}) {
+					return
+				}
+				continue
+			}
+			if !yield(i, Result[T]{V: v}) {
+				return
+			}
+		}
+	}
+}
+
+// CSV adapts r, CSV with a header record, into an iter.Seq2 whose key is a zero-based index counting the records
+// after the header and whose value is the T csvutil decodes from that record, matching columns by the header. A
+// record that cannot be parsed or decoded into a T is yielded as an error wrapping ErrMalformed and skipped. An
+// empty r yields nothing; a header that cannot be parsed is yielded as an error wrapping ErrMalformed and ends the
+// iteration.
+func CSV[T any](ctx context.Context, r io.Reader) iter.Seq2[int, Result[T]] {
+	return func(yield func(int, Result[T]) bool) {
+		if ctx.Err() != nil {
+			return
+		}
+		dec, err := csvutil.NewDecoder(csv.NewReader(r))
+		switch {
+		case errors.Is(err, io.EOF):
+			return
+		case malformed(err):
+			yield(0, Result[T]{Err: fmt.Errorf("stream.CSV: reading header: %w: %w", err, ErrMalformed)})
+			return
+		case err != nil:
+			yield(0, Result[T]{Err: fmt.Errorf("stream.CSV: reading header: %w", err)})
+			return
+		}
+
+		for i := 0; ; i++ {
+			if ctx.Err() != nil {
+				return
+			}
+			var v T
+			err := dec.Decode(&v)
+			switch {
+			case errors.Is(err, io.EOF):
+				return
+			case malformed(err):
+				if !yield(i, Result[T]{Err: fmt.Errorf("stream.CSV: record %d: %w: %w", i, err, ErrMalformed)}) {
+					return
+				}
+				continue
+			case err != nil:
+				yield(i, Result[T]{Err: fmt.Errorf("stream.CSV: record %d: %w", i, err)})
+				return
+			}
+			if !yield(i, Result[T]{V: v}) {
+				return
+			}
+		}
+	}
+}
+
+// malformed reports whether err is a record that could not be parsed or decoded, as opposed to the reader
+// failing. Decoding can carry on with the next record after a malformed one.
+func malformed(err error) bool {
+	var (
+		parse     *csv.ParseError
+		decode    *csvutil.DecodeError
+		unmarshal *csvutil.UnmarshalTypeError
+		missing   *csvutil.MissingColumnsError
+	)
+	return errors.As(err, &parse) || errors.As(err, &decode) || errors.As(err, &unmarshal) ||
+		errors.As(err, &missing) || errors.Is(err, csvutil.ErrFieldCount)
+}
+
+// Walk adapts fs.WalkDir over fsys from root into an iter.Seq2 whose key is the path of an entry, in the lexical
+// order fs.WalkDir visits them, and whose value is the entry. A directory that cannot be read is yielded as an
+// error under its path and its contents are skipped; the walk carries on with the rest of the tree.
+func Walk(ctx context.Context, fsys fs.FS, root string) iter.Seq2[string, Result[fs.DirEntry]] {
+	return func(yield func(string, Result[fs.DirEntry]) bool) {
+		fs.WalkDir(fsys, root, func(path string, d fs.DirEntry, err error) error {
+			if ctx.Err() != nil {
+				return fs.SkipAll
+			}
+			if err != nil {
+				if !yield(path, Result[fs.DirEntry]{V: d, Err: err}) {
+					return fs.SkipAll
+				}
+				// WalkDir reports a directory it cannot read by calling again with err set, and has no contents
+				// of it to walk. Returning nil carries on with the rest of the tree.
+				return nil
+			}
+			if !yield(path, Result[fs.DirEntry]{V: d}) {
+				return fs.SkipAll
+			}
+			return nil
+		})
+	}
+}
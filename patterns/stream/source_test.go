@@ -0,0 +1,250 @@
+package stream
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"io/fs"
+	"iter"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/gostdlib/base/context"
+	"github.com/kylelemons/godebug/pretty"
+)
+
+type record struct {
+	Name string `json:"name" csv:"name"`
+	N    int    `json:"n" csv:"n"`
+}
+
+// got is a collected Result with the error flattened to whether there was one, so it can be compared.
+type got[K, V any] struct {
+	K   K
+	V   V
+	Err bool
+}
+
+// collectResults drains seq into a slice in iteration order.
+func collectResults[K, V any](seq iter.Seq2[K, Result[V]]) []got[K, V] {
+	var out []got[K, V]
+	for k, r := range seq {
+		out = append(out, got[K, V]{K: k, V: r.V, Err: r.Err != nil})
+	}
+	return out
+}
+
+// checkMalformed passes seq through, reporting an error that wraps ErrMalformed when malformed is false, or does
+// not when it is true.
+func checkMalformed[V any](t *testing.T, fn, name string, malformed bool, seq iter.Seq2[int, Result[V]]) iter.Seq2[int, Result[V]] {
+	return func(yield func(int, Result[V]) bool) {
+		for i, r := range seq {
+			if r.Err != nil && errors.Is(r.Err, ErrMalformed) != malformed {
+				t.Errorf("%s(%s): got err == %s, want errors.Is(err, ErrMalformed) == %v", fn, name, r.Err, malformed)
+			}
+			if !yield(i, r) {
+				return
+			}
+		}
+	}
+}
+
+// failReader returns its data and then err.
+type failReader struct {
+	data string
+	err  error
+}
+
+func (f *failReader) Read(p []byte) (int, error) {
+	if f.data == "" {
+		return 0, f.err
+	}
+	n := copy(p, f.data)
+	f.data = f.data[n:]
+	return n, nil
+}
+
+func TestLines(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		r    io.Reader
+		want []got[int, string]
+	}{
+		{
+			name: "Success: every line is yielded without its line ending",
+			r:    strings.NewReader("a\r\nb\n\nc"),
+			want: []got[int, string]{{K: 0, V: "a"}, {K: 1, V: "b"}, {K: 2, V: ""}, {K: 3, V: "c"}},
+		},
+		{
+			name: "Error: a read error is yielded and ends the iteration",
+			r:    &failReader{data: "a\n", err: errors.New("connection reset")},
+			want: []got[int, string]{{K: 0, V: "a"}, {K: 1, Err: true}},
+		},
+	}
+
+	for _, test := range tests {
+		if diff := pretty.Compare(test.want, collectResults(Lines(t.Context(), test.r))); diff != "" {
+			t.Errorf("TestLines(%s): -want/+got:\n%s", test.name, diff)
+		}
+	}
+}
+
+func TestScanner(t *testing.T) {
+	t.Parallel()
+
+	s := bufio.NewScanner(strings.NewReader("a b  c"))
+	s.Split(bufio.ScanWords)
+
+	want := []got[int, string]{{K: 0, V: "a"}, {K: 1, V: "b"}, {K: 2, V: "c"}}
+	if diff := pretty.Compare(want, collectResults(Scanner(t.Context(), s))); diff != "" {
+		t.Errorf("TestScanner: -want/+got:\n%s", diff)
+	}
+}
+
+func TestSourceCancel(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+
+	if got := collectResults(Lines(ctx, strings.NewReader("a\nb\n"))); len(got) != 0 {
+		t.Errorf("TestSourceCancel: Lines got %d results, want 0", len(got))
+	}
+	if got := collectResults(CSV[record](ctx, strings.NewReader("name,n\na,1\n"))); len(got) != 0 {
+		t.Errorf("TestSourceCancel: CSV got %d results, want 0", len(got))
+	}
+	if got := collectResults(Walk(ctx, fstest.MapFS{"a": {}}, ".")); len(got) != 0 {
+		t.Errorf("TestSourceCancel: Walk got %d results, want 0", len(got))
+	}
+}
+
+func TestJSONL(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		r    io.Reader
+		want []got[int, record]
+		// malformed is whether the errors wrap ErrMalformed.
+		malformed bool
+	}{
+		{
+			name: "Success: every line is decoded and blank lines are skipped",
+			r:    strings.NewReader("{\"name\":\"a\",\"n\":1}\n\n{\"name\":\"b\",\"n\":2}\n"),
+			want: []got[int, record]{{K: 0, V: record{Name: "a", N: 1}}, {K: 2, V: record{Name: "b", N: 2}}},
+		},
+		{
+			name:      "Error: a line that does not decode is yielded as an error and skipped",
+			malformed: true,
+			r:         strings.NewReader("{\"name\":\"a\",\"n\":1}\nnot json\n{\"name\":\"b\",\"n\":2}\n"),
+			want:      []got[int, record]{{K: 0, V: record{Name: "a", N: 1}}, {K: 1, Err: true}, {K: 2, V: record{Name: "b", N: 2}}},
+		},
+		{
+			name:      "Error: a line too long to scan is yielded as an error and ends the iteration",
+			r:         strings.NewReader("{\"name\":\"a\",\"n\":1}\n" + strings.Repeat("x", bufio.MaxScanTokenSize+1) + "\n{}\n"),
+			want:      []got[int, record]{{K: 0, V: record{Name: "a", N: 1}}, {K: 1, Err: true}},
+			malformed: true,
+		},
+		{
+			name: "Error: a read error is yielded and ends the iteration",
+			r:    &failReader{data: "{\"name\":\"a\",\"n\":1}\n", err: errors.New("connection reset")},
+			want: []got[int, record]{{K: 0, V: record{Name: "a", N: 1}}, {K: 1, Err: true}},
+		},
+	}
+
+	for _, test := range tests {
+		seq := JSONL[record](t.Context(), test.r)
+		if diff := pretty.Compare(test.want, collectResults(checkMalformed(t, "TestJSONL", test.name, test.malformed, seq))); diff != "" {
+			t.Errorf("TestJSONL(%s): -want/+got:\n%s", test.name, diff)
+		}
+	}
+}
+
+func TestCSV(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		r    io.Reader
+		want []got[int, record]
+		// malformed is whether the errors wrap ErrMalformed.
+		malformed bool
+	}{
+		{
+			name: "Success: every record is decoded by the header",
+			r:    strings.NewReader("n,name\n1,a\n2,b\n"),
+			want: []got[int, record]{{K: 0, V: record{Name: "a", N: 1}}, {K: 1, V: record{Name: "b", N: 2}}},
+		},
+		{
+			name: "Success: an empty reader yields nothing",
+			r:    strings.NewReader(""),
+			want: nil,
+		},
+		{
+			name:      "Error: a record that does not decode is yielded as an error and skipped",
+			malformed: true,
+			r:         strings.NewReader("name,n\na,1\nb,two\nc,3\n"),
+			want:      []got[int, record]{{K: 0, V: record{Name: "a", N: 1}}, {K: 1, Err: true}, {K: 2, V: record{Name: "c", N: 3}}},
+		},
+		{
+			name:      "Error: a record with the wrong number of fields is yielded as an error and skipped",
+			malformed: true,
+			r:         strings.NewReader("name,n\na,1\nb\nc,3\n"),
+			want:      []got[int, record]{{K: 0, V: record{Name: "a", N: 1}}, {K: 1, Err: true}, {K: 2, V: record{Name: "c", N: 3}}},
+		},
+		{
+			name: "Error: a read error is yielded and ends the iteration",
+			r:    &failReader{data: "name,n\na,1\n", err: errors.New("connection reset")},
+			want: []got[int, record]{{K: 0, V: record{Name: "a", N: 1}}, {K: 1, Err: true}},
+		},
+	}
+
+	for _, test := range tests {
+		seq := CSV[record](t.Context(), test.r)
+		if diff := pretty.Compare(test.want, collectResults(checkMalformed(t, "TestCSV", test.name, test.malformed, seq))); diff != "" {
+			t.Errorf("TestCSV(%s): -want/+got:\n%s", test.name, diff)
+		}
+	}
+}
+
+// errFS is a file system whose directory "bad" cannot be read.
+type errFS struct {
+	fstest.MapFS
+}
+
+func (e errFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	if name == "bad" {
+		return nil, errors.New("permission denied")
+	}
+	return e.MapFS.ReadDir(name)
+}
+
+func TestWalk(t *testing.T) {
+	t.Parallel()
+
+	fsys := errFS{fstest.MapFS{
+		"a.txt":     {},
+		"bad/x.txt": {},
+		"dir/b.txt": {},
+	}}
+
+	var paths []string
+	var errs []string
+	for path, r := range Walk(t.Context(), fsys, ".") {
+		if r.Err != nil {
+			errs = append(errs, path)
+			continue
+		}
+		paths = append(paths, path)
+	}
+
+	if diff := pretty.Compare([]string{".", "a.txt", "bad", "dir", "dir/b.txt"}, paths); diff != "" {
+		t.Errorf("TestWalk: paths -want/+got:\n%s", diff)
+	}
+	if diff := pretty.Compare([]string{"bad"}, errs); diff != "" {
+		t.Errorf("TestWalk: errors -want/+got:\n%s", diff)
+	}
+}
@@ -7,6 +7,25 @@ common data sources into one:
   - Slice turns a slice into an iter.Seq2 of index and element (via slices.All).
   - Map turns a map into an iter.Seq2 of key and value (via maps.All).
   - Seq turns a single-value iter.Seq into an iter.Seq2 keyed by a zero-based index.
+  - Lines and Scanner turn an io.Reader or a bufio.Scanner into an iter.Seq2 of line (or token) index and text.
+  - JSONL and CSV decode the records of an io.Reader into an iter.Seq2 of record index and value.
+  - Walk turns fs.WalkDir into an iter.Seq2 of path and fs.DirEntry.
+
+Lines, Scanner, JSONL, CSV and Walk yield a Result rather than a bare value, so a record that cannot be read or
+decoded is reported in-band and their output can be passed straight to foreach.Item.
+
+It also has lazy operators that compose sequences between stages: Transform, TransformResult, Filter, Take, Skip,
+Distinct and Zip work a pair at a time, while Batch, Tumbling, Sliding and Merge gather pairs by count, by time or
+from several sequences at once. None of them look inside a value, so a Result with an Err flows through them.
+
+Sinks end a stream of Results: Collect and CollectMap gather the values and join the errors, FirstErr stops at
+the first error, Partition splits values from errors, ToChan sends every pair on a channel, and Fold and Reduce
+combine every value into one.
+
+Limit paces a sequence to a Limiter, a token bucket with a burst that can be shared by several sequences, so work
+that calls a rate-limited API is throttled at its source instead of inside every ItemFunc. A Limiter made
+WithAdaptive also lowers its rate on errors passed to Report, or seen by Observe on the results, and raises it
+again on successes.
 
 For example, to process each value received on a channel in parallel and stream the results back:
 